@@ -4,9 +4,15 @@ import (
 	"github.com/pedrogao/btrees/common"
 )
 
+var (
+	_ common.OrderedMap[int, any]      = (*BTree)(nil)
+	_ common.OrderStatistics[int, any] = (*BTree)(nil)
+	_ common.Navigable[int, any]       = (*BTree)(nil)
+)
+
 type item struct {
 	key   int
-	value any // value or child
+	value any
 }
 
 func newItem(key int, val any) *item {
@@ -16,12 +22,17 @@ func newItem(key int, val any) *item {
 	}
 }
 
+// node 叶子节点中 items[:count] 都是 kv 对，children 都为 nil。
+// 内部节点中 count 为孩子数，items[0] 为 nil 哨兵，只用来挂最左边的孩子；
+// items[i] (i > 0) 是真正的 kv 对，children[i-1] 中的 key 都小于 items[i].key，
+// children[i] 中的 key 都大于 items[i].key
 type node struct {
 	parent   *node // 父节点
 	max      int
 	count    int
 	items    []*item // 节点kv对
 	children []*node // 子节点
+	size     int     // 子树中 kv 对的数量
 }
 
 func newNode(size int) *node {
@@ -34,14 +45,19 @@ func newNode(size int) *node {
 }
 
 type BTree struct {
-	min  int
-	max  int
-	n    int
-	root *node
+	min     int
+	max     int
+	n       int
+	root    *node
+	changes common.ChangeTrace[*node] // 最近一次分裂或者合并修改的节点，导出时高亮
 }
 
+// NewBTree returns a b tree whose nodes hold at most min*2-1 entries,
+// min must be at least 2
 func NewBTree(min int) *BTree {
-	// min |= 1
+	if min < 2 {
+		min = 2
+	}
 	return &BTree{
 		min: min,
 		max: min * 2,
@@ -49,185 +65,240 @@ func NewBTree(min int) *BTree {
 	}
 }
 
+// Insert key->val, the value of an existing key is replaced.
+// It reports whether the key is new
 func (t *BTree) Insert(key int, val any) bool {
-	cur := t.root
-	if cur == nil {
-		cur = newNode(t.max)
-		t.root = cur
+	if t.root == nil {
+		t.root = newNode(t.max)
 	}
-
-	w := cur.add(key, val)
+	t.changes.Begin()
+	sep, w, ok := t.root.insert(key, val, &t.changes)
 	if w != nil {
+		// 根节点分裂，树高加一
 		n := newNode(t.max)
-		last := cur.removeLast()
-		n.addChild(0, nil, cur)
-		n.addChild(last.key, last.value, w)
+		n.appendChild(nil, t.root)
+		n.appendChild(sep, w)
+		n.recount()
 		t.root = n
+		t.changes.Touch(n)
 	}
-
-	t.n += 1
-	return true
+	if ok {
+		t.n += 1
+	}
+	return ok
 }
 
+// Search returns the value of key, nil if the key does not exist
 func (t *BTree) Search(key int) any {
+	if it := t.find(key); it != nil {
+		return it.value
+	}
+	return nil
+}
+
+// find 返回 key 对应的 item，不存在时返回 nil
+func (t *BTree) find(key int) *item {
 	u := t.root
-	var z any
 	for u != nil {
 		i := u.findIndex(key)
 		if i < 0 { // found
-			return u.items[-(i + 1)].value
+			return u.items[-(i + 1)]
 		}
-		if u.items[i] != nil {
-			z = u.items[i].value
+		if u.isLeaf() {
+			return nil
 		}
 		// search at sub node
-		if i >= u.count {
-			i = u.count - 1
-		}
-		u = u.children[i]
+		u = u.children[i-1]
 	}
-	return z
+	return nil
 }
 
+// Delete removes the key, it reports whether the key existed
 func (t *BTree) Delete(key int) bool {
 	r := t.root
-	if r.delete(key) {
-		t.n--
-		if r.getSize() == 0 && t.n > 0 {
-			t.root = t.root.children[0]
-			t.root.parent = nil
-		}
-		return true
+	t.changes.Begin()
+	if r == nil || !r.delete(key, &t.changes) {
+		return false
 	}
-	return false
+	t.n--
+	// 根节点只剩一个孩子，树高减一
+	if !r.isLeaf() && r.getSize() == 1 {
+		t.root = r.children[0]
+		t.root.parent = nil
+	}
+	return true
 }
 
-func (n *node) delete(key int) bool {
+// insert 插入 kv，节点分裂时返回上移到父节点的 item 和新的右节点，
+// 以及 key 是否是新插入的。分裂修改的节点记录到 c 中
+func (n *node) insert(key int, val any, c *common.ChangeTrace[*node]) (*item, *node, bool) {
 	i := n.findIndex(key)
 	if i < 0 {
-		// found
-		i = -(i + 1)
-		if n.isLeaf() {
-			n.remove(i)
-		} else {
-			smallest := n.children[i].removeSmallest()
-			n.items[i] = smallest
-			n.checkUnderflow(i)
+		n.items[-(i + 1)] = newItem(key, val)
+		return nil, nil, false
+	}
+	if n.isLeaf() {
+		w := n.add(key, val)
+		if w == nil {
+			n.recount()
+			return nil, nil, true
 		}
+		// 左节点的最后一项上移
+		sep := n.removeLast()
+		n.recount()
+		w.recount()
+		c.Touch(n, w)
+		return sep, w, true
+	}
+	sep, w, ok := n.children[i-1].insert(key, val, c)
+	if w == nil {
+		n.recount()
+		return nil, nil, ok
+	}
+	c.Touch(n)
+	other := n.addChild(sep.key, sep.value, w)
+	if other == nil {
+		n.recount()
+		return nil, nil, ok
+	}
+	// 右节点的第一项上移，它的孩子成为右节点最左边的孩子
+	sep = other.items[0]
+	other.items[0] = nil
+	n.recount()
+	other.recount()
+	c.Touch(other)
+	return sep, other, ok
+}
+
+// delete 删除 key，合并修改的节点记录到 c 中
+func (n *node) delete(key int, c *common.ChangeTrace[*node]) bool {
+	i := n.findIndex(key)
+	if n.isLeaf() {
+		if i >= 0 {
+			return false
+		}
+		n.remove(-(i + 1))
+		n.recount()
 		return true
 	}
-	if i > 0 {
-		i -= 1
+	if i < 0 {
+		// found, 用右子树中最小的项替换
+		i = -(i + 1)
+		n.items[i] = n.children[i].removeSmallest(c)
+		n.checkUnderflow(i, c)
+		n.recount()
+		return true
 	}
 	// 从子节点中删除
-	if n.children[i].delete(key) {
+	if n.children[i-1].delete(key, c) {
 		// 判断是否需要重组、合并
-		n.checkUnderflow(i)
+		n.checkUnderflow(i-1, c)
+		n.recount()
 		return true
 	}
-
 	return false
 }
 
-func (n *node) removeSmallest() *item {
+func (n *node) removeSmallest(c *common.ChangeTrace[*node]) *item {
 	if n.isLeaf() {
-		return n.remove(0)
+		y := n.remove(0)
+		n.recount()
+		return y
 	}
-	y := n.children[0].removeSmallest()
-	n.checkUnderflow(0)
+	y := n.children[0].removeSmallest(c)
+	n.checkUnderflow(0, c)
+	n.recount()
 	return y
 }
 
-func (n *node) checkUnderflow(i int) {
-	if n.children[i] == nil {
+// checkUnderflow 第 i 个孩子不足半满时，向兄弟节点借一项，或者与兄弟节点合并
+func (n *node) checkUnderflow(i int, c *common.ChangeTrace[*node]) {
+	w := n.children[i]
+	if !w.underflow() {
 		return
 	}
 	if i == 0 {
 		// 如果被删除的节点是第一个节点，因此其 sibling 是右边的兄弟
-		n.checkUnderflowZero(i)
-	} else {
-		// 如果删除的节点不是第一个节点，其 sibling 是左边的兄弟
-		n.checkUnderflowNonZero(i)
-	}
-}
-
-func (n *node) checkUnderflowZero(i int) {
-	w := n.children[i]
-	if w.underflow() {
-		v := n.children[i+1]
-		if v.getSize() > n.max/2 {
-			leftRotation(n, v, w, i)
+		v := n.children[1]
+		if v.getSize() > v.getMinSize() {
+			leftRotation(n, w, v, 1)
 		} else {
-			merge(n, v, w, i)
-			n.children[i] = w
+			merge(n, w, v, 1)
+			c.Touch(n, w)
 		}
-	}
-}
-
-func (n *node) checkUnderflowNonZero(i int) {
-	w := n.children[i]
-	if w.underflow() {
-		v := n.children[i-1] // sibling
-		if v.getSize() > n.max/2 {
-			// 如果 sibling 半满，那么可以借一个，否则只能合并
-			rightRotation(n, v, w, i-1) // fixme
+	} else {
+		// 如果删除的节点不是第一个节点，其 sibling 是左边的兄弟
+		v := n.children[i-1]
+		if v.getSize() > v.getMinSize() {
+			rightRotation(n, v, w, i)
 		} else {
-			// 合并
 			merge(n, v, w, i)
+			c.Touch(n, v)
 		}
 	}
 }
 
+// merge 右节点 w 和父节点中的分隔项 parent.items[i] 合并到左节点 v
 func merge(parent, v, w *node, i int) {
-	sv := v.getSize()
-	sw := w.getSize()
-	// 合并孩子节点
-	copy(v.items[sv+1:], w.items[:sw])
-	v.count += sw
-	copy(v.children[sv+1:], w.children[:sw+1]) // fixme
-	v.items[sv] = parent.items[i]
-	v.count += 1
-	// 处理 parent
-	if i == 0 {
-		common.RemoveAt(parent.items, 0)
-		common.RemoveAt(parent.items, 0) // 移除一个之后，i-1也变成了0
-		parent.count -= 2
+	if w.isLeaf() {
+		v.appendChild(parent.items[i], nil)
+		for j := 0; j < w.count; j++ {
+			v.appendChild(w.items[j], nil)
+		}
 	} else {
-		common.RemoveAt(parent.items, i-1)
-		common.RemoveAt(parent.items, i-1)
-		parent.count -= 2
+		v.appendChild(parent.items[i], w.children[0])
+		for j := 1; j < w.count; j++ {
+			v.appendChild(w.items[j], w.children[j])
+		}
 	}
+	v.recount()
+	parent.remove(i)
 }
 
-// leftRotation 左旋，右孩子节点减少一个项，替换父指针，然后父指针项补充到左孩子
-// 然后重新达到平衡
+// leftRotation 左旋，右孩子 w 的第一项替换父节点中的分隔项 parent.items[i]，
+// 分隔项补充到左孩子 v 的末尾
 func leftRotation(parent, v, w *node, i int) {
-	sv := v.getSize()
-	sw := w.getSize()
-	shift := ((sw + sv) / 2) - sw
-	w.items[shift] = parent.items[i+1]
-	w.count++
-	smallest := v.remove(0)
-	parent.items[i+1] = smallest
+	if w.isLeaf() {
+		v.appendChild(parent.items[i], nil)
+		parent.items[i] = w.remove(0)
+	} else {
+		v.appendChild(parent.items[i], w.children[0])
+		parent.items[i] = w.items[1]
+		w.remove(0)
+		w.items[0] = nil
+	}
+	v.recount()
+	w.recount()
 }
 
+// rightRotation 右旋，左孩子 v 的最后一项替换父节点中的分隔项 parent.items[i]，
+// 分隔项补充到右孩子 w 的开头
 func rightRotation(parent, v, w *node, i int) {
-	sv := v.getSize()
-	sw := w.getSize()
-	shift := ((sw + sv) / 2) - sw
-	w.items[sw] = parent.items[i]
-	copy(w.items[sw+1:shift+sw], v.items)
-	copy(w.children[sw+1:shift+sw+1], v.children)
-	parent.items[i] = v.items[shift-1]
-	copy(v.items, v.items[shift:v.max])
-	copy(v.children, v.children[shift:v.max+1]) // ?fixme
+	last := v.count - 1
+	if w.isLeaf() {
+		w.insertAt(0, parent.items[i], nil)
+		parent.items[i] = v.remove(last)
+	} else {
+		// v 的最后一个孩子成为 w 最左边的孩子，原来的哨兵位置放入分隔项
+		w.items[0] = parent.items[i]
+		w.insertAt(0, nil, v.children[last])
+		parent.items[i] = v.items[last]
+		v.remove(last)
+	}
+	v.recount()
+	w.recount()
 }
 
+// findIndex 找到 key 时返回 -(下标+1)，否则返回插入的位置。
+// 内部节点从 1 开始查找，跳过哨兵
 func (n *node) findIndex(key int) int {
 	lo, hi := 0, n.count
+	if !n.isLeaf() {
+		lo = 1
+	}
 	for hi != lo {
 		m := (hi + lo) / 2
-		if n.items[m] == nil || key < n.items[m].key {
+		if key < n.items[m].key {
 			hi = m
 		} else if key > n.items[m].key {
 			lo = m + 1
@@ -238,67 +309,106 @@ func (n *node) findIndex(key int) int {
 	return lo
 }
 
+// add 在叶子节点中插入 kv，节点满了则分裂，返回新的右节点
 func (n *node) add(key int, val any) *node {
-	return n.addChild(key, val, nil)
+	i := n.findIndex(key)
+	if i < 0 {
+		panic("duplicate key")
+	}
+	n.insertAt(i, newItem(key, val), nil)
+	if n.full() {
+		return n.split()
+	}
+	return nil
 }
 
+// addChild 在内部节点中插入分隔项和它右边的孩子，节点满了则分裂，返回新的右节点
 func (n *node) addChild(key int, val any, child *node) *node {
 	i := n.findIndex(key)
 	if i < 0 {
 		panic("duplicate key")
 	}
-	if n.isLeaf() || child != nil { // 叶子节点，直接加入即可
-		n.items[i] = newItem(key, val)
-		if child != nil {
-			n.children[i] = child
-			child.parent = n
-		}
-		n.count++
-	} else {
-		if i >= n.count {
-			i = n.count - 1
-		}
-		// 新的子节点
-		w := n.children[i].addChild(key, val, child)
-		if w != nil {
-			x := n.children[i].removeLast()
-			n.addChild(x.key, x.value, w)
-		}
-	}
-
+	n.insertAt(i, newItem(key, val), child)
 	if n.full() {
 		return n.split()
 	}
-
 	return nil
 }
 
+// insertAt 在下标 i 处插入 item 和孩子
+func (n *node) insertAt(i int, it *item, child *node) {
+	copy(n.items[i+1:n.count+1], n.items[i:n.count])
+	copy(n.children[i+1:n.count+1], n.children[i:n.count])
+	n.items[i] = it
+	n.children[i] = child
+	if child != nil {
+		child.parent = n
+	}
+	n.count++
+}
+
+func (n *node) appendChild(it *item, child *node) {
+	n.insertAt(n.count, it, child)
+}
+
 func (n *node) split() *node {
 	// 5/2 = 2
 	m := n.max / 2
 	other := newNode(n.max)
 	other.parent = n.parent
-	copy(other.items, n.items[m:])
-	copy(other.children, n.children[m:])
+	copy(other.items, n.items[m:n.count])
+	copy(other.children, n.children[m:n.count])
 	other.count = n.count - m
+	for i := m; i < n.count; i++ {
+		n.items[i], n.children[i] = nil, nil
+	}
 	n.count = m
+	for i := 0; i < other.count; i++ {
+		if other.children[i] != nil {
+			other.children[i].parent = other
+		}
+	}
 	return other
 }
 
 func (n *node) remove(idx int) *item {
 	n.count--
 	common.RemoveAt(n.children, idx)
-	return common.RemoveAt(n.items, idx)
+	ret := common.RemoveAt(n.items, idx)
+	// RemoveAt 之后最后一个位置是重复的，清空
+	n.items[n.max-1], n.children[n.max-1] = nil, nil
+	return ret
 }
 
 func (n *node) removeLast() *item {
 	return n.remove(n.count - 1)
 }
 
+// recount 根据 item 和孩子的计数重新计算 size，内部节点不计哨兵
+func (n *node) recount() {
+	if n.isLeaf() {
+		n.size = n.count
+		return
+	}
+	n.size = n.count - 1
+	for i := 0; i < n.count; i++ {
+		n.size += n.children[i].size
+	}
+}
+
 func (n *node) getMax() int {
 	return n.max
 }
 
+// getMinSize 非根节点最少的项数，内部节点以孩子数计算。
+// 叶子节点分裂后左节点还要上移一项，因此比内部节点少一项
+func (n *node) getMinSize() int {
+	if n.isLeaf() {
+		return (n.max - 1) / 2
+	}
+	return n.max / 2
+}
+
 func (n *node) getSize() int {
 	return n.count
 }
@@ -308,7 +418,7 @@ func (n *node) full() bool {
 }
 
 func (n *node) underflow() bool {
-	return n.count < (n.max/2 - 1)
+	return n.count < n.getMinSize()
 }
 
 func (n *node) isLeaf() bool {
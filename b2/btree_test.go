@@ -1,6 +1,7 @@
 package b2
 
 import (
+	"math/rand"
 	"strconv"
 	"testing"
 
@@ -60,6 +61,21 @@ func TestBTree_Insert(t *testing.T) {
 	assert.True(ok)
 	ok = bTree.Insert(9, "9")
 	assert.True(ok)
+	assert.NoError(bTree.Verify())
+}
+
+// Insert 替换已有 key 时返回 false，min 小于 2 时按 2 处理
+func TestBTree_InsertReplace(t *testing.T) {
+	assert := assert.New(t)
+	bTree := NewBTree(1)
+	assert.Equal(2, bTree.min)
+	assert.False(bTree.Delete(1))
+	for i := 0; i < 20; i++ {
+		assert.True(bTree.Insert(i, i))
+	}
+	assert.False(bTree.Insert(7, "seven"))
+	assert.Equal("seven", bTree.Search(7))
+	assert.Equal(20, bTree.n)
 }
 
 func TestBTree_Delete(t *testing.T) {
@@ -89,6 +105,7 @@ func TestBTree_Delete(t *testing.T) {
 
 	ok = bTree.Delete(5)
 	assert.True(ok)
+	assert.NoError(bTree.Verify())
 }
 
 func TestBTree_Delete2(t *testing.T) {
@@ -110,6 +127,7 @@ func TestBTree_Delete2(t *testing.T) {
 	assert.True(ok)
 	ok = bTree.Delete(4)
 	assert.True(ok)
+	assert.NoError(bTree.Verify())
 }
 
 func TestBTree_Delete3(t *testing.T) {
@@ -131,4 +149,42 @@ func TestBTree_Delete3(t *testing.T) {
 	assert.True(ok)
 	ok = bTree.Delete(4)
 	assert.True(ok)
+	assert.NoError(bTree.Verify())
+}
+
+func TestBTree_Random(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, min := range []int{2, 3, 8} {
+		bTree := NewBTree(min)
+		m := map[int]string{}
+		for i := 0; i < 5000; i++ {
+			key := rand.Intn(500)
+			if rand.Intn(3) == 0 {
+				_, ok := m[key]
+				assert.Equal(ok, bTree.Delete(key))
+				delete(m, key)
+			} else {
+				_, ok := m[key]
+				assert.Equal(!ok, bTree.Insert(key, strconv.Itoa(key)))
+				m[key] = strconv.Itoa(key)
+			}
+			if i%100 == 0 {
+				assert.NoError(bTree.Verify())
+			}
+		}
+		assert.NoError(bTree.Verify())
+		for key := 0; key < 500; key++ {
+			if val, ok := m[key]; ok {
+				assert.Equal(val, bTree.Search(key))
+			} else {
+				assert.Nil(bTree.Search(key))
+			}
+		}
+		for key := range m {
+			assert.True(bTree.Delete(key))
+		}
+		assert.NoError(bTree.Verify())
+		assert.Equal(0, bTree.n)
+	}
 }
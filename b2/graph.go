@@ -0,0 +1,60 @@
+package b2
+
+import (
+	"strconv"
+
+	"github.com/pedrogao/btrees/common"
+)
+
+// Dot renders the tree in the Graphviz dot language
+func (t *BTree) Dot(options ...common.GraphOption[int]) string {
+	return t.graph(options).Dot()
+}
+
+// Mermaid renders the tree as a Mermaid flowchart, with the same options as Dot
+func (t *BTree) Mermaid(options ...common.GraphOption[int]) string {
+	return t.graph(options).Mermaid()
+}
+
+func (t *BTree) graph(options []common.GraphOption[int]) *common.Graph {
+	opts := common.NewGraphOptions(options...)
+	path := map[*node]bool{}
+	if opts.Path {
+		// 和 find 一样从根节点向下查找
+		for u := t.root; u != nil; {
+			path[u] = true
+			i := u.findIndex(opts.Key)
+			if i < 0 || u.isLeaf() {
+				break
+			}
+			u = u.children[i-1]
+		}
+	}
+	var changed map[*node]bool
+	if opts.Changes {
+		changed = t.changes.Nodes()
+	}
+	return common.BuildGraph(t.root, common.GraphSource[*node]{
+		Children: func(n *node) []*node {
+			if n.isLeaf() {
+				return nil
+			}
+			return n.children[:n.count]
+		},
+		Keys: func(n *node) []string {
+			// 内部节点跳过哨兵
+			start := 0
+			if !n.isLeaf() {
+				start = 1
+			}
+			keys := make([]string, 0, n.count)
+			for _, it := range n.items[start:n.count] {
+				keys = append(keys, strconv.Itoa(it.key))
+			}
+			return keys
+		},
+		Parent: func(n *node) *node {
+			return n.parent
+		},
+	}, path, changed, opts.ParentPointers)
+}
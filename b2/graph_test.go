@@ -0,0 +1,67 @@
+package b2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBTree_Graph(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tree := NewBTree(2)
+	assert.Empty(tree.graph(nil).Nodes)
+	for i := 0; i < 30; i++ {
+		tree.Insert(i, i)
+	}
+	require.NoError(tree.Verify())
+
+	// 查找路径从根节点到包含 key 的节点
+	g := tree.graph([]common.GraphOption[int]{common.HighlightPath(7), common.ShowParents[int]()})
+	assert.True(g.ParentPointers)
+	var path []common.GraphNode
+	for _, n := range g.Nodes {
+		if n.Path {
+			path = append(path, n)
+		}
+		// 孩子的父节点指针指向自己
+		for _, child := range n.Children {
+			assert.Equal(n.ID, g.Nodes[child-1].Parent)
+		}
+	}
+	require.NotEmpty(path)
+	assert.Equal(1, path[0].ID)
+	assert.Zero(path[0].Parent)
+	for i := 1; i < len(path); i++ {
+		assert.Equal(path[i-1].ID, path[i].Parent)
+	}
+	assert.Contains(path[len(path)-1].Keys, "7")
+
+	// 根节点分裂时新的根节点和分裂出的两个节点都被修改
+	tree = NewBTree(2)
+	for i := 0; tree.root == nil || tree.root.isLeaf(); i++ {
+		tree.Insert(i, i)
+	}
+	g = tree.graph([]common.GraphOption[int]{common.HighlightChanges[int]()})
+	require.Len(g.Nodes, 3)
+	for _, n := range g.Nodes {
+		assert.True(n.Changed)
+	}
+
+	// 删除到根节点合并
+	for !tree.root.isLeaf() {
+		tree.DeleteMin()
+	}
+	g = tree.graph([]common.GraphOption[int]{common.HighlightChanges[int]()})
+	require.Len(g.Nodes, 1)
+	assert.True(g.Nodes[0].Changed)
+
+	dot := tree.Dot(common.ShowParents[int]())
+	assert.True(strings.HasPrefix(dot, "digraph G {\n"))
+	assert.NotContains(dot, "dotted")
+	assert.Contains(tree.Mermaid(common.HighlightChanges[int]()), "class n1 changed")
+}
@@ -0,0 +1,24 @@
+package b2
+
+import (
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/pedrogao/btrees/internal/modeltest"
+)
+
+func modelTarget(min int) modeltest.Target[int] {
+	return modeltest.Target[int]{New: func() common.OrderedMap[int, any] {
+		return NewBTree(min)
+	}}
+}
+
+func TestBTree_Model(t *testing.T) {
+	for _, min := range []int{2, 3, 8} {
+		modeltest.RandomTest(t, modelTarget(min), 5, 2000)
+	}
+}
+
+func FuzzBTree(f *testing.F) {
+	modeltest.Fuzz(f, modelTarget(2))
+}
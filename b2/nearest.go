@@ -0,0 +1,99 @@
+package b2
+
+// Floor returns the largest key that is less than or equal to key
+func (t *BTree) Floor(key int) (int, any, bool) {
+	return t.seekBackward(key, false)
+}
+
+// Prev returns the largest key that is less than key
+func (t *BTree) Prev(key int) (int, any, bool) {
+	return t.seekBackward(key, true)
+}
+
+// Ceil returns the smallest key that is greater than or equal to key
+func (t *BTree) Ceil(key int) (int, any, bool) {
+	return t.seekForward(key, false)
+}
+
+// Next returns the smallest key that is greater than key
+func (t *BTree) Next(key int) (int, any, bool) {
+	return t.seekForward(key, true)
+}
+
+// seekForward 从根节点下降，记录路径上最后一个大于 key 的 item 作为候选
+func (t *BTree) seekForward(key int, strict bool) (int, any, bool) {
+	var candidate *item
+	for u := t.root; u != nil; {
+		i := u.findIndex(key)
+		if i < 0 {
+			j := -(i + 1)
+			if !strict {
+				return u.items[j].key, u.items[j].value, true
+			}
+			// 当作插入在 items[j] 之后，内部节点中接着在 items[j] 右边的子树中查找
+			i = j + 1
+		}
+		if i < u.count {
+			candidate = u.items[i]
+		}
+		if u.isLeaf() {
+			break
+		}
+		u = u.children[i-1]
+	}
+	if candidate == nil {
+		return 0, nil, false
+	}
+	return candidate.key, candidate.value, true
+}
+
+// seekBackward 与 seekForward 对称，记录路径上最后一个小于 key 的 item，内部节点跳过哨兵
+func (t *BTree) seekBackward(key int, strict bool) (int, any, bool) {
+	var candidate *item
+	for u := t.root; u != nil; {
+		i := u.findIndex(key)
+		if i < 0 {
+			j := -(i + 1)
+			if !strict {
+				return u.items[j].key, u.items[j].value, true
+			}
+			// 当作插入在 items[j] 之前，内部节点中接着在 items[j] 左边的子树中查找
+			i = j
+		}
+		lo := 0
+		if !u.isLeaf() {
+			lo = 1
+		}
+		if i-1 >= lo {
+			candidate = u.items[i-1]
+		}
+		if u.isLeaf() {
+			break
+		}
+		u = u.children[i-1]
+	}
+	if candidate == nil {
+		return 0, nil, false
+	}
+	return candidate.key, candidate.value, true
+}
+
+// DeleteMin deletes the smallest key and returns it with its value,
+// false if the tree is empty
+func (t *BTree) DeleteMin() (int, any, bool) {
+	key, value, ok := t.Min()
+	if ok {
+		t.Delete(key)
+	}
+	return key, value, ok
+}
+
+// DeleteMax deletes the largest key and returns it with its value,
+// false if the tree is empty
+func (t *BTree) DeleteMax() (int, any, bool) {
+	key, value, ok := t.Max()
+	if ok {
+		t.Delete(key)
+	}
+	return key, value, ok
+}
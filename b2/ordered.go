@@ -0,0 +1,72 @@
+package b2
+
+// Get returns the value of key and whether the key exists
+func (t *BTree) Get(key int) (any, bool) {
+	if it := t.find(key); it != nil {
+		return it.value, true
+	}
+	return nil, false
+}
+
+// Set stores key->val, it is the same as Insert
+func (t *BTree) Set(key int, val any) {
+	t.Insert(key, val)
+}
+
+// Len returns the number of keys
+func (t *BTree) Len() int {
+	return t.n
+}
+
+// Min returns the smallest key and its value, false if the tree is empty
+func (t *BTree) Min() (int, any, bool) {
+	if t.n == 0 {
+		return 0, nil, false
+	}
+	u := t.root
+	for !u.isLeaf() {
+		u = u.children[0]
+	}
+	return u.items[0].key, u.items[0].value, true
+}
+
+// Max returns the largest key and its value, false if the tree is empty
+func (t *BTree) Max() (int, any, bool) {
+	if t.n == 0 {
+		return 0, nil, false
+	}
+	u := t.root
+	for !u.isLeaf() {
+		u = u.children[u.count-1]
+	}
+	it := u.items[u.count-1]
+	return it.key, it.value, true
+}
+
+// Ascend calls fn for every key/value pair in ascending order until fn returns false
+func (t *BTree) Ascend(fn func(key int, val any) bool) {
+	if t.root != nil {
+		t.root.ascend(fn)
+	}
+}
+
+// ascend 中序遍历，内部节点中先遍历 children[i-1]，再访问 items[i]
+func (n *node) ascend(fn func(key int, val any) bool) bool {
+	if n.isLeaf() {
+		for i := 0; i < n.count; i++ {
+			if !fn(n.items[i].key, n.items[i].value) {
+				return false
+			}
+		}
+		return true
+	}
+	for i := 0; i < n.count; i++ {
+		if i > 0 && !fn(n.items[i].key, n.items[i].value) {
+			return false
+		}
+		if !n.children[i].ascend(fn) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package b2
+
+// Rank returns the number of keys that are less than key
+func (t *BTree) Rank(key int) int {
+	rank := 0
+	u := t.root
+	for u != nil {
+		i := u.findIndex(key)
+		if u.isLeaf() {
+			if i < 0 {
+				i = -(i + 1)
+			}
+			return rank + i
+		}
+		if i < 0 {
+			// items[j] 左边有 children[:j] 和 items[1:j]
+			j := -(i + 1)
+			return rank + j - 1 + u.sizeBefore(j)
+		}
+		// children[i-1] 左边有 children[:i-1] 和 items[1:i]
+		rank += i - 1 + u.sizeBefore(i-1)
+		u = u.children[i-1]
+	}
+	return rank
+}
+
+// Select returns the i-th smallest key and its value counting from 0,
+// false if i is out of range
+func (t *BTree) Select(i int) (int, any, bool) {
+	if i < 0 || i >= t.n {
+		return 0, nil, false
+	}
+	u := t.root
+	for !u.isLeaf() {
+		j := 0
+		for ; i >= u.children[j].size; j++ {
+			// 跳过 children[j]，下一个是 items[j+1]
+			i -= u.children[j].size
+			if i == 0 {
+				it := u.items[j+1]
+				return it.key, it.value, true
+			}
+			i--
+		}
+		u = u.children[j]
+	}
+	return u.items[i].key, u.items[i].value, true
+}
+
+// CountRange returns the number of keys in [lo, hi)
+func (t *BTree) CountRange(lo, hi int) int {
+	if lo >= hi {
+		return 0
+	}
+	return t.Rank(hi) - t.Rank(lo)
+}
+
+// sizeBefore 返回 children[:j] 中 kv 对的数量
+func (n *node) sizeBefore(j int) int {
+	size := 0
+	for _, child := range n.children[:j] {
+		size += child.size
+	}
+	return size
+}
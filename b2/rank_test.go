@@ -0,0 +1,43 @@
+package b2
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBTree_Rank(t *testing.T) {
+	assert := assert.New(t)
+	bTree := NewBTree(2)
+	for i := 0; i < 100; i++ {
+		bTree.Insert(i*2, i)
+	}
+	for i := 0; i < 100; i++ {
+		assert.Equal(i, bTree.Rank(i*2))
+		assert.Equal(i+1, bTree.Rank(i*2+1))
+		key, value, ok := bTree.Select(i)
+		assert.True(ok)
+		assert.Equal(i*2, key)
+		assert.Equal(i, value)
+	}
+	_, _, ok := bTree.Select(100)
+	assert.False(ok)
+	assert.Equal(5, bTree.CountRange(10, 20))
+	assert.Equal(0, bTree.CountRange(20, 10))
+
+	r := rand.New(rand.NewSource(1))
+	for _, i := range r.Perm(100)[:50] {
+		assert.True(bTree.Delete(i * 2))
+	}
+	assert.NoError(bTree.Verify())
+	i := 0
+	bTree.Ascend(func(key int, val any) bool {
+		assert.Equal(i, bTree.Rank(key))
+		k, _, _ := bTree.Select(i)
+		assert.Equal(key, k)
+		i++
+		return true
+	})
+	assert.Equal(50, i)
+}
@@ -0,0 +1,113 @@
+package b2
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrCorrupt = errors.New("b2: tree is corrupt")
+
+// Verify checks the structural invariants of the tree: keys are ordered
+// within and across nodes, non-root nodes are at least half full, parent
+// pointers are consistent, all leaves are at the same depth and the subtree
+// sizes match the number of keys
+func (t *BTree) Verify() error {
+	if t.root == nil {
+		if t.n != 0 {
+			return fmt.Errorf("%w: nil root with %d keys", ErrCorrupt, t.n)
+		}
+		return nil
+	}
+	if t.root.parent != nil {
+		return fmt.Errorf("%w: root has a parent", ErrCorrupt)
+	}
+	if !t.root.isLeaf() && t.root.count < 2 {
+		return fmt.Errorf("%w: internal root has %d children", ErrCorrupt, t.root.count)
+	}
+	v := verifier{leafDepth: -1}
+	if err := v.verify(t.root, nil, nil, 0); err != nil {
+		return err
+	}
+	if v.count != t.n {
+		return fmt.Errorf("%w: found %d keys, expected %d", ErrCorrupt, v.count, t.n)
+	}
+	return nil
+}
+
+type verifier struct {
+	leafDepth int
+	count     int
+}
+
+// verify 检查以 n 为根的子树，子树中的 key 都在 (lo, hi) 之内，nil 表示没有边界
+func (v *verifier) verify(n *node, lo, hi *int, depth int) error {
+	if n.count >= n.max {
+		return fmt.Errorf("%w: node of size %d is full", ErrCorrupt, n.count)
+	}
+	if n.parent != nil && n.underflow() {
+		return fmt.Errorf("%w: node of size %d is under %d", ErrCorrupt, n.count, n.getMinSize())
+	}
+	before := v.count
+	// 内部节点跳过哨兵
+	start := 0
+	if !n.isLeaf() {
+		if n.items[0] != nil {
+			return fmt.Errorf("%w: internal node without sentinel", ErrCorrupt)
+		}
+		start = 1
+	}
+	prev := lo
+	for i := start; i < n.count; i++ {
+		key := n.items[i].key
+		if prev != nil && key <= *prev {
+			return fmt.Errorf("%w: key %d is out of order", ErrCorrupt, key)
+		}
+		if hi != nil && key >= *hi {
+			return fmt.Errorf("%w: key %d is out of order", ErrCorrupt, key)
+		}
+		prev = &n.items[i].key
+	}
+	v.count += n.count - start
+
+	if n.isLeaf() {
+		for i := 0; i < n.count; i++ {
+			if n.children[i] != nil {
+				return fmt.Errorf("%w: leaf has a child", ErrCorrupt)
+			}
+		}
+		if v.leafDepth == -1 {
+			v.leafDepth = depth
+		} else if v.leafDepth != depth {
+			return fmt.Errorf("%w: leaves at depth %d and %d", ErrCorrupt, v.leafDepth, depth)
+		}
+		return v.checkSize(n, before)
+	}
+	for i := 0; i < n.count; i++ {
+		child := n.children[i]
+		if child == nil {
+			return fmt.Errorf("%w: internal node misses child %d", ErrCorrupt, i)
+		}
+		if child.parent != n {
+			return fmt.Errorf("%w: child %d has a wrong parent", ErrCorrupt, i)
+		}
+		clo, chi := lo, hi
+		if i > 0 {
+			clo = &n.items[i].key
+		}
+		if i+1 < n.count {
+			chi = &n.items[i+1].key
+		}
+		if err := v.verify(child, clo, chi, depth+1); err != nil {
+			return err
+		}
+	}
+	return v.checkSize(n, before)
+}
+
+// checkSize 检查 n 的 size 等于遍历子树时数到的 key 数
+func (v *verifier) checkSize(n *node, before int) error {
+	if n.size != v.count-before {
+		return fmt.Errorf("%w: node size %d, counted %d", ErrCorrupt, n.size, v.count-before)
+	}
+	return nil
+}
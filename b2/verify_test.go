@@ -0,0 +1,45 @@
+package b2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBTree_VerifyCorrupt(t *testing.T) {
+	build := func() *BTree {
+		bTree := NewBTree(2)
+		for i := 0; i < 50; i++ {
+			bTree.Insert(i, i)
+		}
+		assert.NoError(t, bTree.Verify())
+		return bTree
+	}
+
+	corruptions := map[string]func(bTree *BTree){
+		"node order": func(bTree *BTree) {
+			items := bTree.root.items
+			items[1], items[2] = items[2], items[1]
+		},
+		"parent range": func(bTree *BTree) {
+			bTree.root.children[0].items[1].key = 1000
+		},
+		"parent pointer": func(bTree *BTree) {
+			bTree.root.children[1].parent = nil
+		},
+		"underflow": func(bTree *BTree) {
+			bTree.root.children[0].count = 1
+		},
+		"count": func(bTree *BTree) {
+			bTree.n++
+		},
+		"size": func(bTree *BTree) {
+			bTree.root.children[0].size++
+		},
+	}
+	for name, corrupt := range corruptions {
+		bTree := build()
+		corrupt(bTree)
+		assert.ErrorIs(t, bTree.Verify(), ErrCorrupt, name)
+	}
+}
@@ -0,0 +1,91 @@
+package bptree
+
+import "slices"
+
+// WriteBatch collects puts and deletes and applies them to a BPTree at once.
+// The zero value is an empty batch
+type WriteBatch[K, V any] struct {
+	ops []batchOp[K, V]
+}
+
+type batchOp[K, V any] struct {
+	key    K
+	value  V
+	delete bool
+}
+
+// Put adds key->value to the batch, it behaves like Insert when applied
+func (b *WriteBatch[K, V]) Put(key K, value V) {
+	b.ops = append(b.ops, batchOp[K, V]{key: key, value: value})
+}
+
+// Delete adds the deletion of key to the batch
+func (b *WriteBatch[K, V]) Delete(key K) {
+	b.ops = append(b.ops, batchOp[K, V]{key: key, delete: true})
+}
+
+// Len returns the number of operations in the batch
+func (b *WriteBatch[K, V]) Len() int {
+	return len(b.ops)
+}
+
+// Reset empties the batch so it can be reused
+func (b *WriteBatch[K, V]) Reset() {
+	b.ops = b.ops[:0]
+}
+
+// Apply applies the batch to the tree in key order, operations on the same key
+// keep the order they were added in. In concurrent mode the whole tree is
+// latched while the batch is applied, so every lookup and write sees either
+// none or all of it
+func (b *WriteBatch[K, V]) Apply(t *BPTree[K, V]) {
+	ops := slices.Clone(b.ops)
+	slices.SortStableFunc(ops, func(x, y batchOp[K, V]) int { return t.compare(x.key, y.key) })
+	if t.concurrent {
+		t.batchLatch.Lock()
+		defer t.batchLatch.Unlock()
+		t.rootLatch.Lock()
+		defer t.rootLatch.Unlock()
+	}
+	t.applyBatch(ops)
+}
+
+// applyBatch 按 key 的顺序修改，下一个 key 仍然落在当前叶子节点中，
+// 并且上一次修改没有分裂或者合并节点时，不需要从根节点重新下降。
+// 并发模式下已经持有 batchLatch 的写锁，不再需要节点的锁
+func (t *BPTree[K, V]) applyBatch(ops []batchOp[K, V]) {
+	var leaf *leafNode[K, V]
+	for _, op := range ops {
+		if leaf == nil || !t.leafCovers(leaf, op.key) {
+			if t.root == nil {
+				if !op.delete {
+					t.startRoot(op.key, op.value)
+				}
+				continue
+			}
+			leaf = t.findLeaf(op.key)
+		}
+		if op.delete {
+			safe := t.deleteSafe(leaf)
+			if t.deleteFromLeaf(leaf, op.key, nil, nil) && !safe {
+				leaf = nil
+			}
+			continue
+		}
+		t.insertIntoLeafNode(leaf, op.key, op.value)
+		t.lowerFirstKeys(leaf, op.key)
+		if leaf.full() {
+			t.splitLeaf(leaf)
+			leaf = nil
+		}
+	}
+}
+
+// leafCovers key 一定属于 leaf：在第一个和最后一个 key 之间，
+// 或者 leaf 是最右边的叶子节点并且 key 不小于第一个 key
+func (t *BPTree[K, V]) leafCovers(leaf *leafNode[K, V], key K) bool {
+	if leaf.count == 0 || t.compare(key, leaf.kvs[0].key) < 0 {
+		return false
+	}
+	return t.compare(key, leaf.kvs[leaf.count-1].key) <= 0 || leaf.next == nil
+}
@@ -0,0 +1,116 @@
+package bptree
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteBatch(t *testing.T) {
+	for _, size := range []int{3, 4, 7} {
+		r := rand.New(rand.NewSource(int64(size)))
+		bt := NewBPTree[int, int](MaxInternal(size), MaxLeaf(size))
+		m := map[int]int{}
+		var b WriteBatch[int, int]
+		for round := 0; round < 50; round++ {
+			b.Reset()
+			for i := 0; i < 100; i++ {
+				key := r.Intn(1000)
+				if r.Intn(3) == 0 {
+					b.Delete(key)
+					delete(m, key)
+				} else {
+					b.Put(key, i)
+					m[key] = i
+				}
+			}
+			b.Apply(bt)
+
+			keys := make([]int, 0, len(m))
+			for key := range m {
+				keys = append(keys, key)
+			}
+			sort.Ints(keys)
+			checkLinks(t, bt, keys)
+			for key, value := range m {
+				v, ok := bt.Search(key)
+				assert.True(t, ok)
+				assert.Equal(t, value, v)
+			}
+		}
+	}
+}
+
+func TestWriteBatch_Order(t *testing.T) {
+	assert := assert.New(t)
+	bt := NewBPTree[int, string](MaxInternal(3), MaxLeaf(3))
+	var b WriteBatch[int, string]
+	b.Put(2, "a")
+	b.Put(1, "b")
+	b.Delete(2)
+	b.Put(2, "c")
+	b.Delete(1)
+	assert.Equal(5, b.Len())
+	b.Apply(bt)
+
+	v, ok := bt.Search(2)
+	assert.True(ok)
+	assert.Equal("c", v)
+	_, ok = bt.Search(1)
+	assert.False(ok)
+
+	// 多值模式下 Put 与 Insert 相同，追加 value
+	mt := NewMultiBPTree[int, string](MaxInternal(3), MaxLeaf(3))
+	b.Reset()
+	b.Put(1, "x")
+	b.Put(1, "y")
+	b.Apply(mt)
+	assert.Equal([]string{"x", "y"}, mt.SearchAll(1))
+}
+
+// 每个批次插入或者删除 100 个 key，读者看到的数量总是 100 的倍数
+func TestWriteBatch_Concurrent(t *testing.T) {
+	const batch = 100
+	bt := NewBPTree[int, int](MaxInternal(4), MaxLeaf(4), Concurrent())
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(w)))
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				assert.Zero(t, bt.Len()%batch)
+				assert.Zero(t, bt.Rank(1<<30)%batch)
+				bt.Search(r.Intn(batch * 10))
+			}
+		}(w)
+	}
+
+	var b WriteBatch[int, int]
+	for round := 0; round < 200; round++ {
+		b.Reset()
+		base := round % 10 * batch
+		for i := 0; i < batch; i++ {
+			if round/10%2 == 0 {
+				b.Put(base+i, round)
+			} else {
+				b.Delete(base + i)
+			}
+		}
+		b.Apply(bt)
+	}
+	close(done)
+	wg.Wait()
+	assert.NoError(t, bt.Verify())
+}
@@ -0,0 +1,89 @@
+package bptree
+
+import (
+	"cmp"
+	"errors"
+	"iter"
+
+	"github.com/pedrogao/btrees/common"
+)
+
+var ErrNotSorted = errors.New("bptree: bulk load keys are not strictly increasing")
+
+// BulkLoad builds a b+ tree from key/value pairs sorted by the natural order of K
+func BulkLoad[K cmp.Ordered, V any](seq iter.Seq2[K, V], options ...Option) (*BPTree[K, V], error) {
+	return BulkLoadFunc(cmp.Compare[K], seq, options...)
+}
+
+// BulkLoadFunc builds a b+ tree from key/value pairs sorted by compare.
+// Leaves are filled from left to right to the fill factor while seq is read,
+// only the pairs of the last two leaves are buffered. Then the internal levels
+// are built bottom-up, which is much faster than repeated Insert and leaves no
+// half-full nodes behind
+func BulkLoadFunc[K, V any](compare func(a, b K) int, seq iter.Seq2[K, V], options ...Option) (*BPTree[K, V], error) {
+	t := NewBPTreeFunc[K, V](compare, options...)
+
+	// 叶子节点 count 达到 max 时就会分裂，因此最多容纳 max-1 项
+	var (
+		level   []node[K, V]
+		prev    *leafNode[K, V]
+		min     = t.maxLeaf / 2
+		per     = common.FillCount(t.fill, min, t.maxLeaf-1)
+		entries []kv[K, V]
+		n       int
+	)
+	addLeaf := func(entries []kv[K, V]) {
+		leaf := newLeafNode[K, V](t.maxLeaf, compare)
+		leaf.count = copy(leaf.kvs, entries)
+		leaf.recount()
+		leaf.prev = prev
+		if prev != nil {
+			prev.next = leaf
+		}
+		prev = leaf
+		level = append(level, leaf)
+	}
+	for key, value := range seq {
+		// 写出叶子节点后 entries 中仍然留着 per 项，最后一项就是上一个 key
+		if len(entries) > 0 && compare(entries[len(entries)-1].key, key) >= 0 {
+			return nil, ErrNotSorted
+		}
+		entries = append(entries, kv[K, V]{key: key, value: value})
+		n++
+		// 留下至少一个节点的项，最后两个节点可能需要平分
+		if len(entries) == 2*per {
+			addLeaf(entries[:per])
+			entries = append(entries[:0], entries[per:]...)
+		}
+	}
+	if n == 0 {
+		return t, nil
+	}
+	for _, size := range common.FillSizes(len(entries), per, min, t.maxLeaf-1) {
+		addLeaf(entries[:size])
+		entries = entries[size:]
+	}
+
+	// 自底向上逐层构建内部节点，直到只剩一个节点作为根节点
+	min = t.maxInternal / 2
+	per = common.FillCount(t.fill, min, t.maxInternal-1)
+	if per < 2 {
+		per = 2
+	}
+	for len(level) > 1 {
+		var parents []node[K, V]
+		for _, size := range common.FillSizes(len(level), per, min, t.maxInternal-1) {
+			parent := newInternalNode[K, V](t.maxInternal, compare)
+			for _, child := range level[:size] {
+				parent.insert(child.getFirstKey(), child)
+			}
+			parent.recount()
+			level = level[size:]
+			parents = append(parents, parent)
+		}
+		level = parents
+	}
+	t.root = level[0]
+	t.size.Store(int64(n))
+	return t, nil
+}
@@ -0,0 +1,101 @@
+package bptree
+
+import (
+	"iter"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sortedPairs(keys []int) iter.Seq2[int, int] {
+	return func(yield func(int, int) bool) {
+		for _, k := range keys {
+			if !yield(k, k*10) {
+				return
+			}
+		}
+	}
+}
+
+func TestBulkLoad(t *testing.T) {
+	for _, size := range [][2]int{{3, 3}, {4, 3}, {3, 5}, {7, 7}, {0, 0}} {
+		for _, fill := range []float64{0.5, 0.9, 1} {
+			for _, n := range []int{0, 1, 2, 5, 100, 3000} {
+				keys := make([]int, n)
+				for i := range keys {
+					keys[i] = i*3 - n
+				}
+				bt, err := BulkLoad(sortedPairs(keys), MaxLeaf(size[0]), MaxInternal(size[1]), FillFactor(fill))
+				require.NoError(t, err)
+				require.NoError(t, bt.Verify(), "size %v fill %v n %d", size, fill, n)
+				if n == 0 {
+					assert.True(t, bt.Empty())
+					continue
+				}
+				checkLinks(t, bt, keys)
+
+				// 除了最后两个叶子节点，其余叶子节点都按填充因子装满
+				per := common.FillCount(fill, bt.maxLeaf/2, bt.maxLeaf-1)
+				var counts []int
+				for leaf := bt.First(); leaf != nil; leaf = leaf.next {
+					counts = append(counts, leaf.count)
+				}
+				for i, count := range counts {
+					if i < len(counts)-2 {
+						assert.Equal(t, per, count)
+					}
+					assert.Less(t, count, bt.maxLeaf)
+					if len(counts) > 1 {
+						assert.GreaterOrEqual(t, count, bt.maxLeaf/2)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestBulkLoad_ThenModify(t *testing.T) {
+	for _, size := range []int{3, 4, 7} {
+		r := rand.New(rand.NewSource(int64(size)))
+		keys := make([]int, 1000)
+		m := map[int]bool{}
+		for i := range keys {
+			keys[i] = i * 2
+			m[i*2] = true
+		}
+		bt, err := BulkLoad(sortedPairs(keys), MaxLeaf(size), MaxInternal(size), FillFactor(1))
+		require.NoError(t, err)
+		for i := 0; i < 3000; i++ {
+			k := r.Intn(2500) - 250
+			if r.Intn(2) == 0 {
+				bt.Delete(k)
+				delete(m, k)
+			} else {
+				bt.Insert(k, k*10)
+				m[k] = true
+			}
+		}
+		want := make([]int, 0, len(m))
+		for k := range m {
+			want = append(want, k)
+		}
+		sort.Ints(want)
+		checkLinks(t, bt, want)
+		for _, k := range want {
+			v, ok := bt.Search(k)
+			assert.True(t, ok)
+			assert.Equal(t, k*10, v)
+		}
+	}
+}
+
+func TestBulkLoad_NotSorted(t *testing.T) {
+	_, err := BulkLoad(sortedPairs([]int{1, 3, 2}))
+	assert.ErrorIs(t, err, ErrNotSorted)
+	_, err = BulkLoad(sortedPairs([]int{1, 1}))
+	assert.ErrorIs(t, err, ErrNotSorted)
+}
@@ -0,0 +1,81 @@
+package bptree
+
+import (
+	"fmt"
+
+	"github.com/pedrogao/btrees/common"
+)
+
+// Dot renders the tree in the Graphviz dot language, leaves are linked by
+// dashed edges. Unlike Graph it can highlight a search path, the nodes
+// touched by the last split or merge and the parent pointers.
+// It must not run concurrently with writes
+func (t *BPTree[K, V]) Dot(options ...common.GraphOption[K]) string {
+	return t.exportGraph(options).Dot()
+}
+
+// Mermaid renders the tree as a Mermaid flowchart, with the same options as Dot
+func (t *BPTree[K, V]) Mermaid(options ...common.GraphOption[K]) string {
+	return t.exportGraph(options).Mermaid()
+}
+
+func (t *BPTree[K, V]) exportGraph(options []common.GraphOption[K]) *common.Graph {
+	opts := common.NewGraphOptions(options...)
+	path := map[node[K, V]]bool{}
+	if opts.Path {
+		// 和 findLeaf 一样从根节点向下查找
+		for n := t.root; n != nil; {
+			path[n] = true
+			inter, ok := n.(*internalNode[K, V])
+			if !ok {
+				break
+			}
+			n = inter.lookup(opts.Key)
+		}
+	}
+	var changed map[node[K, V]]bool
+	if opts.Changes {
+		changed = t.changes.Nodes()
+	}
+	return common.BuildGraph(t.root, common.GraphSource[node[K, V]]{
+		Children: func(n node[K, V]) []node[K, V] {
+			inter, ok := n.(*internalNode[K, V])
+			if !ok {
+				return nil
+			}
+			children := make([]node[K, V], inter.count)
+			for i := range children {
+				children[i] = inter.kcs[i].child
+			}
+			return children
+		},
+		Keys: func(n node[K, V]) []string {
+			var keys []string
+			switch n := n.(type) {
+			case *leafNode[K, V]:
+				for i := 0; i < n.count; i++ {
+					keys = append(keys, fmt.Sprint(n.kvs[i].key))
+				}
+			case *internalNode[K, V]:
+				// k0 只是下界，不参与路由
+				for i := 1; i < n.count; i++ {
+					keys = append(keys, fmt.Sprint(n.kcs[i].key))
+				}
+			}
+			return keys
+		},
+		Parent: func(n node[K, V]) node[K, V] {
+			// 避免 nil 指针转换成非 nil 的接口
+			if p := n.parent(); p != nil {
+				return p
+			}
+			return nil
+		},
+		Next: func(n node[K, V]) node[K, V] {
+			if leaf, ok := n.(*leafNode[K, V]); ok && leaf.next != nil {
+				return leaf.next
+			}
+			return nil
+		},
+	}, path, changed, opts.ParentPointers)
+}
@@ -0,0 +1,74 @@
+package bptree
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBPTree_Graph(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tree := NewBPTree[int, int](MaxInternal(3), MaxLeaf(3))
+	assert.Empty(tree.exportGraph(nil).Nodes)
+	for i := 0; i < 30; i++ {
+		tree.Insert(i, i)
+	}
+	require.NoError(tree.Verify())
+
+	// 查找路径从根节点到包含 key 的叶子节点
+	g := tree.exportGraph([]common.GraphOption[int]{common.HighlightPath(7), common.ShowParents[int]()})
+	var (
+		path   []common.GraphNode
+		leaves int
+	)
+	for _, n := range g.Nodes {
+		if n.Path {
+			path = append(path, n)
+		}
+		for _, child := range n.Children {
+			assert.Equal(n.ID, g.Nodes[child-1].Parent)
+		}
+		if n.Leaf {
+			leaves++
+		}
+	}
+	require.NotEmpty(path)
+	assert.Equal(1, path[0].ID)
+	for i := 1; i < len(path); i++ {
+		assert.Contains(path[i-1].Children, path[i].ID)
+	}
+	last := path[len(path)-1]
+	assert.True(last.Leaf)
+	assert.Contains(last.Keys, "7")
+
+	// 叶子节点按顺序链接，最后一个叶子没有 next
+	dot := tree.Dot(common.ShowParents[int]())
+	assert.Equal(leaves-1, strings.Count(dot, "style=dashed"))
+	assert.Equal(len(g.Nodes)-1, strings.Count(dot, "style=dotted"))
+
+	// 根节点分裂时新的根节点和分裂出的两个节点都被修改
+	tree = NewBPTree[int, int](MaxInternal(3), MaxLeaf(3))
+	for i := 0; tree.root == nil || tree.root.isLeaf(); i++ {
+		tree.Insert(i, i)
+	}
+	g = tree.exportGraph([]common.GraphOption[int]{common.HighlightChanges[int]()})
+	require.Len(g.Nodes, 3)
+	for _, n := range g.Nodes {
+		assert.True(n.Changed)
+	}
+
+	// 删除到叶子节点合并，剩下的根节点被修改
+	for !tree.root.isLeaf() {
+		key, _, _ := tree.Min()
+		tree.Delete(key)
+	}
+	g = tree.exportGraph([]common.GraphOption[int]{common.HighlightChanges[int]()})
+	require.Len(g.Nodes, 1)
+	assert.True(g.Nodes[0].Changed)
+	assert.Contains(tree.Mermaid(common.HighlightChanges[int]()), "class n1 changed")
+}
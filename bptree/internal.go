@@ -3,108 +3,94 @@ package bptree
 import (
 	"fmt"
 	"sort"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/pedrogao/btrees/common"
 )
 
-type kc struct {
-	key   int
-	child node
+type kc[K, V any] struct {
+	key   K
+	child node[K, V]
 }
 
 // one empty slot for split
-type kcs []kc
+type kcs[K, V any] []kc[K, V]
 
-func (a kcs) Len() int { return len(a) }
+func (a kcs[K, V]) Len() int { return len(a) }
 
-func (a kcs) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a kcs[K, V]) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 
-func (a kcs) Less(i, j int) bool {
-	if a[i].key == 0 {
-		return false
-	}
-
-	if a[j].key == 0 {
-		return true
-	}
-
-	return a[i].key < a[j].key
-}
-
-// internalNode 第一个 key 为空
-// +---++----++----++----+
-// |   || v1 || k1 || v2 |
-// +---++----++----++----+
-type internalNode struct {
-	kcs        kcs           // kv键值对
-	max, count int           // kv最大数量、数量
-	p          *internalNode // 父节点
+// internalNode 第一个 key 只作为下界，不参与路由
+// +----++----++----++----+
+// | k0 || v1 || k1 || v2 |
+// +----++----++----++----+
+// k0 不大于第一个孩子中的任何 key，插入更小的 key 时会被调低，
+// 因此 kcs 始终有序，且不依赖某个特殊的零值作为哨兵
+type internalNode[K, V any] struct {
+	latch
+	kcs        kcs[K, V]           // kv键值对
+	max, count int                 // kv最大数量、数量
+	p          *internalNode[K, V] // 父节点
+	compare    func(a, b K) int    // key 比较函数
+	total      atomic.Int64        // 子树中 kv 对的数量，并发模式下可能在只持有孩子的锁时读取
 }
 
-func newInternalNode(max int) *internalNode {
+func newInternalNode[K, V any](max int, compare func(a, b K) int) *internalNode[K, V] {
 	// 但判断内部节点的标注仍以 key 为准，即以 key 作为 full，split 的标准
-	i := &internalNode{
-		max:   max,
-		count: 0,
-		kcs:   make([]kc, max),
+	i := &internalNode[K, V]{
+		max:     max,
+		count:   0,
+		kcs:     make([]kc[K, V], max),
+		compare: compare,
 	}
 
 	return i
 }
 
-func (n *internalNode) find(key int) (int, bool) {
+func (n *internalNode[K, V]) find(key K) (int, bool) {
 	// 如果没有任何数据，直接返回 0，false，即 0 号位插入，且未找到
 	if n.count == 0 {
 		return 0, false
 	}
 	// todo >= or >
-	c := func(i int) bool { return n.kcs[i].key >= key }
+	c := func(i int) bool { return n.compare(n.kcs[i].key, key) >= 0 }
 	i := sort.Search(n.count, c)
-	if i < n.count && n.kcs[i].key == key {
+	if i < n.count && n.compare(n.kcs[i].key, key) == 0 {
 		return i, true
 	}
 
 	return i, false
 }
 
-func (n *internalNode) lookup(key int) node {
-	// 如果没有任何数据，直接返回 0，false，即 0 号位插入，且未找到
+func (n *internalNode[K, V]) lookup(key K) node[K, V] {
+	// 如果没有任何数据，直接返回 nil
 	if n.count == 0 {
 		return nil
 	}
+	return n.kcs[n.lookupIndex(key)].child
+}
 
-	c := func(i int) bool { return n.kcs[i].key >= key }
+// lookupIndex 返回 key 所在孩子的下标，n 不能为空
+func (n *internalNode[K, V]) lookupIndex(key K) int {
+	c := func(i int) bool { return n.compare(n.kcs[i].key, key) > 0 }
+	// 第一个大于 key 的位置，它左边的孩子包含 key；比 k0 还小的 key 也在第一个孩子中
 	i := sort.Search(n.count, c)
-
-	if i < n.count && n.kcs[i].key == key {
-		return n.kcs[i].child
-	}
-
 	if i == 0 {
-		return n.kcs[0].child
+		return 0
 	}
-
-	if i < n.count && n.kcs[i].key > key {
-		return n.kcs[i-1].child
-	}
-
-	if i >= n.count {
-		return n.kcs[n.count-1].child
-	}
-
-	return nil
+	return i - 1
 }
 
-func (n *internalNode) full() bool { return n.count >= n.max }
+func (n *internalNode[K, V]) full() bool { return n.count >= n.max }
 
-func (n *internalNode) halfFull() bool { return n.count >= n.max/2 }
+func (n *internalNode[K, V]) halfFull() bool { return n.count >= n.max/2 }
 
-func (n *internalNode) parent() *internalNode { return n.p }
+func (n *internalNode[K, V]) parent() *internalNode[K, V] { return n.p }
 
-func (n *internalNode) setParent(p *internalNode) { n.p = p }
+func (n *internalNode[K, V]) setParent(p *internalNode[K, V]) { n.p = p }
 
-func (n *internalNode) insert(key int, child node) bool {
+func (n *internalNode[K, V]) insert(key K, child node[K, V]) bool {
 	// 即使 key 重复，仍然需要插入，因此 b+tree 的内部节点就是会重复的
 	i, _ := n.find(key)
 	if i >= n.max {
@@ -118,13 +104,13 @@ func (n *internalNode) insert(key int, child node) bool {
 	return true
 }
 
-func (n *internalNode) split() (*internalNode, int) {
+func (n *internalNode[K, V]) split() (*internalNode[K, V], K) {
 	// 3/2 => 1
 	midIndex := n.count / 2
 	midKey := n.kcs[midIndex].key
 
 	// create the split node without a parent
-	next := newInternalNode(n.max)
+	next := newInternalNode[K, V](n.max, n.compare)
 	copy(next.kcs, n.kcs[midIndex:])
 	next.count = n.count - midIndex
 	// update parent
@@ -136,39 +122,39 @@ func (n *internalNode) split() (*internalNode, int) {
 	return next, midKey
 }
 
-func (n *internalNode) getMaxSize() int {
+func (n *internalNode[K, V]) getMaxSize() int {
 	return n.max
 }
 
-func (n *internalNode) getMinSize() int {
+func (n *internalNode[K, V]) getMinSize() int {
 	return n.max / 2
 }
 
-func (n *internalNode) isRoot() bool {
+func (n *internalNode[K, V]) isRoot() bool {
 	return n.p == nil
 }
 
-func (n *internalNode) getSize() int {
+func (n *internalNode[K, V]) getSize() int {
 	return n.count
 }
 
-func (n *internalNode) valueIndex(val node) int {
-	for i, item := range n.kcs {
-		if item.child == val {
+func (n *internalNode[K, V]) valueIndex(val node[K, V]) int {
+	for i := 0; i < n.count; i++ {
+		if n.kcs[i].child == val {
 			return i
 		}
 	}
 	return -1
 }
 
-func (n *internalNode) setKeyAt(index int, val node) {
+func (n *internalNode[K, V]) setKeyAt(index int, val node[K, V]) {
 	firstKey := val.getFirstKey()
 	n.kcs[index].key = firstKey
 	n.kcs[index].child = val
 }
 
-func (n *internalNode) moveLastToFrontOf(n2 node) {
-	other, ok := n2.(*internalNode)
+func (n *internalNode[K, V]) moveLastToFrontOf(n2 node[K, V]) {
+	other, ok := n2.(*internalNode[K, V])
 	if !ok {
 		return
 	}
@@ -177,39 +163,43 @@ func (n *internalNode) moveLastToFrontOf(n2 node) {
 	other.resize(1)
 	copy(other.kcs[1:], other.kcs)
 	other.kcs[0] = removeItem
+	removeItem.child.setParent(other)
 }
 
-func (n *internalNode) remove(n2 node) {
+func (n *internalNode[K, V]) remove(n2 node[K, V]) {
 	idx := n.valueIndex(n2)
 	common.RemoveAt(n.kcs, idx)
 	n.count--
 }
 
-func (n *internalNode) moveAllTo(neighbor node) {
-	other, ok := neighbor.(*internalNode)
+func (n *internalNode[K, V]) moveAllTo(neighbor node[K, V]) {
+	other, ok := neighbor.(*internalNode[K, V])
 	if !ok {
 		return
 	}
-	copy(other.kcs[other.count:], n.kcs)
-	n.kcs = make([]kc, n.max)
+	copy(other.kcs[other.count:], n.kcs[:n.count])
+	for i := 0; i < n.count; i++ {
+		n.kcs[i].child.setParent(other)
+	}
+	n.kcs = make([]kc[K, V], n.max)
 	other.resize(n.count)
 	n.count = 0
 }
 
-func (n *internalNode) isLeaf() bool {
+func (n *internalNode[K, V]) isLeaf() bool {
 	return false
 }
 
-func (n *internalNode) valueAt(i int) any {
+func (n *internalNode[K, V]) valueAt(i int) any {
 	return n.kcs[i].child
 }
 
-func (n *internalNode) resize(i int) {
+func (n *internalNode[K, V]) resize(i int) {
 	n.count += i
 }
 
-func (n *internalNode) moveFirstToEndOf(n2 node) {
-	other, ok := n2.(*internalNode)
+func (n *internalNode[K, V]) moveFirstToEndOf(n2 node[K, V]) {
+	other, ok := n2.(*internalNode[K, V])
 	if !ok {
 		return
 	}
@@ -217,17 +207,30 @@ func (n *internalNode) moveFirstToEndOf(n2 node) {
 	removeItem := common.RemoveAt(n.kcs, 0)
 	other.kcs[other.count] = removeItem
 	other.resize(1)
+	removeItem.child.setParent(other)
 }
 
-func (n *internalNode) getFirstKey() int {
+func (n *internalNode[K, V]) getFirstKey() K {
 	return n.kcs[0].key
 }
 
-func (n *internalNode) id() string {
+func (n *internalNode[K, V]) id() string {
 	id := uintptr(unsafe.Pointer(n))
 	return fmt.Sprintf("%x", id)
 }
 
-func (n *internalNode) nextNode() node {
+func (n *internalNode[K, V]) nextNode() node[K, V] {
 	return nil
 }
+
+func (n *internalNode[K, V]) getTotal() int { return int(n.total.Load()) }
+
+func (n *internalNode[K, V]) addTotal(d int) { n.total.Add(int64(d)) }
+
+func (n *internalNode[K, V]) recount() {
+	total := 0
+	for i := 0; i < n.count; i++ {
+		total += n.kcs[i].child.getTotal()
+	}
+	n.total.Store(int64(total))
+}
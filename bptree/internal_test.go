@@ -1,6 +1,7 @@
 package bptree
 
 import (
+	"cmp"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,14 +10,14 @@ import (
 func Test_internalNode_insert(t *testing.T) {
 	assert := assert.New(t)
 	// 2 个 key，3 个 pointer，以 key 作为 full，split 的标准
-	n := newInternalNode(3)
+	n := newInternalNode[int, string](3, cmp.Compare[int])
 	assert.Equal(n.kcs.Len(), 3)
-	p1 := newLeafNode(2)
+	p1 := newLeafNode[int, string](2, cmp.Compare[int])
 	p1.insert(1, "c")
 	p1.insert(5, "a")
 	n.insert(0, p1) // 0表示无
 
-	p2 := newLeafNode(2)
+	p2 := newLeafNode[int, string](2, cmp.Compare[int])
 	p2.insert(12, "b")
 	n.insert(5, p2)
 
@@ -27,25 +28,25 @@ func Test_internalNode_insert(t *testing.T) {
 func Test_internalNode_split(t *testing.T) {
 	assert := assert.New(t)
 	// 2 个 key，3 个 pointer，以 key 作为 full，split 的标准
-	n := newInternalNode(3)
+	n := newInternalNode[int, string](3, cmp.Compare[int])
 	assert.Equal(n.kcs.Len(), 3)
 
-	p1 := newLeafNode(3)
+	p1 := newLeafNode[int, string](3, cmp.Compare[int])
 	p1.insert(1, "1")
 	n.insert(0, p1) // 0表示无
 
-	p2 := newLeafNode(3)
+	p2 := newLeafNode[int, string](3, cmp.Compare[int])
 	p2.insert(5, "5")
 	n.insert(5, p2)
 
 	assert.Equal(n.count, 2)
 	assert.False(n.full())
 
-	p3 := newLeafNode(3)
+	p3 := newLeafNode[int, string](3, cmp.Compare[int])
 	p3.insert(12, "12")
 	n.insert(12, p3)
 
-	p4 := newLeafNode(3)
+	p4 := newLeafNode[int, string](3, cmp.Compare[int])
 	p4.insert(18, "18")
 	p4.insert(21, "21")
 	n.insert(18, p4)
@@ -54,7 +55,7 @@ func Test_internalNode_split(t *testing.T) {
 func Test_internalNode_full(t *testing.T) {
 	assert := assert.New(t)
 
-	n := newInternalNode(3)
+	n := newInternalNode[int, string](3, cmp.Compare[int])
 	assert.Equal(n.kcs.Len(), 3)
 	n.count = 1
 	assert.Equal(n.halfFull(), true)
@@ -75,15 +76,15 @@ func Test_internalNode_full(t *testing.T) {
 func Test_internalNode_remove(t *testing.T) {
 	assert := assert.New(t)
 
-	n := newInternalNode(3)
+	n := newInternalNode[int, string](3, cmp.Compare[int])
 	assert.Equal(n.kcs.Len(), 3)
 
-	child := newLeafNode(3)
+	child := newLeafNode[int, string](3, cmp.Compare[int])
 	child.insert(5, "a")
 	child.insert(12, "b")
 	child.insert(1, "c")
-	child2 := newLeafNode(3)
-	child3 := newLeafNode(3)
+	child2 := newLeafNode[int, string](3, cmp.Compare[int])
+	child3 := newLeafNode[int, string](3, cmp.Compare[int])
 
 	n.insert(5, child3)
 	n.insert(12, child2)
@@ -99,7 +100,7 @@ func Test_internalNode_remove(t *testing.T) {
 	assert.Equal(ok, false)
 
 	// n 5,12
-	other := newInternalNode(3)
+	other := newInternalNode[int, string](3, cmp.Compare[int])
 	assert.Equal(other.kcs.Len(), 3)
 	n.moveLastToFrontOf(other)
 	// n 5; other 12
@@ -0,0 +1,180 @@
+package bptree
+
+import "iter"
+
+// Ascend calls fn for every key/value pair in ascending order
+// until fn returns false
+func (t *BPTree[K, V]) Ascend(fn func(key K, value V) bool) {
+	c := t.Cursor()
+	for ok := c.First(); ok; ok = c.Next() {
+		if !fn(c.Key(), c.Value()) {
+			return
+		}
+	}
+}
+
+// AscendRange calls fn for every key/value pair in [lo, hi) in ascending order
+// until fn returns false
+func (t *BPTree[K, V]) AscendRange(lo, hi K, fn func(key K, value V) bool) {
+	c := t.Cursor()
+	for ok := c.Seek(lo); ok; ok = c.Next() {
+		if t.compare(c.Key(), hi) >= 0 {
+			return
+		}
+		if !fn(c.Key(), c.Value()) {
+			return
+		}
+	}
+}
+
+// Descend calls fn for every key/value pair in descending order
+// until fn returns false
+func (t *BPTree[K, V]) Descend(fn func(key K, value V) bool) {
+	c := t.Cursor()
+	for ok := c.Last(); ok; ok = c.Prev() {
+		if !fn(c.Key(), c.Value()) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over all key/value pairs in ascending order
+func (t *BPTree[K, V]) All() iter.Seq2[K, V] {
+	return t.Ascend
+}
+
+// Backward returns an iterator over all key/value pairs in descending order
+func (t *BPTree[K, V]) Backward() iter.Seq2[K, V] {
+	return t.Descend
+}
+
+// Range returns an iterator over the key/value pairs in [lo, hi) in ascending order
+func (t *BPTree[K, V]) Range(lo, hi K) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		t.AscendRange(lo, hi, yield)
+	}
+}
+
+// Cursor walks the leaves of a BPTree through the sibling links.
+// A cursor is positioned by First, Last or Seek and moved by Next and Prev,
+// each of them reports whether the cursor points to a pair afterwards.
+// In multimap mode every value of a key is a separate pair.
+// Modifying the tree invalidates the cursor, it must be positioned again
+type Cursor[K, V any] struct {
+	tree *BPTree[K, V]
+	leaf *leafNode[K, V]
+	idx  int
+	dup  int // 0 为 kv 的 value，大于 0 时为 dups[dup-1]
+}
+
+// Cursor returns an unpositioned cursor of the tree
+func (t *BPTree[K, V]) Cursor() *Cursor[K, V] {
+	return &Cursor[K, V]{tree: t}
+}
+
+// First moves the cursor to the smallest key
+func (c *Cursor[K, V]) First() bool {
+	if c.tree.Empty() {
+		return c.reset()
+	}
+	c.leaf, c.idx, c.dup = c.tree.First(), 0, 0
+	return c.skipForward()
+}
+
+// Last moves the cursor to the largest key
+func (c *Cursor[K, V]) Last() bool {
+	if c.tree.Empty() {
+		return c.reset()
+	}
+	tmp := c.tree.root
+	for !tmp.isLeaf() {
+		inter := tmp.(*internalNode[K, V])
+		tmp = inter.kcs[inter.count-1].child
+	}
+	c.leaf = tmp.(*leafNode[K, V])
+	c.idx = c.leaf.count - 1
+	return c.skipBackward()
+}
+
+// Seek moves the cursor to the smallest key that is greater than or equal to key
+func (c *Cursor[K, V]) Seek(key K) bool {
+	if c.tree.Empty() {
+		return c.reset()
+	}
+	c.leaf, c.dup = c.tree.findLeaf(key), 0
+	c.idx, _ = c.leaf.find(key)
+	return c.skipForward()
+}
+
+// Next moves the cursor to the next pair
+func (c *Cursor[K, V]) Next() bool {
+	if c.leaf == nil {
+		return false
+	}
+	if c.dup < len(c.leaf.kvs[c.idx].dups) {
+		c.dup++
+		return true
+	}
+	c.idx, c.dup = c.idx+1, 0
+	return c.skipForward()
+}
+
+// Prev moves the cursor to the previous pair
+func (c *Cursor[K, V]) Prev() bool {
+	if c.leaf == nil {
+		return false
+	}
+	if c.dup > 0 {
+		c.dup--
+		return true
+	}
+	c.idx--
+	return c.skipBackward()
+}
+
+// Valid reports whether the cursor points to a key/value pair
+func (c *Cursor[K, V]) Valid() bool {
+	return c.leaf != nil
+}
+
+// Key returns the key under the cursor, the cursor must be valid
+func (c *Cursor[K, V]) Key() K {
+	return c.leaf.kvs[c.idx].key
+}
+
+// Value returns the value under the cursor, the cursor must be valid
+func (c *Cursor[K, V]) Value() V {
+	if c.dup > 0 {
+		return c.leaf.kvs[c.idx].dups[c.dup-1]
+	}
+	return c.leaf.kvs[c.idx].value
+}
+
+// skipForward 当前叶子节点已经遍历完，则沿着 next 指针前进
+func (c *Cursor[K, V]) skipForward() bool {
+	for c.leaf != nil && c.idx >= c.leaf.count {
+		c.leaf, c.idx = c.leaf.next, 0
+	}
+	return c.leaf != nil
+}
+
+// skipBackward 当前叶子节点已经遍历完，则沿着 prev 指针后退，
+// 停在 kv 的最后一个 value 上
+func (c *Cursor[K, V]) skipBackward() bool {
+	for c.leaf != nil && c.idx < 0 {
+		c.leaf = c.leaf.prev
+		if c.leaf != nil {
+			c.idx = c.leaf.count - 1
+		}
+	}
+	if c.leaf == nil {
+		return false
+	}
+	c.dup = len(c.leaf.kvs[c.idx].dups)
+	return true
+}
+
+func (c *Cursor[K, V]) reset() bool {
+	c.leaf, c.idx, c.dup = nil, 0, 0
+	return false
+}
@@ -0,0 +1,164 @@
+package bptree
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func buildRandomTree(r *rand.Rand, size, ops int) (*BPTree[int, int], []int) {
+	bt := NewBPTree[int, int](MaxInternal(size), MaxLeaf(size))
+	m := map[int]bool{}
+	for i := 0; i < ops; i++ {
+		k := r.Intn(ops) - ops/4
+		if r.Intn(3) == 0 {
+			bt.Delete(k)
+			delete(m, k)
+		} else {
+			bt.Insert(k, k*10)
+			m[k] = true
+		}
+	}
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return bt, keys
+}
+
+func TestBPTree_Ascend(t *testing.T) {
+	assert := assert.New(t)
+
+	for size := 3; size <= 8; size++ {
+		r := rand.New(rand.NewSource(int64(size)))
+		bt, keys := buildRandomTree(r, size, 2000)
+
+		var got []int
+		bt.Ascend(func(key int, value int) bool {
+			assert.Equal(key*10, value)
+			got = append(got, key)
+			return true
+		})
+		assert.Equal(keys, got)
+
+		got = got[:0]
+		bt.Descend(func(key int, value int) bool {
+			got = append(got, key)
+			return true
+		})
+		for i, j := 0, len(got)-1; i < j; i, j = i+1, j-1 {
+			got[i], got[j] = got[j], got[i]
+		}
+		assert.Equal(keys, got)
+	}
+}
+
+func TestBPTree_AscendRange(t *testing.T) {
+	assert := assert.New(t)
+	r := rand.New(rand.NewSource(1))
+	bt, keys := buildRandomTree(r, 5, 2000)
+
+	for i := 0; i < 100; i++ {
+		lo := r.Intn(2000) - 600
+		hi := lo + r.Intn(300)
+
+		var want []int
+		for _, k := range keys {
+			if k >= lo && k < hi {
+				want = append(want, k)
+			}
+		}
+		var got []int
+		bt.AscendRange(lo, hi, func(key int, value int) bool {
+			got = append(got, key)
+			return true
+		})
+		assert.Equal(want, got, "range [%d, %d)", lo, hi)
+	}
+
+	// stop early
+	n := 0
+	bt.AscendRange(keys[0], keys[len(keys)-1], func(key int, value int) bool {
+		n++
+		return n < 3
+	})
+	assert.Equal(3, n)
+}
+
+func TestBPTree_Seq(t *testing.T) {
+	assert := assert.New(t)
+	bt := NewBPTree[int, string](MaxInternal(4), MaxLeaf(4))
+	for i := 1; i <= 100; i++ {
+		bt.Insert(i, "v")
+	}
+
+	sum := 0
+	for k, v := range bt.All() {
+		assert.Equal("v", v)
+		sum += k
+	}
+	assert.Equal(5050, sum)
+
+	var got []int
+	for k := range bt.Range(10, 15) {
+		got = append(got, k)
+	}
+	assert.Equal([]int{10, 11, 12, 13, 14}, got)
+
+	got = got[:0]
+	for k := range bt.Backward() {
+		if k < 97 {
+			break
+		}
+		got = append(got, k)
+	}
+	assert.Equal([]int{100, 99, 98, 97}, got)
+}
+
+func TestCursor(t *testing.T) {
+	assert := assert.New(t)
+
+	empty := NewBPTree[int, int]()
+	c := empty.Cursor()
+	assert.False(c.First())
+	assert.False(c.Last())
+	assert.False(c.Seek(1))
+	assert.False(c.Valid())
+
+	bt := NewBPTree[int, int](MaxInternal(3), MaxLeaf(3))
+	for i := 0; i < 100; i += 2 {
+		bt.Insert(i, i)
+	}
+
+	c = bt.Cursor()
+	assert.True(c.Seek(31))
+	assert.Equal(32, c.Key())
+	assert.True(c.Next())
+	assert.Equal(34, c.Key())
+	assert.True(c.Prev())
+	assert.True(c.Prev())
+	assert.Equal(30, c.Key())
+
+	assert.True(c.Seek(40))
+	assert.Equal(40, c.Key())
+	assert.False(c.Seek(99))
+	assert.False(c.Valid())
+
+	assert.True(c.First())
+	assert.Equal(0, c.Key())
+	assert.False(c.Prev())
+
+	assert.True(c.Last())
+	assert.Equal(98, c.Key())
+	assert.False(c.Next())
+
+	// walk every leaf back and forth
+	n := 0
+	for ok := c.Last(); ok; ok = c.Prev() {
+		n++
+	}
+	assert.Equal(50, n)
+}
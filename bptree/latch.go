@@ -0,0 +1,218 @@
+package bptree
+
+import "sync"
+
+// latch 节点的读写锁，只在并发模式下使用
+type latch struct {
+	mu sync.RWMutex
+}
+
+func (l *latch) lock()    { l.mu.Lock() }
+func (l *latch) unlock()  { l.mu.Unlock() }
+func (l *latch) rLock()   { l.mu.RLock() }
+func (l *latch) rUnlock() { l.mu.RUnlock() }
+
+// latchSet 一次写操作持有的写锁，按从上到下的顺序加锁。
+// 为 nil 时表示非并发模式，所有方法都不做任何事
+type latchSet[K, V any] struct {
+	tree  *BPTree[K, V]
+	root  bool // 是否持有 rootLatch
+	keep  bool // 持有整条路径的锁，不释放祖先
+	nodes []node[K, V]
+}
+
+func (s *latchSet[K, V]) lock(n node[K, V]) {
+	if s == nil {
+		return
+	}
+	n.lock()
+	s.nodes = append(s.nodes, n)
+}
+
+// releaseAncestors 最后加锁的节点是安全的，结构修改不会越过它，
+// 释放它之上的所有锁，包括 rootLatch
+func (s *latchSet[K, V]) releaseAncestors() {
+	if s.keep {
+		return
+	}
+	if s.root {
+		s.tree.rootLatch.Unlock()
+		s.root = false
+	}
+	last := len(s.nodes) - 1
+	for _, n := range s.nodes[:last] {
+		n.unlock()
+	}
+	s.nodes = append(s.nodes[:0], s.nodes[last])
+}
+
+func (s *latchSet[K, V]) releaseAll() {
+	if s.root {
+		s.tree.rootLatch.Unlock()
+		s.root = false
+	}
+	for _, n := range s.nodes {
+		n.unlock()
+	}
+	s.nodes = s.nodes[:0]
+}
+
+// top 返回持有的最上层节点，它的父节点没有被锁住，
+// 其他线程可能正在修改它的父指针；持有 rootLatch 时返回 nil
+func (s *latchSet[K, V]) top() node[K, V] {
+	if s == nil || s.root {
+		return nil
+	}
+	return s.nodes[0]
+}
+
+// readLeafConcurrent 读锁从上往下交替加锁：先锁孩子，再释放父节点。
+// pick 选择下降的孩子
+func (t *BPTree[K, V]) readLeafConcurrent(pick func(inter *internalNode[K, V]) node[K, V], fn func(leaf *leafNode[K, V])) {
+	t.batchLatch.RLock()
+	defer t.batchLatch.RUnlock()
+	t.rootLatch.RLock()
+	n := t.root
+	if n == nil {
+		t.rootLatch.RUnlock()
+		return
+	}
+	n.rLock()
+	t.rootLatch.RUnlock()
+	for {
+		inter, ok := n.(*internalNode[K, V])
+		if !ok {
+			break
+		}
+		child := pick(inter)
+		child.rLock()
+		n.rUnlock()
+		n = child
+	}
+	leaf := n.(*leafNode[K, V])
+	defer leaf.rUnlock()
+	fn(leaf)
+}
+
+// insertConcurrent 写锁从上往下加锁：孩子插入后不会分裂时，释放所有祖先的锁。
+// 维护子树计数时，插入新的 kv 对要更新所有祖先的计数，如果祖先的锁已经释放，
+// 则持有整条路径的锁重试，因此只有替换 value 的插入可以并行
+func (t *BPTree[K, V]) insertConcurrent(key K, value V) {
+	t.batchLatch.RLock()
+	defer t.batchLatch.RUnlock()
+	if (t.equal == nil || !t.counts) && t.insertLatched(key, value, false) {
+		return
+	}
+	t.insertLatched(key, value, true)
+}
+
+// insertLatched keep 为 true 时持有整条路径的锁。维护子树计数时，
+// 需要插入新的 kv 对但是祖先的锁已经释放，不做修改并返回 false
+func (t *BPTree[K, V]) insertLatched(key K, value V, keep bool) bool {
+	s := &latchSet[K, V]{tree: t, root: true, keep: keep}
+	t.rootLatch.Lock()
+	defer s.releaseAll()
+	if t.root == nil {
+		t.startRoot(key, value)
+		return true
+	}
+	n := t.root
+	for {
+		s.lock(n)
+		if n.getSize()+1 < n.getMaxSize() {
+			s.releaseAncestors()
+		}
+		inter, ok := n.(*internalNode[K, V])
+		if !ok {
+			break
+		}
+		// 下降时直接调低 k0，不需要像 lowerFirstKeys 一样回溯已经释放的祖先；
+		// 即使之后放弃插入，k0 仍然是下界
+		if t.compare(key, inter.kcs[0].key) < 0 {
+			inter.kcs[0].key = key
+		}
+		n = inter.lookup(key)
+	}
+	leaf := n.(*leafNode[K, V])
+	if !s.root && t.counts {
+		// 祖先的锁已经释放，只能替换已有 key 的 value
+		idx, ok := leaf.find(key)
+		if ok {
+			leaf.kvs[idx].value = value
+		}
+		return ok
+	}
+	t.insertIntoLeafNode(leaf, key, value)
+	if leaf.full() {
+		t.splitLeaf(leaf)
+	}
+	return true
+}
+
+// deleteConcurrent 与 insertConcurrent 相同，孩子删除一项后不会下溢时，
+// 释放所有祖先的锁；维护子树计数并且 key 存在时，持有整条路径的锁重试
+func (t *BPTree[K, V]) deleteConcurrent(key K, remove func(e *kv[K, V]) bool) bool {
+	return t.deleteConcurrentBy(func(inter *internalNode[K, V]) node[K, V] {
+		return inter.lookup(key)
+	}, func(leaf *leafNode[K, V]) (K, bool) {
+		_, ok := leaf.find(key)
+		return key, ok
+	}, remove)
+}
+
+// deleteConcurrentBy 沿 pick 下降到叶子节点，删除 at 在叶子节点中选出的 key，
+// 选出和删除 key 时一直持有叶子节点的锁
+func (t *BPTree[K, V]) deleteConcurrentBy(pick func(inter *internalNode[K, V]) node[K, V], at func(leaf *leafNode[K, V]) (K, bool), remove func(e *kv[K, V]) bool) bool {
+	t.batchLatch.RLock()
+	defer t.batchLatch.RUnlock()
+	if remove == nil || !t.counts {
+		if found, done := t.deleteLatched(pick, at, remove, false); done {
+			return found
+		}
+	}
+	found, _ := t.deleteLatched(pick, at, remove, true)
+	return found
+}
+
+// deleteLatched 返回 key 是否存在，以及是否完成了删除。
+// 维护子树计数时，key 存在但是祖先的锁已经释放，不做修改，done 为 false
+func (t *BPTree[K, V]) deleteLatched(pick func(inter *internalNode[K, V]) node[K, V], at func(leaf *leafNode[K, V]) (K, bool), remove func(e *kv[K, V]) bool, keep bool) (found, done bool) {
+	s := &latchSet[K, V]{tree: t, root: true, keep: keep}
+	t.rootLatch.Lock()
+	defer s.releaseAll()
+	if t.root == nil {
+		return false, true
+	}
+	n := t.root
+	for {
+		s.lock(n)
+		if t.deleteSafe(n) {
+			s.releaseAncestors()
+		}
+		inter, ok := n.(*internalNode[K, V])
+		if !ok {
+			break
+		}
+		n = pick(inter)
+	}
+	leaf := n.(*leafNode[K, V])
+	key, found := at(leaf)
+	if !found {
+		return false, true
+	}
+	if !s.root && t.counts {
+		return true, false
+	}
+	return t.deleteFromLeaf(leaf, key, remove, s), true
+}
+
+// deleteSafe 删除一项后 n 不会下溢，也不需要调整根节点
+func (t *BPTree[K, V]) deleteSafe(n node[K, V]) bool {
+	if n.isRoot() {
+		if n.isLeaf() {
+			return n.getSize() > 1
+		}
+		return n.getSize() > 2
+	}
+	return n.getSize() > n.getMinSize()
+}
@@ -0,0 +1,139 @@
+package bptree
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// checkLinks 树结构正确，且叶子链表正反两个方向都与 keys 一致
+func checkLinks(t *testing.T, bt *BPTree[int, int], keys []int) {
+	assert.NoError(t, bt.Verify())
+	assert.Equal(t, len(keys), bt.Len())
+
+	var got []int
+	bt.Ascend(func(key int, value int) bool {
+		got = append(got, key)
+		return true
+	})
+	assert.Equal(t, keys, got)
+
+	got = got[:0]
+	bt.Descend(func(key int, value int) bool {
+		got = append(got, key)
+		return true
+	})
+	sort.Ints(got)
+	assert.Equal(t, keys, got)
+}
+
+// 每个 goroutine 只操作自己的 key，结果可以和本地的 map 对比
+func TestBPTree_ConcurrentDisjoint(t *testing.T) {
+	const (
+		workers = 8
+		ops     = 3000
+	)
+	for _, size := range []int{3, 4, 7, 0} {
+		bt := NewBPTree[int, int](MaxInternal(size), MaxLeaf(size), Concurrent())
+		maps := make([]map[int]bool, workers)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			maps[w] = map[int]bool{}
+			wg.Add(1)
+			go func(w int, m map[int]bool) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(int64(size*workers + w)))
+				for i := 0; i < ops; i++ {
+					k := r.Intn(ops/2)*workers + w
+					switch r.Intn(3) {
+					case 0:
+						bt.Delete(k)
+						delete(m, k)
+					case 1:
+						bt.Insert(k, k*10)
+						m[k] = true
+					default:
+						v, ok := bt.Search(k)
+						if !assert.Equal(t, m[k], ok, "size %d key %d", size, k) {
+							return
+						}
+						if ok {
+							assert.Equal(t, k*10, v)
+						}
+					}
+				}
+			}(w, maps[w])
+		}
+		wg.Wait()
+
+		var keys []int
+		for _, m := range maps {
+			for k := range m {
+				keys = append(keys, k)
+			}
+		}
+		sort.Ints(keys)
+		checkLinks(t, bt, keys)
+		for _, k := range keys {
+			bt.Delete(k)
+		}
+		assert.True(t, bt.Empty())
+	}
+}
+
+// 读者和写者操作同一批 key，读到的值必须是写者写入的值
+func TestBPTree_ConcurrentShared(t *testing.T) {
+	const (
+		writers = 4
+		readers = 4
+		keys    = 500
+		ops     = 4000
+	)
+	bt := NewBPTree[int, int](MaxInternal(4), MaxLeaf(4), Concurrent())
+	for k := 0; k < keys; k += 2 {
+		bt.Insert(k, k*10)
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < ops; i++ {
+				k := r.Intn(keys)
+				if r.Intn(2) == 0 {
+					bt.Delete(k)
+				} else {
+					bt.Insert(k, k*10)
+				}
+			}
+		}(int64(w))
+	}
+	for g := 0; g < readers; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < ops; i++ {
+				k := r.Intn(keys)
+				if v, ok := bt.Search(k); ok {
+					assert.Equal(t, k*10, v)
+				}
+				bt.Empty()
+			}
+		}(int64(writers + g))
+	}
+	wg.Wait()
+
+	var want []int
+	for k := 0; k < keys; k++ {
+		if _, ok := bt.Search(k); ok {
+			want = append(want, k)
+		}
+	}
+	checkLinks(t, bt, want)
+}
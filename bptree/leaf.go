@@ -3,37 +3,43 @@ package bptree
 import (
 	"fmt"
 	"sort"
+	"sync/atomic"
 	"unsafe"
 
 	"github.com/pedrogao/btrees/common"
 )
 
-type kv struct {
-	key   int
-	value string
+type kv[K, V any] struct {
+	key   K
+	value V
+	dups  []V // 多值模式下同一个 key 的其余 value，按插入顺序排列
 }
 
-type kvs []kv
+type kvs[K, V any] []kv[K, V]
 
-func (a kvs) Len() int           { return len(a) }
-func (a kvs) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a kvs) Less(i, j int) bool { return a[i].key < a[j].key }
+func (a kvs[K, V]) Len() int      { return len(a) }
+func (a kvs[K, V]) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 
 // leafNode 第一个 key 不为空
 // +----++----++----++----+
 // | k1 || v2 || k2 || v2 |
 // +----++----++----++----+
-type leafNode struct {
-	kvs        kvs           // 内部kv对
-	max, count int           // kv对数量
-	next       *leafNode     // 下一个叶子节点
-	p          *internalNode // 父节点
-}
-
-func newLeafNode(max int) *leafNode {
-	return &leafNode{
-		kvs: make([]kv, max),
-		max: max,
+type leafNode[K, V any] struct {
+	latch
+	kvs        kvs[K, V]           // 内部kv对
+	max, count int                 // kv对数量
+	next       *leafNode[K, V]     // 下一个叶子节点
+	prev       *leafNode[K, V]     // 上一个叶子节点
+	p          *internalNode[K, V] // 父节点
+	compare    func(a, b K) int    // key 比较函数
+	total      atomic.Int64        // kv 对数量，包括 dups
+}
+
+func newLeafNode[K, V any](max int, compare func(a, b K) int) *leafNode[K, V] {
+	return &leafNode[K, V]{
+		kvs:     make([]kv[K, V], max),
+		max:     max,
+		compare: compare,
 	}
 }
 
@@ -42,41 +48,47 @@ func newLeafNode(max int) *leafNode {
 // If the key does not exist in the node, it returns index to
 // insert the key (the index of the smallest key in the node that larger
 // than the given key) and false.
-func (l *leafNode) find(key int) (int, bool) {
+func (l *leafNode[K, V]) find(key K) (int, bool) {
 	c := func(i int) bool {
-		return l.kvs[i].key >= key
+		return l.compare(l.kvs[i].key, key) >= 0
 	}
 	// count 很重要，表示搜索的右边界
 	i := sort.Search(l.count, c)
 
-	if i < l.count && l.kvs[i].key == key {
+	if i < l.count && l.compare(l.kvs[i].key, key) == 0 {
 		return i, true
 	}
 
 	return i, false
 }
 
-func (l *leafNode) insert(key int, value string) {
+// insert 插入 kv，返回 key 是否是新插入的
+func (l *leafNode[K, V]) insert(key K, value V) bool {
 	i, ok := l.find(key)
 	// 不支持 key 重复，发现有 key 直接替换即可
 	if ok {
 		l.kvs[i].value = value
-		return
+		return false
 	}
 	copy(l.kvs[i+1:], l.kvs[i:l.count])
-	l.kvs[i].key = key
-	l.kvs[i].value = value
+	// 整体赋值，不能留下原来这个位置上的 dups
+	l.kvs[i] = kv[K, V]{key: key, value: value}
 	l.count++
+	return true
 }
 
-func (l *leafNode) split() *leafNode {
-	next := newLeafNode(l.max)
+func (l *leafNode[K, V]) split() *leafNode[K, V] {
+	next := newLeafNode[K, V](l.max, l.compare)
 
 	mid := l.getMinSize()
 	copy(next.kvs, l.kvs[mid:])
 
 	next.count = l.max - mid
 	next.next = l.next
+	next.prev = l
+	if l.next != nil {
+		l.next.prev = next
+	}
 
 	l.count = l.count - (l.max - mid)
 	l.next = next
@@ -84,31 +96,31 @@ func (l *leafNode) split() *leafNode {
 	return next
 }
 
-func (l *leafNode) full() bool { return l.count >= l.max }
+func (l *leafNode[K, V]) full() bool { return l.count >= l.max }
 
-func (l *leafNode) halfFull() bool { return l.count >= l.max/2 }
+func (l *leafNode[K, V]) halfFull() bool { return l.count >= l.max/2 }
 
-func (l *leafNode) parent() *internalNode { return l.p }
+func (l *leafNode[K, V]) parent() *internalNode[K, V] { return l.p }
 
-func (l *leafNode) setParent(p *internalNode) { l.p = p }
+func (l *leafNode[K, V]) setParent(p *internalNode[K, V]) { l.p = p }
 
-func (l *leafNode) getMaxSize() int {
+func (l *leafNode[K, V]) getMaxSize() int {
 	return l.max
 }
 
-func (l *leafNode) getMinSize() int {
+func (l *leafNode[K, V]) getMinSize() int {
 	return l.max / 2
 }
 
-func (l *leafNode) isRoot() bool {
+func (l *leafNode[K, V]) isRoot() bool {
 	return l.p == nil
 }
 
-func (l *leafNode) getSize() int {
+func (l *leafNode[K, V]) getSize() int {
 	return l.count
 }
 
-func (l *leafNode) remove(key int) bool {
+func (l *leafNode[K, V]) remove(key K) bool {
 	idx, b := l.find(key)
 	if !b {
 		return false
@@ -118,8 +130,8 @@ func (l *leafNode) remove(key int) bool {
 	return true
 }
 
-func (l *leafNode) moveLastToFrontOf(n node) {
-	other, ok := n.(*leafNode)
+func (l *leafNode[K, V]) moveLastToFrontOf(n node[K, V]) {
+	other, ok := n.(*leafNode[K, V])
 	if !ok {
 		return
 	}
@@ -130,31 +142,48 @@ func (l *leafNode) moveLastToFrontOf(n node) {
 	other.kvs[0] = removeItem
 }
 
-func (l *leafNode) moveAllTo(neighbor node) {
-	other, ok := neighbor.(*leafNode)
+func (l *leafNode[K, V]) moveAllTo(neighbor node[K, V]) {
+	other, ok := neighbor.(*leafNode[K, V])
 	if !ok {
 		return
 	}
-	copy(other.kvs[other.count:], l.kvs)
-	l.kvs = make([]kv, l.max)
+	copy(other.kvs[other.count:], l.kvs[:l.count])
+	l.kvs = make([]kv[K, V], l.max)
 	other.resize(l.count)
 	l.count = 0
+	// l 总是 neighbor 右边的兄弟，合并后从叶子链表中摘除
+	other.next = l.next
+	if l.next != nil {
+		l.next.prev = other
+	}
+	l.next, l.prev = nil, nil
+}
+
+// unlink removes l from the sibling chain
+func (l *leafNode[K, V]) unlink() {
+	if l.prev != nil {
+		l.prev.next = l.next
+	}
+	if l.next != nil {
+		l.next.prev = l.prev
+	}
+	l.next, l.prev = nil, nil
 }
 
-func (l *leafNode) isLeaf() bool {
+func (l *leafNode[K, V]) isLeaf() bool {
 	return true
 }
 
-func (l *leafNode) valueAt(i int) any {
+func (l *leafNode[K, V]) valueAt(i int) any {
 	return l.kvs[i].value
 }
 
-func (l *leafNode) resize(i int) {
+func (l *leafNode[K, V]) resize(i int) {
 	l.count += i
 }
 
-func (l *leafNode) moveFirstToEndOf(n node) {
-	other, ok := n.(*leafNode)
+func (l *leafNode[K, V]) moveFirstToEndOf(n node[K, V]) {
+	other, ok := n.(*leafNode[K, V])
 	if !ok {
 		return
 	}
@@ -164,15 +193,32 @@ func (l *leafNode) moveFirstToEndOf(n node) {
 	other.resize(1)
 }
 
-func (l *leafNode) getFirstKey() int {
+func (l *leafNode[K, V]) getFirstKey() K {
 	return l.kvs[0].key
 }
 
-func (l *leafNode) id() string {
+func (l *leafNode[K, V]) id() string {
 	id := uintptr(unsafe.Pointer(l))
 	return fmt.Sprintf("%x", id)
 }
 
-func (l *leafNode) nextNode() node {
+func (l *leafNode[K, V]) nextNode() node[K, V] {
 	return l.next
 }
+
+func (l *leafNode[K, V]) getTotal() int { return int(l.total.Load()) }
+
+func (l *leafNode[K, V]) addTotal(d int) { l.total.Add(int64(d)) }
+
+func (l *leafNode[K, V]) recount() {
+	total := 0
+	for i := 0; i < l.count; i++ {
+		total += l.kvs[i].weight()
+	}
+	l.total.Store(int64(total))
+}
+
+// weight 返回 kv 对的数量，多值模式下包括 dups
+func (e *kv[K, V]) weight() int {
+	return 1 + len(e.dups)
+}
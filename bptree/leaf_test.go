@@ -1,6 +1,7 @@
 package bptree
 
 import (
+	"cmp"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -9,17 +10,17 @@ import (
 func Test_leafNode_find(t *testing.T) {
 	assert := assert.New(t)
 
-	n := newLeafNode(2)
+	n := newLeafNode[int, string](2, cmp.Compare[int])
 	assert.Equal(n.kvs.Len(), 2)
 	found, ok := n.find(1)
 	assert.Equal(found, 0)
 	assert.Equal(ok, false)
 
-	n.kvs[0] = kv{
+	n.kvs[0] = kv[int, string]{
 		key:   5,
 		value: "a",
 	}
-	n.kvs[1] = kv{
+	n.kvs[1] = kv[int, string]{
 		key:   12,
 		value: "b",
 	}
@@ -35,7 +36,7 @@ func Test_leafNode_find(t *testing.T) {
 func Test_leafNode_insert(t *testing.T) {
 	assert := assert.New(t)
 
-	n := newLeafNode(3)
+	n := newLeafNode[int, string](3, cmp.Compare[int])
 	assert.Equal(n.kvs.Len(), 3)
 	found, ok := n.find(1)
 	assert.Equal(found, 0)
@@ -52,21 +53,21 @@ func Test_leafNode_insert(t *testing.T) {
 func Test_leafNode_split(t *testing.T) {
 	assert := assert.New(t)
 
-	n := newLeafNode(4)
+	n := newLeafNode[int, string](4, cmp.Compare[int])
 	assert.Equal(n.kvs.Len(), 4)
-	n.kvs[0] = kv{
+	n.kvs[0] = kv[int, string]{
 		key:   1,
 		value: "c",
 	}
-	n.kvs[1] = kv{
+	n.kvs[1] = kv[int, string]{
 		key:   5,
 		value: "a",
 	}
-	n.kvs[2] = kv{
+	n.kvs[2] = kv[int, string]{
 		key:   12,
 		value: "b",
 	}
-	n.kvs[3] = kv{
+	n.kvs[3] = kv[int, string]{
 		key:   20,
 		value: "d",
 	}
@@ -74,6 +75,8 @@ func Test_leafNode_split(t *testing.T) {
 	next := n.split()
 	assert.Equal(n.count, 2)
 	assert.Equal(next.count, 2)
+	assert.Equal(n.next, next)
+	assert.Equal(next.prev, n)
 	assert.Equal(n.kvs[0].key, 1)
 	assert.Equal(n.kvs[0].value, "c")
 	assert.Equal(n.kvs[1].key, 5)
@@ -87,7 +90,7 @@ func Test_leafNode_split(t *testing.T) {
 func Test_leafNode_full(t *testing.T) {
 	assert := assert.New(t)
 
-	n := newLeafNode(3)
+	n := newLeafNode[int, string](3, cmp.Compare[int])
 	assert.Equal(n.kvs.Len(), 3)
 	n.count = 1
 	assert.Equal(n.halfFull(), true)
@@ -109,7 +112,7 @@ func Test_leafNode_full(t *testing.T) {
 func Test_leafNode_remove(t *testing.T) {
 	assert := assert.New(t)
 
-	n := newLeafNode(3)
+	n := newLeafNode[int, string](3, cmp.Compare[int])
 	assert.Equal(n.kvs.Len(), 3)
 	n.insert(5, "a")
 	n.insert(12, "b")
@@ -124,7 +127,7 @@ func Test_leafNode_remove(t *testing.T) {
 	assert.Equal(ok, false)
 
 	// n 5,12
-	other := newLeafNode(3)
+	other := newLeafNode[int, string](3, cmp.Compare[int])
 	assert.Equal(other.kvs.Len(), 3)
 	n.moveLastToFrontOf(other)
 	// n 5; other 12
@@ -0,0 +1,36 @@
+package bptree
+
+import (
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/pedrogao/btrees/internal/modeltest"
+)
+
+func modelTarget(options ...Option) modeltest.Target[int] {
+	return modeltest.Target[int]{New: func() common.OrderedMap[int, any] {
+		return NewBPTree[int, any](options...)
+	}}
+}
+
+// 检查器在每一步之后调用 Verify
+var _ modeltest.Verifier = (*BPTree[int, any])(nil)
+
+func TestBPTree_Model(t *testing.T) {
+	// 叶子节点和内部节点大小不同时，MaxInternal(3) 的内部节点可能只有一个孩子。
+	// 每一步之后都要完整比较和 Verify，-short 和 -race 时只测大小不同的组合
+	sizes := [][2]int{{3, 3}, {4, 4}, {7, 7}, {4, 3}, {3, 5}, {6, 3}, {5, 4}}
+	if testing.Short() || raceEnabled {
+		sizes = [][2]int{{4, 3}, {3, 5}}
+	}
+	for _, size := range sizes {
+		leaf, internal := MaxLeaf(size[0]), MaxInternal(size[1])
+		modeltest.RandomTest(t, modelTarget(leaf, internal), 2, 1000)
+		modeltest.RandomTest(t, modelTarget(leaf, internal, Concurrent()), 1, 1000)
+		modeltest.RandomTest(t, modelTarget(leaf, internal, ConcurrentCounts()), 1, 1000)
+	}
+}
+
+func FuzzBPTree(f *testing.F) {
+	modeltest.Fuzz(f, modelTarget(MaxLeaf(4), MaxInternal(3)))
+}
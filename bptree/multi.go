@@ -0,0 +1,59 @@
+package bptree
+
+import (
+	"cmp"
+	"slices"
+)
+
+// NewMultiBPTree returns a b+ tree in multimap mode ordered by the natural order of K,
+// values are compared with ==
+func NewMultiBPTree[K cmp.Ordered, V comparable](options ...Option) *BPTree[K, V] {
+	return NewMultiBPTreeFunc[K, V](cmp.Compare[K], func(a, b V) bool { return a == b }, options...)
+}
+
+// NewMultiBPTreeFunc returns a b+ tree in multimap mode, used e.g. for secondary indexes.
+// Insert appends the value to the posting list of the key instead of replacing it,
+// so all values of a key live in one leaf entry and never straddle leaves.
+// equal is used by DeleteValue to find the value to delete
+func NewMultiBPTreeFunc[K, V any](compare func(a, b K) int, equal func(a, b V) bool, options ...Option) *BPTree[K, V] {
+	t := NewBPTreeFunc[K, V](compare, options...)
+	t.equal = equal
+	return t
+}
+
+// SearchAll returns all values of the key in insertion order, nil if the key does not exist
+func (t *BPTree[K, V]) SearchAll(key K) []V {
+	var values []V
+	t.readLeaf(key, func(leaf *leafNode[K, V]) {
+		if idx, ok := leaf.find(key); ok {
+			e := &leaf.kvs[idx]
+			values = append(append(make([]V, 0, len(e.dups)+1), e.value), e.dups...)
+		}
+	})
+	return values
+}
+
+// DeleteValue deletes one value of the key from a multimap tree and reports
+// whether it was found. The key is deleted with its last value
+func (t *BPTree[K, V]) DeleteValue(key K, value V) bool {
+	if t.equal == nil {
+		panic("bptree: DeleteValue needs a multimap tree")
+	}
+	found := false
+	t.delete(key, func(e *kv[K, V]) bool {
+		if t.equal(e.value, value) {
+			found = true
+			if len(e.dups) == 0 {
+				return true
+			}
+			e.value, e.dups = e.dups[0], e.dups[1:]
+			return false
+		}
+		if i := slices.IndexFunc(e.dups, func(v V) bool { return t.equal(v, value) }); i >= 0 {
+			found = true
+			e.dups = slices.Delete(e.dups, i, i+1)
+		}
+		return false
+	})
+	return found
+}
@@ -0,0 +1,114 @@
+package bptree
+
+import (
+	"math/rand"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiBPTree(t *testing.T) {
+	assert := assert.New(t)
+	bt := NewMultiBPTree[int, string](MaxLeaf(3), MaxInternal(3))
+
+	for _, v := range []string{"a", "b", "c", "d"} {
+		bt.Insert(2, v)
+	}
+	bt.Insert(1, "x")
+	bt.Insert(3, "y")
+	assert.Equal([]string{"a", "b", "c", "d"}, bt.SearchAll(2))
+	assert.Nil(bt.SearchAll(4))
+	v, ok := bt.Search(2)
+	assert.True(ok)
+	assert.Equal("a", v)
+
+	var pairs []string
+	bt.Ascend(func(key int, value string) bool {
+		pairs = append(pairs, value)
+		return true
+	})
+	assert.Equal([]string{"x", "a", "b", "c", "d", "y"}, pairs)
+	pairs = pairs[:0]
+	bt.Descend(func(key int, value string) bool {
+		pairs = append(pairs, value)
+		return true
+	})
+	assert.Equal([]string{"y", "d", "c", "b", "a", "x"}, pairs)
+
+	assert.True(bt.DeleteValue(2, "c"))
+	assert.True(bt.DeleteValue(2, "a"))
+	assert.False(bt.DeleteValue(2, "a"))
+	assert.False(bt.DeleteValue(4, "a"))
+	assert.Equal([]string{"b", "d"}, bt.SearchAll(2))
+	assert.True(bt.DeleteValue(2, "d"))
+	assert.True(bt.DeleteValue(2, "b"))
+	_, ok = bt.Search(2)
+	assert.False(ok)
+
+	bt.Insert(3, "z")
+	bt.Delete(3)
+	assert.Nil(bt.SearchAll(3))
+
+	assert.Panics(func() { NewBPTree[int, string]().DeleteValue(1, "a") })
+}
+
+func TestMultiBPTree_Random(t *testing.T) {
+	for _, size := range []int{3, 4, 7} {
+		for _, concurrent := range []bool{false, true} {
+			r := rand.New(rand.NewSource(int64(size)))
+			options := []Option{MaxLeaf(size), MaxInternal(size)}
+			if concurrent {
+				options = append(options, Concurrent())
+			}
+			bt := NewMultiBPTree[int, int](options...)
+			m := map[int][]int{}
+			for i := 0; i < 5000; i++ {
+				k, v := r.Intn(200), r.Intn(5)
+				switch r.Intn(4) {
+				case 0:
+					i := slices.Index(m[k], v)
+					assert.Equal(t, i >= 0, bt.DeleteValue(k, v))
+					if i >= 0 {
+						m[k] = slices.Delete(m[k], i, i+1)
+					}
+					if len(m[k]) == 0 {
+						delete(m, k)
+					}
+				case 1:
+					if r.Intn(10) == 0 {
+						bt.Delete(k)
+						delete(m, k)
+					}
+				default:
+					bt.Insert(k, v)
+					m[k] = append(m[k], v)
+				}
+			}
+			assert.NoError(t, bt.Verify())
+
+			keys := make([]int, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Ints(keys)
+			var want, gotKeys []int
+			for _, k := range keys {
+				assert.Equal(t, m[k], bt.SearchAll(k))
+				want = append(want, m[k]...)
+			}
+			var got []int
+			bt.Ascend(func(key int, value int) bool {
+				if len(gotKeys) == 0 || gotKeys[len(gotKeys)-1] != key {
+					gotKeys = append(gotKeys, key)
+				}
+				got = append(got, value)
+				return true
+			})
+			assert.Equal(t, keys, gotKeys)
+			assert.Equal(t, want, got)
+			assert.Equal(t, len(want), bt.Len())
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package bptree
+
+import "sort"
+
+// Floor returns the largest key that is less than or equal to key.
+// In multimap mode the last value of the key is returned, as Max does
+func (t *BPTree[K, V]) Floor(key K) (K, V, bool) {
+	return t.seekBackward(key, false)
+}
+
+// Prev returns the largest key that is less than key
+func (t *BPTree[K, V]) Prev(key K) (K, V, bool) {
+	return t.seekBackward(key, true)
+}
+
+// Ceil returns the smallest key that is greater than or equal to key.
+// In multimap mode the first value of the key is returned, as Min does
+func (t *BPTree[K, V]) Ceil(key K) (K, V, bool) {
+	return t.seekForward(key, false)
+}
+
+// Next returns the smallest key that is greater than key
+func (t *BPTree[K, V]) Next(key K) (K, V, bool) {
+	return t.seekForward(key, true)
+}
+
+// seekForward 查找第一个大于等于（strict 时大于）key 的 kv。
+// 下降时记录最近的右边兄弟子树的分隔 key，叶子节点中没有结果时，
+// 结果一定是这棵子树中最小的 key，于是从分隔 key 重新查找。
+// 每次下降都只持有一条路径上的读锁，不沿着叶子链表加锁，避免和写者死锁
+func (t *BPTree[K, V]) seekForward(key K, strict bool) (rk K, rv V, found bool) {
+	for {
+		var bound K
+		hasBound := false
+		t.readLeafBy(func(inter *internalNode[K, V]) node[K, V] {
+			i := inter.lookupIndex(key)
+			if i+1 < inter.count {
+				bound, hasBound = inter.kcs[i+1].key, true
+			}
+			return inter.kcs[i].child
+		}, func(leaf *leafNode[K, V]) {
+			idx, ok := leaf.find(key)
+			if ok && strict {
+				idx++
+			}
+			if idx < leaf.count {
+				e := &leaf.kvs[idx]
+				rk, rv, found = e.key, e.value, true
+			}
+		})
+		if found || !hasBound {
+			return rk, rv, found
+		}
+		// 分隔 key 大于 key，右边子树中的 key 都不小于分隔 key
+		key, strict = bound, false
+	}
+}
+
+// seekBackward 查找最后一个小于等于（strict 时小于）key 的 kv，
+// 与 seekForward 对称，记录的是所在子树自己的分隔 key
+func (t *BPTree[K, V]) seekBackward(key K, strict bool) (rk K, rv V, found bool) {
+	for {
+		var bound K
+		hasBound := false
+		t.readLeafBy(func(inter *internalNode[K, V]) node[K, V] {
+			i := inter.lookupIndex(key)
+			if strict {
+				// 第一个大于等于 key 的分隔 key 左边的孩子才可能有小于 key 的 kv
+				i = max(sort.Search(inter.count, func(j int) bool {
+					return t.compare(inter.kcs[j].key, key) >= 0
+				})-1, 0)
+			}
+			if i > 0 {
+				bound, hasBound = inter.kcs[i].key, true
+			}
+			return inter.kcs[i].child
+		}, func(leaf *leafNode[K, V]) {
+			idx, ok := leaf.find(key)
+			if !ok || strict {
+				idx--
+			}
+			if idx >= 0 {
+				e := &leaf.kvs[idx]
+				rk, rv, found = e.key, e.value, true
+				if len(e.dups) > 0 {
+					rv = e.dups[len(e.dups)-1]
+				}
+			}
+		})
+		if found || !hasBound {
+			return rk, rv, found
+		}
+		// 左边子树中的 key 都小于分隔 key
+		key, strict = bound, true
+	}
+}
+
+// DeleteMin deletes the smallest pair and returns it, false if the tree is empty.
+// In multimap mode only the first value of the smallest key is deleted
+func (t *BPTree[K, V]) DeleteMin() (K, V, bool) {
+	return t.deleteEnd(func(inter *internalNode[K, V]) node[K, V] {
+		return inter.kcs[0].child
+	}, func(leaf *leafNode[K, V]) int {
+		return 0
+	}, func(e *kv[K, V]) (V, bool) {
+		v := e.value
+		if len(e.dups) == 0 {
+			return v, true
+		}
+		e.value, e.dups = e.dups[0], e.dups[1:]
+		return v, false
+	})
+}
+
+// DeleteMax deletes the largest pair and returns it, false if the tree is empty.
+// In multimap mode only the last value of the largest key is deleted
+func (t *BPTree[K, V]) DeleteMax() (K, V, bool) {
+	return t.deleteEnd(func(inter *internalNode[K, V]) node[K, V] {
+		return inter.kcs[inter.count-1].child
+	}, func(leaf *leafNode[K, V]) int {
+		return leaf.count - 1
+	}, func(e *kv[K, V]) (V, bool) {
+		if len(e.dups) == 0 {
+			return e.value, true
+		}
+		v := e.dups[len(e.dups)-1]
+		e.dups = e.dups[:len(e.dups)-1]
+		return v, false
+	})
+}
+
+// deleteEnd 沿 pick 下降到第一个或者最后一个叶子节点，删除其中第 at 个 key。
+// pop 从 kv 中取出一个 value，返回 true 时删除整个 kv。
+// 并发模式下选出 key 和删除时一直持有叶子节点的锁，比它更小或者更大的 key
+// 只能插入这个叶子节点，因此删除的一定是当时的最小或者最大的 key
+func (t *BPTree[K, V]) deleteEnd(pick func(inter *internalNode[K, V]) node[K, V], at func(leaf *leafNode[K, V]) int, pop func(e *kv[K, V]) (V, bool)) (K, V, bool) {
+	var (
+		key   K
+		value V
+		ok    bool
+	)
+	find := func(leaf *leafNode[K, V]) (K, bool) {
+		if leaf.count == 0 {
+			var zero K
+			return zero, false
+		}
+		key = leaf.kvs[at(leaf)].key
+		return key, true
+	}
+	remove := func(e *kv[K, V]) bool {
+		var all bool
+		value, all = pop(e)
+		return all
+	}
+	if t.concurrent {
+		ok = t.deleteConcurrentBy(pick, find, remove)
+	} else {
+		t.descend(pick, func(leaf *leafNode[K, V]) {
+			if _, found := find(leaf); found {
+				ok = t.deleteFromLeaf(leaf, key, remove, nil)
+			}
+		})
+	}
+	if !ok {
+		var zero K
+		return zero, value, false
+	}
+	return key, value, true
+}
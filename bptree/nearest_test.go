@@ -0,0 +1,144 @@
+package bptree
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiBPTree_Nearest(t *testing.T) {
+	assert := assert.New(t)
+	bt := NewMultiBPTree[int, string](MaxLeaf(3), MaxInternal(3))
+	for _, v := range []string{"a", "b", "c"} {
+		bt.Insert(10, v)
+		bt.Insert(20, v)
+	}
+
+	k, v, ok := bt.Floor(15)
+	assert.True(ok)
+	assert.Equal(10, k)
+	assert.Equal("c", v)
+	k, v, ok = bt.Ceil(15)
+	assert.True(ok)
+	assert.Equal(20, k)
+	assert.Equal("a", v)
+
+	k, v, _ = bt.DeleteMin()
+	assert.Equal(10, k)
+	assert.Equal("a", v)
+	k, v, _ = bt.DeleteMax()
+	assert.Equal(20, k)
+	assert.Equal("c", v)
+	assert.Equal([]string{"b", "c"}, bt.SearchAll(10))
+	assert.Equal([]string{"a", "b"}, bt.SearchAll(20))
+	assert.Equal(4, bt.Len())
+	assert.NoError(bt.Verify())
+}
+
+// 多个 goroutine 同时弹出最小的 key，每个 key 只被弹出一次
+func TestBPTree_ConcurrentDeleteMin(t *testing.T) {
+	const (
+		workers = 8
+		n       = 4000
+	)
+	bt := NewBPTree[int, int](MaxInternal(4), MaxLeaf(4), Concurrent())
+	for i := 0; i < n; i++ {
+		bt.Insert(i, i)
+	}
+	var (
+		wg     sync.WaitGroup
+		popped [workers][]int
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for {
+				key, value, ok := bt.DeleteMin()
+				if !ok {
+					return
+				}
+				assert.Equal(t, key, value)
+				popped[w] = append(popped[w], key)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	var all []int
+	for _, keys := range popped {
+		assert.True(t, sort.IntsAreSorted(keys))
+		all = append(all, keys...)
+	}
+	sort.Ints(all)
+	assert.Len(t, all, n)
+	for i, key := range all {
+		assert.Equal(t, i, key)
+	}
+	assert.Equal(t, 0, bt.Len())
+	assert.NoError(t, bt.Verify())
+}
+
+// 弹出和插入更小、更大的 key 同时进行，每个 key 只被弹出一次
+func TestBPTree_ConcurrentDeleteEnds(t *testing.T) {
+	const n = 2000
+	for _, option := range []Option{Concurrent(), ConcurrentCounts()} {
+		bt := NewBPTree[int, int](MaxInternal(3), MaxLeaf(4), option)
+		for i := 0; i < n; i++ {
+			bt.Insert(i, i)
+		}
+		var (
+			wg       sync.WaitGroup
+			inserted atomic.Bool
+			popped   [4][]int
+		)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 1; i <= n; i++ {
+				bt.Insert(-i, -i)
+				bt.Insert(n-1+i, n-1+i)
+			}
+			inserted.Store(true)
+		}()
+		for w := range popped {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for {
+					done := inserted.Load()
+					pop := bt.DeleteMin
+					if w%2 == 1 {
+						pop = bt.DeleteMax
+					}
+					key, value, ok := pop()
+					if !ok {
+						if done {
+							return
+						}
+						continue
+					}
+					assert.Equal(t, key, value)
+					popped[w] = append(popped[w], key)
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		var all []int
+		for _, keys := range popped {
+			all = append(all, keys...)
+		}
+		sort.Ints(all)
+		require.Len(t, all, 3*n)
+		for i, key := range all {
+			assert.Equal(t, i-n, key)
+		}
+		assert.Equal(t, 0, bt.Len())
+		assert.NoError(t, bt.Verify())
+	}
+}
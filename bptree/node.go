@@ -5,11 +5,11 @@ const (
 	MaxKC = 511
 )
 
-type node interface {
+type node[K, V any] interface {
 	// find return the index of element, and found or not
-	find(key int) (int, bool)
-	parent() *internalNode
-	setParent(*internalNode)
+	find(key K) (int, bool)
+	parent() *internalNode[K, V]
+	setParent(*internalNode[K, V])
 	full() bool
 	halfFull() bool
 	getMaxSize() int
@@ -17,12 +17,21 @@ type node interface {
 	getSize() int
 	resize(int)
 	isRoot() bool
-	moveLastToFrontOf(node)
-	moveAllTo(neighbor node)
+	moveLastToFrontOf(node[K, V])
+	moveAllTo(neighbor node[K, V])
 	isLeaf() bool
 	valueAt(i int) any
-	moveFirstToEndOf(n node)
-	getFirstKey() int
+	moveFirstToEndOf(n node[K, V])
+	getFirstKey() K
 	id() string
-	nextNode() node
+	nextNode() node[K, V]
+	// getTotal 返回子树中 kv 对的数量，多值模式下每个 value 单独计数
+	getTotal() int
+	addTotal(d int)
+	// recount 根据孩子或者 kv 对重新计算 total，用于分裂、合并、重组之后
+	recount()
+	lock()
+	unlock()
+	rLock()
+	rUnlock()
 }
@@ -0,0 +1,5 @@
+//go:build !race
+
+package bptree
+
+const raceEnabled = false
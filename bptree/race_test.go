@@ -0,0 +1,6 @@
+//go:build race
+
+package bptree
+
+// raceEnabled 开启竞争检测时测试慢很多，耗时长的测试缩小规模
+const raceEnabled = true
@@ -0,0 +1,58 @@
+package bptree
+
+// Rank returns the number of pairs whose key is less than key
+func (t *BPTree[K, V]) Rank(key K) int {
+	rank := 0
+	t.readCounted(func(inter *internalNode[K, V]) node[K, V] {
+		i := inter.lookupIndex(key)
+		for j := 0; j < i; j++ {
+			rank += inter.kcs[j].child.getTotal()
+		}
+		return inter.kcs[i].child
+	}, func(leaf *leafNode[K, V]) {
+		idx, _ := leaf.find(key)
+		for j := 0; j < idx; j++ {
+			rank += leaf.kvs[j].weight()
+		}
+	})
+	return rank
+}
+
+// Select returns the i-th smallest pair counting from 0, false if i is out of range.
+// In multimap mode every value of a key is a separate pair
+func (t *BPTree[K, V]) Select(i int) (key K, value V, ok bool) {
+	if i < 0 {
+		return key, value, false
+	}
+	t.readCounted(func(inter *internalNode[K, V]) node[K, V] {
+		for j := 0; j < inter.count-1; j++ {
+			total := inter.kcs[j].child.getTotal()
+			if i < total {
+				return inter.kcs[j].child
+			}
+			i -= total
+		}
+		return inter.kcs[inter.count-1].child
+	}, func(leaf *leafNode[K, V]) {
+		for j := 0; j < leaf.count; j++ {
+			e := &leaf.kvs[j]
+			if i < e.weight() {
+				key, value, ok = e.key, e.value, true
+				if i > 0 {
+					value = e.dups[i-1]
+				}
+				return
+			}
+			i -= e.weight()
+		}
+	})
+	return key, value, ok
+}
+
+// CountRange returns the number of pairs whose key is in [lo, hi)
+func (t *BPTree[K, V]) CountRange(lo, hi K) int {
+	if t.compare(lo, hi) >= 0 {
+		return 0
+	}
+	return t.Rank(hi) - t.Rank(lo)
+}
@@ -0,0 +1,109 @@
+package bptree
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBPTree_Rank(t *testing.T) {
+	assert := assert.New(t)
+	bt := NewBPTree[int, int](MaxInternal(3), MaxLeaf(3))
+	for i := 0; i < 100; i++ {
+		bt.Insert(i*2, i)
+	}
+	for i := 0; i < 100; i++ {
+		assert.Equal(i, bt.Rank(i*2))
+		assert.Equal(i+1, bt.Rank(i*2+1))
+		key, value, ok := bt.Select(i)
+		assert.True(ok)
+		assert.Equal(i*2, key)
+		assert.Equal(i, value)
+	}
+	assert.Equal(0, bt.Rank(-1))
+	_, _, ok := bt.Select(100)
+	assert.False(ok)
+	_, _, ok = bt.Select(-1)
+	assert.False(ok)
+	assert.Equal(5, bt.CountRange(10, 20))
+	assert.Equal(100, bt.CountRange(-1, 1000))
+	assert.Equal(0, bt.CountRange(20, 10))
+
+	for i := 0; i < 100; i += 2 {
+		assert.True(bt.Delete(i * 2))
+	}
+	assert.NoError(bt.Verify())
+	assert.Equal(50, bt.Len())
+	assert.Equal(25, bt.Rank(100))
+	key, _, _ := bt.Select(0)
+	assert.Equal(2, key)
+}
+
+func TestMultiBPTree_Rank(t *testing.T) {
+	assert := assert.New(t)
+	bt := NewMultiBPTree[int, string](MaxLeaf(3), MaxInternal(3))
+	bt.Insert(1, "x")
+	for _, v := range []string{"a", "b", "c"} {
+		bt.Insert(2, v)
+	}
+	bt.Insert(3, "y")
+
+	assert.Equal(5, bt.Len())
+	assert.Equal(1, bt.Rank(2))
+	assert.Equal(4, bt.Rank(3))
+	assert.Equal(3, bt.CountRange(2, 3))
+	for i, want := range []string{"x", "a", "b", "c", "y"} {
+		_, value, ok := bt.Select(i)
+		assert.True(ok)
+		assert.Equal(want, value)
+	}
+
+	assert.True(bt.DeleteValue(2, "b"))
+	assert.Equal(4, bt.Len())
+	assert.Equal(3, bt.Rank(3))
+	_, value, _ := bt.Select(2)
+	assert.Equal("c", value)
+	assert.NoError(bt.Verify())
+}
+
+// 并发插入删除之后，子树计数仍然与 key 一致。
+// 没有 ConcurrentCounts 时 Rank 和 Select 重新计数
+func TestBPTree_ConcurrentRank(t *testing.T) {
+	const workers = 8
+	for _, option := range []Option{Concurrent(), ConcurrentCounts()} {
+		bt := NewBPTree[int, int](MaxInternal(4), MaxLeaf(4), option)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				r := rand.New(rand.NewSource(int64(w)))
+				for i := 0; i < 2000; i++ {
+					key := r.Intn(500)*workers + w
+					if r.Intn(3) == 0 {
+						bt.Delete(key)
+					} else {
+						bt.Insert(key, i)
+					}
+					bt.Rank(key)
+					bt.Select(r.Intn(100))
+				}
+			}(w)
+		}
+		wg.Wait()
+
+		assert.NoError(t, bt.Verify())
+		i := 0
+		bt.Ascend(func(key int, value int) bool {
+			assert.Equal(t, i, bt.Rank(key))
+			k, _, ok := bt.Select(i)
+			assert.True(t, ok)
+			assert.Equal(t, key, k)
+			i++
+			return true
+		})
+		assert.Equal(t, bt.Len(), i)
+	}
+}
@@ -0,0 +1,330 @@
+package bptree
+
+// piece 拆分或者连接过程中的一棵子树，height 为叶子节点到 root 的层数，叶子节点为 0，
+// root 为 nil 表示空树
+type piece[K, V any] struct {
+	root   node[K, V]
+	height int
+}
+
+// Split moves the pairs whose key is less than key to the first tree and the
+// others to the second one, t is left empty. Whole subtrees are moved, only
+// the nodes on the path to key are cut, so it takes O(log n) plus rebalancing
+func (t *BPTree[K, V]) Split(key K) (*BPTree[K, V], *BPTree[K, V]) {
+	t.lockAll()
+	defer t.unlockAll()
+	left, right := t.empty(), t.empty()
+	if t.root == nil {
+		return left, right
+	}
+	l, r := t.splitPiece(piece[K, V]{t.root, t.height()}, key)
+	left.setRoot(l.root)
+	right.setRoot(r.root)
+	t.setRoot(nil)
+	return left, right
+}
+
+// Join moves all pairs of a and b into a new tree, a and b are left empty.
+// Every key of a must be less than every key of b and both trees must have
+// the same options. It takes O(log n) plus rebalancing
+func Join[K, V any](a, b *BPTree[K, V]) *BPTree[K, V] {
+	if a.maxLeaf != b.maxLeaf || a.maxInternal != b.maxInternal || (a.equal == nil) != (b.equal == nil) {
+		panic("bptree: Join needs trees with the same options")
+	}
+	a.lockAll()
+	defer a.unlockAll()
+	b.lockAll()
+	defer b.unlockAll()
+	t := a.empty()
+	switch {
+	case a.root == nil:
+		t.setRoot(b.root)
+	case b.root == nil:
+		t.setRoot(a.root)
+	default:
+		last, first := lastLeaf(a.root), firstLeaf(b.root)
+		if t.compare(last.kvs[last.count-1].key, first.kvs[0].key) >= 0 {
+			panic("bptree: Join needs the keys of a to be less than the keys of b")
+		}
+		last.next, first.prev = first, last
+		// b 的 k0 可能是已经删除的更小的 key，调高到真正的最小 key，
+		// 保证连接后的分隔 key 大于 a 中所有的 key
+		for n := b.root; !n.isLeaf(); n = n.(*internalNode[K, V]).kcs[0].child {
+			n.(*internalNode[K, V]).kcs[0].key = first.kvs[0].key
+		}
+		t.setRoot(t.concat(piece[K, V]{a.root, a.height()}, piece[K, V]{b.root, b.height()}).root)
+	}
+	a.setRoot(nil)
+	b.setRoot(nil)
+	return t
+}
+
+// DeleteRange deletes all pairs whose key is in [lo, hi) and returns the number
+// of deleted pairs. The range is cut out with two splits and the remaining
+// parts are joined, subtrees inside the range are unlinked without visiting them
+func (t *BPTree[K, V]) DeleteRange(lo, hi K) int {
+	if t.compare(lo, hi) >= 0 {
+		return 0
+	}
+	t.lockAll()
+	defer t.unlockAll()
+	if t.root == nil {
+		return 0
+	}
+	l, m := t.splitPiece(piece[K, V]{t.root, t.height()}, lo)
+	if m.root == nil {
+		t.setRoot(l.root)
+		return 0
+	}
+	m, r := t.splitPiece(m, hi)
+	if l.root != nil && r.root != nil {
+		last, first := lastLeaf(l.root), firstLeaf(r.root)
+		last.next, first.prev = first, last
+	}
+	deleted := 0
+	if m.root != nil {
+		deleted = m.root.getTotal()
+	}
+	t.setRoot(t.concat(l, r).root)
+	return deleted
+}
+
+// lockAll 并发模式下与 WriteBatch 一样独占整棵树，
+// 没有维护子树计数时重新计数，之后可以直接使用计数
+func (t *BPTree[K, V]) lockAll() {
+	if !t.concurrent {
+		return
+	}
+	t.batchLatch.Lock()
+	t.rootLatch.Lock()
+	if !t.counts && t.root != nil {
+		recountAll(t.root)
+	}
+}
+
+func (t *BPTree[K, V]) unlockAll() {
+	if t.concurrent {
+		t.rootLatch.Unlock()
+		t.batchLatch.Unlock()
+	}
+}
+
+// empty 返回与 t 选项相同的空树
+func (t *BPTree[K, V]) empty() *BPTree[K, V] {
+	return &BPTree[K, V]{options: t.options, compare: t.compare, equal: t.equal}
+}
+
+// setRoot 替换根节点，并由根节点的计数更新 size
+func (t *BPTree[K, V]) setRoot(root node[K, V]) {
+	t.root = root
+	if root == nil {
+		t.size.Store(0)
+		return
+	}
+	root.setParent(nil)
+	t.size.Store(int64(root.getTotal()))
+}
+
+func (t *BPTree[K, V]) height() int {
+	h := 0
+	for n := t.root; n != nil && !n.isLeaf(); n = n.(*internalNode[K, V]).kcs[0].child {
+		h++
+	}
+	return h
+}
+
+func firstLeaf[K, V any](n node[K, V]) *leafNode[K, V] {
+	for !n.isLeaf() {
+		n = n.(*internalNode[K, V]).kcs[0].child
+	}
+	return n.(*leafNode[K, V])
+}
+
+func lastLeaf[K, V any](n node[K, V]) *leafNode[K, V] {
+	for !n.isLeaf() {
+		inter := n.(*internalNode[K, V])
+		n = inter.kcs[inter.count-1].child
+	}
+	return n.(*leafNode[K, V])
+}
+
+// splitPiece 沿 key 的路径把 p 拆成两棵树，左边的 key 都小于 key，右边的都不小于 key。
+// 路径上每个节点在下降的孩子处一分为二，孩子左边的部分和右边的部分各自成为一棵树，
+// 最后左边的树按从上到下的顺序、右边的树按从下到上的顺序连接起来。
+// 路径两侧的子树原样保留，叶子链表只在 key 处断开
+func (t *BPTree[K, V]) splitPiece(p piece[K, V], key K) (piece[K, V], piece[K, V]) {
+	var lefts, rights []piece[K, V]
+	n, h := p.root, p.height
+	for !n.isLeaf() {
+		inter := n.(*internalNode[K, V])
+		i := inter.lookupIndex(key)
+		child := inter.kcs[i].child
+		right := newInternalNode[K, V](t.maxInternal, t.compare)
+		right.count = copy(right.kcs, inter.kcs[i+1:inter.count])
+		for j := 0; j < right.count; j++ {
+			right.kcs[j].child.setParent(right)
+		}
+		clear(inter.kcs[i:inter.count])
+		inter.count = i
+		lefts = append(lefts, newPiece[K, V](inter, h))
+		rights = append(rights, newPiece[K, V](right, h))
+		n, h = child, h-1
+	}
+
+	leaf := n.(*leafNode[K, V])
+	idx, _ := leaf.find(key)
+	right := newLeafNode[K, V](t.maxLeaf, t.compare)
+	right.count = copy(right.kvs, leaf.kvs[idx:leaf.count])
+	clear(leaf.kvs[idx:leaf.count])
+	leaf.count = idx
+	t.links.Lock()
+	prev, next := leaf.prev, leaf.next
+	if leaf.count == 0 && prev != nil {
+		prev.next = nil
+	}
+	leaf.next = nil
+	if right.count > 0 {
+		right.next = next
+	}
+	if next != nil {
+		next.prev = nil
+		if right.count > 0 {
+			next.prev = right
+		}
+	}
+	t.links.Unlock()
+	lefts = append(lefts, newPiece[K, V](leaf, 0))
+	rights = append(rights, newPiece[K, V](right, 0))
+
+	l := lefts[0]
+	for _, p := range lefts[1:] {
+		l = t.concat(l, p)
+	}
+	r := rights[len(rights)-1]
+	for i := len(rights) - 2; i >= 0; i-- {
+		r = t.concat(r, rights[i])
+	}
+	return l, r
+}
+
+// newPiece 由拆分后的节点构造一棵树：没有 kv 或者孩子时为空树，
+// 内部节点只有一个孩子时由孩子作为根节点，高度减一。
+// MaxInternal(3) 时孩子也可能只有一个孩子，一直下降到有两个孩子的节点
+func newPiece[K, V any](n node[K, V], height int) piece[K, V] {
+	if n.getSize() == 0 {
+		return piece[K, V]{}
+	}
+	n.recount()
+	n.setParent(nil)
+	return shrink(piece[K, V]{n, height})
+}
+
+// concat 连接两棵树，a 中的 key 都小于 b 中的 key，并且 b 的 k0 大于 a 中所有的 key。
+// 矮的树作为高的树最右边（或者最左边）的孩子挂在同样高度的位置，
+// 再与相邻的兄弟重组或者合并，父节点满了则向上分裂。叶子链表由调用者维护
+func (t *BPTree[K, V]) concat(a, b piece[K, V]) piece[K, V] {
+	switch {
+	case a.root == nil:
+		return b
+	case b.root == nil:
+		return a
+	case a.height == b.height:
+		root := newInternalNode[K, V](t.maxInternal, t.compare)
+		root.kcs[0] = kc[K, V]{key: a.root.getFirstKey(), child: a.root}
+		root.kcs[1] = kc[K, V]{key: b.root.getFirstKey(), child: b.root}
+		root.count = 2
+		a.root.setParent(root)
+		b.root.setParent(root)
+		root.recount()
+		t.fixPair(root, 0)
+		if root.count == 1 {
+			child := root.kcs[0].child
+			child.setParent(nil)
+			return piece[K, V]{child, a.height}
+		}
+		return piece[K, V]{root, a.height + 1}
+	case a.height > b.height:
+		// a 的最右路径上，孩子高度与 b 相同的节点
+		p := a.root.(*internalNode[K, V])
+		for h := a.height; h > b.height+1; h-- {
+			p = p.kcs[p.count-1].child.(*internalNode[K, V])
+		}
+		p.kcs[p.count] = kc[K, V]{key: b.root.getFirstKey(), child: b.root}
+		p.count++
+		b.root.setParent(p)
+		for q := p; q != nil; q = q.p {
+			q.addTotal(b.root.getTotal())
+		}
+		t.fixPair(p, p.count-2)
+		return t.splitFull(p, shrink(a))
+	default:
+		// b 的最左路径上，孩子高度与 a 相同的节点，原来的 k0 成为分隔 key
+		p := b.root.(*internalNode[K, V])
+		for h := b.height; h > a.height+1; h-- {
+			p = p.kcs[0].child.(*internalNode[K, V])
+		}
+		copy(p.kcs[1:p.count+1], p.kcs[:p.count])
+		p.kcs[0] = kc[K, V]{key: a.root.getFirstKey(), child: a.root}
+		p.count++
+		a.root.setParent(p)
+		for q := p; q != nil; q = q.p {
+			q.addTotal(a.root.getTotal())
+		}
+		t.lowerFirstKeys(p, a.root.getFirstKey())
+		t.fixPair(p, 0)
+		return t.splitFull(p, shrink(b))
+	}
+}
+
+// fixPair p 的第 i 和 i+1 个孩子中有不足半满的节点时，能放下则合并，否则重新分配，
+// 两者加起来至少有 max 项，因此分配后都不少于 max/2 项
+func (t *BPTree[K, V]) fixPair(p *internalNode[K, V], i int) {
+	x, y := p.kcs[i].child, p.kcs[i+1].child
+	if x.getSize() >= x.getMinSize() && y.getSize() >= y.getMinSize() {
+		return
+	}
+	if x.getSize()+y.getSize() < x.getMaxSize() {
+		t.links.Lock()
+		y.moveAllTo(x)
+		t.links.Unlock()
+		x.recount()
+		p.remove(y)
+		return
+	}
+	for y.getSize() < y.getMinSize() {
+		x.moveLastToFrontOf(y)
+	}
+	for x.getSize() < x.getMinSize() {
+		y.moveFirstToEndOf(x)
+	}
+	p.setKeyAt(i+1, y)
+	x.recount()
+	y.recount()
+}
+
+// shrink 合并后根节点只剩一个孩子时，由孩子作为根节点
+func shrink[K, V any](p piece[K, V]) piece[K, V] {
+	for !p.root.isLeaf() && p.root.getSize() == 1 {
+		p.root = p.root.(*internalNode[K, V]).kcs[0].child
+		p.root.setParent(nil)
+		p.height--
+	}
+	return p
+}
+
+// splitFull p 满了则分裂，并沿祖先向上插入，返回 top 这棵树连接后的根节点和高度
+func (t *BPTree[K, V]) splitFull(p *internalNode[K, V], top piece[K, V]) piece[K, V] {
+	if !p.full() {
+		return top
+	}
+	t.root = top.root
+	t.changes.Begin()
+	sibling, midKey := p.split()
+	p.recount()
+	sibling.recount()
+	t.insertIntoParent(p, sibling, midKey)
+	if t.root != top.root {
+		return piece[K, V]{t.root, top.height + 1}
+	}
+	return top
+}
@@ -0,0 +1,237 @@
+package bptree
+
+import (
+	"math/rand"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func rangeKeys(lo, hi, step int) []int {
+	var keys []int
+	for k := lo; k < hi; k += step {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func TestBPTree_Split(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{3, 4, 7} {
+		for n := 0; n < 300; n += 37 {
+			for i := 0; i < 10; i++ {
+				bt := NewBPTree[int, int](MaxInternal(size), MaxLeaf(size))
+				keys := rangeKeys(0, n*2, 2)
+				for _, k := range r.Perm(len(keys)) {
+					bt.Insert(keys[k], keys[k]*10)
+				}
+				key := r.Intn(n*2+4) - 2
+				left, right := bt.Split(key)
+				at, _ := slices.BinarySearch(keys, key)
+				checkLinks(t, left, append([]int(nil), keys[:at]...))
+				checkLinks(t, right, append([]int(nil), keys[at:]...))
+				checkLinks(t, bt, nil)
+
+				// 拆分后的树可以继续修改
+				left.Insert(-1, -10)
+				right.Insert(n*2+1, 0)
+				assert.NoError(t, left.Verify())
+				assert.NoError(t, right.Verify())
+				v, ok := left.Search(-1)
+				assert.True(t, ok)
+				assert.Equal(t, -10, v)
+			}
+		}
+	}
+}
+
+func TestBPTree_Join(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, size := range []int{3, 4, 7} {
+		// 高度相差较大的两棵树
+		for _, n := range [][2]int{{0, 0}, {0, 10}, {1, 300}, {300, 1}, {50, 60}, {1000, 3}} {
+			a := NewBPTree[int, int](MaxInternal(size), MaxLeaf(size))
+			b := NewBPTree[int, int](MaxInternal(size), MaxLeaf(size))
+			for i := 0; i < n[0]; i++ {
+				a.Insert(i, i)
+			}
+			for i := 0; i < n[1]; i++ {
+				b.Insert(n[0]+i, i)
+			}
+			// 删除 b 的一部分最小 key，留下过时的 k0
+			for i := 0; i < n[1]/3; i++ {
+				b.Delete(n[0] + r.Intn(n[1]/3+1))
+			}
+			var keys []int
+			a.Ascend(func(key, value int) bool {
+				keys = append(keys, key)
+				return true
+			})
+			b.Ascend(func(key, value int) bool {
+				keys = append(keys, key)
+				return true
+			})
+			joined := Join(a, b)
+			checkLinks(t, joined, keys)
+			checkLinks(t, a, nil)
+			checkLinks(t, b, nil)
+			for _, k := range keys {
+				assert.Equal(t, slices.Index(keys, k), joined.Rank(k))
+			}
+		}
+	}
+}
+
+func TestBPTree_JoinPanics(t *testing.T) {
+	a := NewBPTree[int, int](MaxInternal(3), MaxLeaf(3))
+	b := NewBPTree[int, int](MaxInternal(3), MaxLeaf(3))
+	a.Insert(5, 5)
+	b.Insert(5, 5)
+	assert.Panics(t, func() { Join(a, b) })
+	assert.Panics(t, func() { Join(a, NewBPTree[int, int](MaxLeaf(4))) })
+}
+
+func TestBPTree_DeleteRange(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	for _, size := range []int{3, 4, 7} {
+		bt := NewBPTree[int, int](MaxInternal(size), MaxLeaf(size))
+		model := map[int]bool{}
+		for i := 0; i < 200; i++ {
+			switch r.Intn(4) {
+			case 0:
+				lo := r.Intn(1000)
+				hi := lo + r.Intn(100)
+				deleted := 0
+				for k := range model {
+					if k >= lo && k < hi {
+						delete(model, k)
+						deleted++
+					}
+				}
+				assert.Equal(t, deleted, bt.DeleteRange(lo, hi))
+			default:
+				for j := 0; j < 20; j++ {
+					k := r.Intn(1000)
+					bt.Insert(k, k)
+					model[k] = true
+				}
+			}
+			keys := make([]int, 0, len(model))
+			for k := range model {
+				keys = append(keys, k)
+			}
+			slices.Sort(keys)
+			if len(keys) == 0 {
+				keys = nil
+			}
+			checkLinks(t, bt, keys)
+		}
+		assert.Equal(t, 0, bt.DeleteRange(10, 10))
+		assert.Equal(t, bt.Len(), bt.DeleteRange(-1, 1000))
+		checkLinks(t, bt, nil)
+	}
+}
+
+// 叶子节点和内部节点大小不同，MaxInternal(3) 的内部节点可能只有一个孩子
+func TestBPTree_SplitJoinMixed(t *testing.T) {
+	for seed := 0; seed < 20; seed++ {
+		for _, size := range [][2]int{{4, 3}, {6, 3}, {3, 5}, {5, 4}} {
+			testSplitJoin(t, rand.New(rand.NewSource(int64(seed))), size[0], size[1])
+		}
+	}
+}
+
+// testSplitJoin 随机插入、DeleteRange、Split 和 Join，每次操作之后检查树的结构
+func testSplitJoin(t *testing.T, r *rand.Rand, maxLeaf, maxInternal int) {
+	bt := NewBPTree[int, int](MaxLeaf(maxLeaf), MaxInternal(maxInternal))
+	model := map[int]bool{}
+	for i := 0; i < 300; i++ {
+		switch r.Intn(6) {
+		case 0:
+			lo := r.Intn(500)
+			hi := lo + r.Intn(80)
+			deleted := 0
+			for k := range model {
+				if k >= lo && k < hi {
+					delete(model, k)
+					deleted++
+				}
+			}
+			assert.Equal(t, deleted, bt.DeleteRange(lo, hi))
+			assert.NoError(t, bt.Verify(), "%d/%d DeleteRange(%d, %d)", maxLeaf, maxInternal, lo, hi)
+		case 1:
+			key := r.Intn(500)
+			left, right := bt.Split(key)
+			assert.NoError(t, left.Verify(), "%d/%d Split(%d)", maxLeaf, maxInternal, key)
+			assert.NoError(t, right.Verify(), "%d/%d Split(%d)", maxLeaf, maxInternal, key)
+			bt = Join(left, right)
+			assert.NoError(t, bt.Verify(), "%d/%d Join at %d", maxLeaf, maxInternal, key)
+		default:
+			for j := 0; j < 10; j++ {
+				k := r.Intn(500)
+				bt.Insert(k, k)
+				model[k] = true
+			}
+		}
+	}
+	keys := make([]int, 0, len(model))
+	for k := range model {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	checkLinks(t, bt, keys)
+}
+
+func TestMultiBPTree_Split(t *testing.T) {
+	assert := assert.New(t)
+	bt := NewMultiBPTree[int, int](MaxInternal(3), MaxLeaf(3))
+	for i := 0; i < 20; i++ {
+		for v := 0; v < 5; v++ {
+			bt.Insert(i, v)
+		}
+	}
+	assert.Equal(25, bt.DeleteRange(5, 10))
+	assert.NoError(bt.Verify())
+	assert.Equal(75, bt.Len())
+	assert.Nil(bt.SearchAll(7))
+
+	left, right := bt.Split(12)
+	assert.NoError(left.Verify())
+	assert.NoError(right.Verify())
+	assert.Equal(35, left.Len())
+	assert.Equal(40, right.Len())
+	assert.Equal([]int{0, 1, 2, 3, 4}, right.SearchAll(12))
+
+	joined := Join(left, right)
+	assert.NoError(joined.Verify())
+	assert.Equal(75, joined.Len())
+	assert.Equal([]int{0, 1, 2, 3, 4}, joined.SearchAll(11))
+}
+
+// DeleteRange 与其他写操作并发，只删除 [0, 1000) 中的 key，其他 key 都保留
+func TestBPTree_ConcurrentDeleteRange(t *testing.T) {
+	bt := NewBPTree[int, int](MaxInternal(4), MaxLeaf(4), Concurrent())
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				bt.Insert((i*4+w)%1000, i)
+				bt.Insert(1000+i*4+w, i)
+			}
+		}(w)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			bt.DeleteRange(0, 1000)
+		}
+	}()
+	wg.Wait()
+	bt.DeleteRange(0, 1000)
+	checkLinks(t, bt, rangeKeys(1000, 3000, 1))
+}
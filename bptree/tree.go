@@ -2,35 +2,102 @@ package bptree
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
 	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/pedrogao/btrees/common"
 )
 
 // BPTree b+ tree
-type BPTree struct {
-	root        node
+type BPTree[K, V any] struct {
+	options
+	root      node[K, V]
+	compare   func(a, b K) int
+	equal     func(a, b V) bool // 不为 nil 时为多值模式，同一个 key 可以有多个 value
+	rootLatch sync.RWMutex      // 并发模式下保护 root 指针
+	// 并发模式下 WriteBatch 持有写锁，其他操作在整个下降过程中持有读锁
+	batchLatch sync.RWMutex
+	links      sync.Mutex                     // 保护叶子节点之间的 next、prev 指针
+	size       atomic.Int64                   // kv 对数量，多值模式下每个 value 单独计数
+	changes    common.ChangeTrace[node[K, V]] // 最近一次分裂或者合并修改的节点，导出时高亮
+}
+
+var (
+	_ common.OrderedMap[int, any]      = (*BPTree[int, any])(nil)
+	_ common.OrderStatistics[int, any] = (*BPTree[int, any])(nil)
+	_ common.Navigable[int, any]       = (*BPTree[int, any])(nil)
+)
+
+type options struct {
 	maxLeaf     int
 	maxInternal int
+	concurrent  bool
+	counts      bool // 并发模式下维护准确的子树计数
+	fill        float64
 }
 
-type Option func(tree *BPTree)
+type Option func(opts *options)
 
 func MaxLeaf(max int) Option {
-	return func(tree *BPTree) {
-		tree.maxLeaf = max
+	return func(opts *options) {
+		opts.maxLeaf = max
 	}
 }
 
 func MaxInternal(max int) Option {
-	return func(tree *BPTree) {
-		tree.maxInternal = max
+	return func(opts *options) {
+		opts.maxInternal = max
+	}
+}
+
+// Concurrent makes Search, Insert, Delete and the read-modify-write operations safe for use by multiple goroutines.
+// Every node has a read-write latch and operations crab from the root down,
+// releasing ancestors as soon as the child can not split or underflow.
+// Writes do not keep the subtree counts of the internal nodes up to date, so
+// Rank, Select, CountRange, Split, Join and DeleteRange recount the whole tree
+// in O(n) and, like WriteBatch.Apply, exclude all other operations while they run;
+// see ConcurrentCounts. Iteration, cursors and Graph are not synchronized with writers
+func Concurrent() Option {
+	return func(opts *options) {
+		opts.concurrent = true
+	}
+}
+
+// ConcurrentCounts implies Concurrent and keeps the subtree counts up to date,
+// so the order statistics take O(log n) and run in parallel with lookups.
+// Adding or removing a pair changes the counts of all ancestors, so such
+// writes keep the whole path latched and run one at a time; lookups and
+// writes that only replace a value still run in parallel
+func ConcurrentCounts() Option {
+	return func(opts *options) {
+		opts.concurrent = true
+		opts.counts = true
 	}
 }
 
-func NewBPTree(options ...Option) *BPTree {
-	b := &BPTree{}
+// FillFactor sets how full BulkLoad packs the nodes, from (0, 1],
+// it defaults to common.DefaultFillFactor
+func FillFactor(fill float64) Option {
+	return func(opts *options) {
+		opts.fill = fill
+	}
+}
+
+// NewBPTree returns a b+ tree ordered by the natural order of K
+func NewBPTree[K cmp.Ordered, V any](options ...Option) *BPTree[K, V] {
+	return NewBPTreeFunc[K, V](cmp.Compare[K], options...)
+}
+
+// NewBPTreeFunc returns a b+ tree ordered by compare, which must return
+// a negative number when a < b, zero when a == b and a positive number when a > b.
+// It is meant for key types that are not cmp.Ordered, e.g. []byte or composite keys
+func NewBPTreeFunc[K, V any](compare func(a, b K) int, options ...Option) *BPTree[K, V] {
+	b := &BPTree[K, V]{compare: compare}
 	for _, option := range options {
-		option(b)
+		option(&b.options)
 	}
 	if b.maxInternal <= 0 {
 		b.maxInternal = MaxKC
@@ -42,16 +109,16 @@ func NewBPTree(options ...Option) *BPTree {
 }
 
 // First returns the first leafNode
-func (t *BPTree) First() *leafNode {
+func (t *BPTree[K, V]) First() *leafNode[K, V] {
 	var (
 		tmp   = t.root
-		inter *internalNode
+		inter *internalNode[K, V]
 		ok    bool
 	)
 
 	// 如果是内部节点，则一直往下找
 	for tmp != nil {
-		inter, ok = tmp.(*internalNode)
+		inter, ok = tmp.(*internalNode[K, V])
 		if !ok {
 			// tmp 不是内部节点，那么是叶子节点，直接 break
 			break
@@ -59,15 +126,25 @@ func (t *BPTree) First() *leafNode {
 		tmp = inter.kcs[0].child
 	}
 
-	return tmp.(*leafNode)
+	return tmp.(*leafNode[K, V])
 }
 
-func (t *BPTree) Empty() bool {
+func (t *BPTree[K, V]) Empty() bool {
+	if t.concurrent {
+		t.rootLatch.RLock()
+		defer t.rootLatch.RUnlock()
+	}
 	return t.root == nil
 }
 
-// Insert key->value
-func (t *BPTree) Insert(key int, value string) {
+// Insert key->value, the value of an existing key is replaced,
+// in multimap mode the value is appended to the values of the key.
+// Put reports the replaced value
+func (t *BPTree[K, V]) Insert(key K, value V) {
+	if t.concurrent {
+		t.insertConcurrent(key, value)
+		return
+	}
 	// 如果是空树，那么新建 root 节点
 	if t.root == nil {
 		t.startRoot(key, value)
@@ -77,44 +154,201 @@ func (t *BPTree) Insert(key int, value string) {
 	t.insertIntoLeaf(key, value)
 }
 
-// Delete key
-func (t *BPTree) Delete(key int) {
+// Delete key and report whether it existed, in multimap mode all values of the key are deleted
+func (t *BPTree[K, V]) Delete(key K) bool {
+	return t.delete(key, nil)
+}
+
+// delete 删除 key，返回 key 是否存在。remove 不为 nil 时由它决定是否删除整个 key，
+// 多值模式下只从 posting list 中删除一个 value、key 仍然存在时也返回 true
+func (t *BPTree[K, V]) delete(key K, remove func(e *kv[K, V]) bool) bool {
+	if t.concurrent {
+		return t.deleteConcurrent(key, remove)
+	}
 	if t.Empty() {
-		return
+		return false
 	}
 	leaf := t.findLeaf(key)
 	if leaf == nil {
-		return
+		return false
 	}
-	ok := leaf.remove(key)
+	return t.deleteFromLeaf(leaf, key, remove, nil)
+}
+
+// deleteFromLeaf 从叶子节点中删除 key 并调整树结构，返回 key 是否存在。
+// 并发模式下 s 必须持有整条路径的锁，用来更新祖先的计数
+func (t *BPTree[K, V]) deleteFromLeaf(leaf *leafNode[K, V], key K, remove func(e *kv[K, V]) bool, s *latchSet[K, V]) bool {
+	idx, ok := leaf.find(key)
 	if !ok {
+		return false
+	}
+	e := &leaf.kvs[idx]
+	weight := e.weight()
+	if remove != nil && !remove(e) {
+		t.addTotal(leaf, e.weight()-weight)
+		return true
+	}
+	t.addTotal(leaf, -weight)
+	leaf.remove(key)
+	t.changes.Begin()
+	t.coalesceOrRedistribute(leaf, s)
+	return true
+}
+
+// addTotal 叶子节点中的 kv 对数量变化了 d，更新它和所有祖先的计数。
+// 没有维护子树计数时，祖先的锁可能已经释放，只更新叶子节点
+func (t *BPTree[K, V]) addTotal(leaf *leafNode[K, V], d int) {
+	if d == 0 {
+		return
+	}
+	t.size.Add(int64(d))
+	leaf.addTotal(d)
+	if !t.counted() {
 		return
 	}
-	t.coalesceOrRedistribute(leaf)
+	for p := leaf.parent(); p != nil; p = p.parent() {
+		p.addTotal(d)
+	}
+}
+
+// counted 内部节点的计数是否随写操作更新
+func (t *BPTree[K, V]) counted() bool {
+	return !t.concurrent || t.counts
+}
+
+// recountAll 自底向上重新计算所有节点的计数，调用者独占整棵树
+func recountAll[K, V any](n node[K, V]) {
+	if inter, ok := n.(*internalNode[K, V]); ok {
+		for i := 0; i < inter.count; i++ {
+			recountAll(inter.kcs[i].child)
+		}
+	}
+	n.recount()
 }
 
 // Search searches the key in B+ tree
 // If the key exists, it returns the value of key and true
-// If the key does not exist, it returns an empty string and false
-func (t *BPTree) Search(key int) (string, bool) {
-	if t.Empty() {
-		return "", false
+// If the key does not exist, it returns the zero value of V and false.
+// In multimap mode it returns the first value of the key
+func (t *BPTree[K, V]) Search(key K) (V, bool) {
+	var (
+		value V
+		found bool
+	)
+	t.readLeaf(key, func(leaf *leafNode[K, V]) {
+		if idx, ok := leaf.find(key); ok {
+			value, found = leaf.kvs[idx].value, true
+		}
+	})
+	return value, found
+}
+
+// readLeaf 找到 key 所在的叶子节点并调用 fn，并发模式下 fn 执行期间持有叶子节点的读锁
+func (t *BPTree[K, V]) readLeaf(key K, fn func(leaf *leafNode[K, V])) {
+	t.readLeafBy(func(inter *internalNode[K, V]) node[K, V] {
+		return inter.lookup(key)
+	}, fn)
+}
+
+// readLeafBy 从根节点开始由 pick 选择孩子，一直下降到叶子节点并调用 fn，空树不调用 fn
+func (t *BPTree[K, V]) readLeafBy(pick func(inter *internalNode[K, V]) node[K, V], fn func(leaf *leafNode[K, V])) {
+	if t.concurrent {
+		t.readLeafConcurrent(pick, fn)
+		return
 	}
+	t.descend(pick, fn)
+}
 
-	leaf := t.findLeaf(key)
-	if leaf == nil {
-		return "", false
+// readCounted 与 readLeafBy 相同，pick 可以使用子树计数。
+// 并发模式下没有维护计数时，独占整棵树并重新计数
+func (t *BPTree[K, V]) readCounted(pick func(inter *internalNode[K, V]) node[K, V], fn func(leaf *leafNode[K, V])) {
+	if t.counted() {
+		t.readLeafBy(pick, fn)
+		return
 	}
+	t.lockAll()
+	defer t.unlockAll()
+	t.descend(pick, fn)
+}
 
-	idx, b := leaf.find(key)
-	if !b {
-		return "", false
+// descend 不加锁地从根节点下降到叶子节点
+func (t *BPTree[K, V]) descend(pick func(inter *internalNode[K, V]) node[K, V], fn func(leaf *leafNode[K, V])) {
+	n := t.root
+	if n == nil {
+		return
+	}
+	for !n.isLeaf() {
+		n = pick(n.(*internalNode[K, V]))
+	}
+	fn(n.(*leafNode[K, V]))
+}
+
+// Get returns the value of key and whether the key exists, it is the same as Search
+func (t *BPTree[K, V]) Get(key K) (V, bool) {
+	return t.Search(key)
+}
+
+// Set stores key->value, it is the same as Insert
+func (t *BPTree[K, V]) Set(key K, value V) {
+	t.Insert(key, value)
+}
+
+// Len returns the number of key/value pairs, in multimap mode every value is counted
+func (t *BPTree[K, V]) Len() int {
+	if t.concurrent {
+		// 不读到 WriteBatch 执行到一半时的数量
+		t.batchLatch.RLock()
+		defer t.batchLatch.RUnlock()
 	}
+	return int(t.size.Load())
+}
 
-	return leaf.kvs[idx].value, true
+// Min returns the smallest key and its first value, false if the tree is empty
+func (t *BPTree[K, V]) Min() (key K, value V, ok bool) {
+	t.readLeafBy(func(inter *internalNode[K, V]) node[K, V] {
+		return inter.kcs[0].child
+	}, func(leaf *leafNode[K, V]) {
+		e := &leaf.kvs[0]
+		key, value, ok = e.key, e.value, true
+	})
+	return key, value, ok
 }
 
-func (t *BPTree) coalesceOrRedistribute(n node) {
+// Max returns the largest key and its last value, false if the tree is empty
+func (t *BPTree[K, V]) Max() (key K, value V, ok bool) {
+	t.readLeafBy(func(inter *internalNode[K, V]) node[K, V] {
+		return inter.kcs[inter.count-1].child
+	}, func(leaf *leafNode[K, V]) {
+		e := &leaf.kvs[leaf.count-1]
+		key, value, ok = e.key, e.value, true
+		if len(e.dups) > 0 {
+			value = e.dups[len(e.dups)-1]
+		}
+	})
+	return key, value, ok
+}
+
+// insertIntoLeafNode 多值模式下 key 已存在时追加到 posting list，否则插入新的 key。
+// 新增 kv 对时更新祖先的计数
+func (t *BPTree[K, V]) insertIntoLeafNode(leaf *leafNode[K, V], key K, value V) {
+	if t.equal != nil {
+		if idx, ok := leaf.find(key); ok {
+			leaf.kvs[idx].dups = append(leaf.kvs[idx].dups, value)
+			t.addTotal(leaf, 1)
+			return
+		}
+	}
+	if leaf.insert(key, value) {
+		t.addTotal(leaf, 1)
+	}
+}
+
+// coalesceOrRedistribute 并发模式下 s 持有 n 及其祖先的写锁，
+// 兄弟节点在修改前加锁，结构修改不会越过 s 持有的最上层节点
+func (t *BPTree[K, V]) coalesceOrRedistribute(n node[K, V], s *latchSet[K, V]) {
+	if top := s.top(); top != nil && top == n {
+		return
+	}
 	if n.isRoot() {
 		t.adjustRoot(n)
 		return
@@ -123,17 +357,35 @@ func (t *BPTree) coalesceOrRedistribute(n node) {
 	if n.halfFull() {
 		return
 	}
+	t.rebalance(n, s)
+}
+
+// rebalance n 与兄弟节点重组或者合并。内部节点最小只有一个孩子时（如 MaxInternal(3)），
+// n 可能没有兄弟节点：先通过祖父节点重组或者合并 parent，使 n 有兄弟节点。
+// 并发模式下 parent 只有一个孩子时不安全，祖父节点也在 s 中
+func (t *BPTree[K, V]) rebalance(n node[K, V], s *latchSet[K, V]) {
 	parent := n.parent()
+	if parent.getSize() == 1 {
+		if parent.isRoot() {
+			// 根节点只有一个孩子，由 n 作为根节点
+			t.adjustRoot(parent)
+			t.coalesceOrRedistribute(n, s)
+			return
+		}
+		t.rebalance(parent, s)
+		parent = n.parent()
+	}
 	idx := parent.valueIndex(n)
 	if idx < 0 {
 		panic("can't find child")
 	}
-	var sibling node
+	var sibling node[K, V]
 	if idx == 0 {
 		sibling = parent.kcs[idx+1].child
 	} else {
 		sibling = parent.kcs[idx-1].child
 	}
+	s.lock(sibling)
 	// 重组
 	if n.getSize()+sibling.getSize() >= n.getMaxSize() {
 		t.redistribute(sibling, n, parent, idx)
@@ -142,15 +394,15 @@ func (t *BPTree) coalesceOrRedistribute(n node) {
 	// 合并
 	if idx == 0 {
 		// n 在左边，sibling 在右边
-		t.coalesce(n, sibling, parent)
+		t.coalesce(n, sibling, parent, s)
 	} else {
 		// n 在右边
-		t.coalesce(sibling, n, parent)
+		t.coalesce(sibling, n, parent, s)
 	}
 }
 
-func (t *BPTree) redistribute(neighbor, n node,
-	parent *internalNode, index int) {
+func (t *BPTree[K, V]) redistribute(neighbor, n node[K, V],
+	parent *internalNode[K, V], index int) {
 	// 将 neighbor 末尾移到 node 的最前面
 	// 或者将 node 的开始项移到 neighbor 末尾
 	if index == 0 {
@@ -159,77 +411,111 @@ func (t *BPTree) redistribute(neighbor, n node,
 		parent.setKeyAt(1, neighbor)
 	} else {
 		neighbor.moveLastToFrontOf(n)
-		parent.setKeyAt(index, neighbor)
+		parent.setKeyAt(index, n)
 	}
+	// 父节点的计数不变
+	neighbor.recount()
+	n.recount()
 }
 
-func (t *BPTree) coalesce(neighbor, n node, parent *internalNode) {
+func (t *BPTree[K, V]) coalesce(neighbor, n node[K, V], parent *internalNode[K, V], s *latchSet[K, V]) {
 	// 合并以后可能还需要合并或者重组
 	// n 所有项移动到 neighbor
+	t.links.Lock()
 	n.moveAllTo(neighbor)
+	t.links.Unlock()
+	neighbor.recount()
+	n.recount()
 	// 从 parent 中删除 node
 	parent.remove(n)
-	t.coalesceOrRedistribute(parent)
+	t.changes.Touch(neighbor, parent)
+	t.coalesceOrRedistribute(parent, s)
 }
 
-func (t *BPTree) adjustRoot(oldRoot node) {
+func (t *BPTree[K, V]) adjustRoot(oldRoot node[K, V]) {
 	// 根节点还不是最后一个节点，仍然是内部节点，且有一个孩子节点
 	if oldRoot.getSize() == 1 && !oldRoot.isLeaf() {
-		t.root = oldRoot.valueAt(0).(node)
+		t.root = oldRoot.valueAt(0).(node[K, V])
 		t.root.setParent(nil)
 	}
 	// 只剩下根节点了，且已经没有子节点了
-	if oldRoot.isLeaf() && oldRoot.getSize() == 0 {
+	if oldRoot.getSize() == 0 {
 		t.root = nil
 	}
 }
 
-func (t *BPTree) insertIntoLeaf(key int, value string) {
+func (t *BPTree[K, V]) insertIntoLeaf(key K, value V) {
 	leaf := t.findLeaf(key)
 	if leaf == nil {
 		return
 	}
-	leaf.insert(key, value)
+	t.insertIntoLeafNode(leaf, key, value)
+	t.lowerFirstKeys(leaf, key)
 	// leaf 是否需要分裂
 	if !leaf.full() {
 		return
 	}
-	// 叶子节点分裂，并将 key 插入父节点
+	t.splitLeaf(leaf)
+}
+
+// splitLeaf 叶子节点分裂，并将新节点的第一个 key 插入父节点
+func (t *BPTree[K, V]) splitLeaf(leaf *leafNode[K, V]) {
+	t.changes.Begin()
+	t.links.Lock()
 	newNode := leaf.split()
+	t.links.Unlock()
+	leaf.recount()
+	newNode.recount()
 	t.insertIntoParent(leaf, newNode, newNode.kvs[0].key)
 }
 
-func (t *BPTree) insertIntoParent(old, new node, firstKey int) {
+// lowerFirstKeys 插入了比最左 key 更小的 key 时，沿最左路径调低内部节点的 k0，
+// 保证 k0 始终是第一个孩子的下界
+func (t *BPTree[K, V]) lowerFirstKeys(n node[K, V], key K) {
+	for p := n.parent(); p != nil; n, p = p, p.parent() {
+		if p.kcs[0].child != n || t.compare(key, p.kcs[0].key) >= 0 {
+			return
+		}
+		p.kcs[0].key = key
+	}
+}
+
+func (t *BPTree[K, V]) insertIntoParent(old, new node[K, V], firstKey K) {
 	if old.isRoot() {
 		// 新建 root，并替换 root
-		root := newInternalNode(t.maxInternal)
-		root.insert(0, old)
+		root := newInternalNode[K, V](t.maxInternal, t.compare)
+		root.insert(old.getFirstKey(), old)
 		root.insert(firstKey, new)
+		root.recount()
 		t.root = root
+		t.changes.Touch(old, new, root)
 		return
 	}
 	parent := old.parent()
 	new.setParent(parent)
 	parent.insert(firstKey, new)
+	t.changes.Touch(old, new, parent)
 	// 父节点无需分裂
 	if !parent.full() {
 		return
 	}
 	parentSibling, midKey := parent.split()
+	parent.recount()
+	parentSibling.recount()
 	// 父节点仍需分裂
 	t.insertIntoParent(parent, parentSibling, midKey)
 }
 
-func (t *BPTree) findLeaf(key int) *leafNode {
+func (t *BPTree[K, V]) findLeaf(key K) *leafNode[K, V] {
 	var (
 		tmp   = t.root
-		inter *internalNode
+		inter *internalNode[K, V]
 		ok    bool
 	)
 
 	// 如果是内部节点，则一直往下找
 	for tmp != nil {
-		inter, ok = tmp.(*internalNode)
+		inter, ok = tmp.(*internalNode[K, V])
 		if !ok {
 			// tmp 不是内部节点，那么是叶子节点，直接 break
 			break
@@ -237,16 +523,18 @@ func (t *BPTree) findLeaf(key int) *leafNode {
 		tmp = inter.lookup(key)
 	}
 
-	return tmp.(*leafNode)
+	return tmp.(*leafNode[K, V])
 }
 
-func (t *BPTree) startRoot(key int, value string) {
-	n := newLeafNode(t.maxLeaf)
+func (t *BPTree[K, V]) startRoot(key K, value V) {
+	n := newLeafNode[K, V](t.maxLeaf, t.compare)
 	n.insert(key, value)
+	n.recount()
 	t.root = n
+	t.size.Add(1)
 }
 
-func (t *BPTree) printGraph() {
+func (t *BPTree[K, V]) printGraph() {
 	fmt.Println("-----------------------------------")
 	cur := t.root
 	if cur == nil {
@@ -255,7 +543,7 @@ func (t *BPTree) printGraph() {
 	t.printNode(cur)
 }
 
-func (t *BPTree) Graph() string {
+func (t *BPTree[K, V]) Graph() string {
 	cur := t.root
 	out := bytes.NewBufferString("")
 	out.WriteString("digraph G {\n")
@@ -264,10 +552,10 @@ func (t *BPTree) Graph() string {
 	return out.String()
 }
 
-func (t *BPTree) graph(cur node, out *bytes.Buffer) {
+func (t *BPTree[K, V]) graph(cur node[K, V], out *bytes.Buffer) {
 	leafPrefix, internalPrefix := "LEAF_", "INT_"
 	switch n := cur.(type) {
-	case *leafNode:
+	case *leafNode[K, V]:
 		out.WriteString(leafPrefix)
 		out.WriteString(n.id())
 		out.WriteString("[shape=plain color=green ")
@@ -288,7 +576,7 @@ func (t *BPTree) graph(cur node, out *bytes.Buffer) {
 		out.WriteString("<TR>")
 
 		for i := 0; i < n.count; i++ {
-			out.WriteString(fmt.Sprintf("<TD>%d</TD>\n", n.kvs[i].key))
+			out.WriteString(fmt.Sprintf("<TD>%v</TD>\n", n.kvs[i].key))
 		}
 		out.WriteString("</TR>")
 		out.WriteString("</TABLE>>];\n")
@@ -317,7 +605,7 @@ func (t *BPTree) graph(cur node, out *bytes.Buffer) {
 			out.WriteString(n.id())
 			out.WriteString(";\n")
 		}
-	case *internalNode:
+	case *internalNode[K, V]:
 		out.WriteString(internalPrefix)
 		out.WriteString(n.id())
 		out.WriteString("[shape=plain color=pink ")
@@ -342,7 +630,7 @@ func (t *BPTree) graph(cur node, out *bytes.Buffer) {
 			out.WriteString(n.kcs[i].child.id())
 			out.WriteString("\">")
 			if i > 0 {
-				out.WriteString(strconv.Itoa(n.kcs[i].key))
+				out.WriteString(fmt.Sprint(n.kcs[i].key))
 			} else {
 				out.WriteString(" ")
 			}
@@ -379,7 +667,7 @@ func (t *BPTree) graph(cur node, out *bytes.Buffer) {
 	}
 }
 
-func (t *BPTree) printTree() {
+func (t *BPTree[K, V]) printTree() {
 	fmt.Println("-----------------------------------")
 	cur := t.root
 	if cur == nil {
@@ -388,24 +676,20 @@ func (t *BPTree) printTree() {
 	t.printNode(cur)
 }
 
-func (t *BPTree) printNode(cur node) {
+func (t *BPTree[K, V]) printNode(cur node[K, V]) {
 	switch n := cur.(type) {
-	case *leafNode:
+	case *leafNode[K, V]:
 		fmt.Printf("- leaf %s (size %d)\n", n.id(), n.count)
 		for i := 0; i < n.count; i++ {
-			fmt.Printf("<%d, %s>,", n.kvs[i].key, n.kvs[i].value)
+			fmt.Printf("<%v, %v>,", n.kvs[i].key, n.kvs[i].value)
 		}
 		fmt.Println()
 		fmt.Println()
 		break
-	case *internalNode:
+	case *internalNode[K, V]:
 		fmt.Printf("- internal %s (size %d)\n", n.id(), n.count)
 		for i := 0; i < n.count; i++ {
-			key := n.kcs[i].key
-			if i == 0 {
-				key = 0
-			}
-			fmt.Printf("<%d, %s>", key, n.kcs[i].child.id())
+			fmt.Printf("<%v, %s>", n.kcs[i].key, n.kcs[i].child.id())
 		}
 		fmt.Println()
 		fmt.Println()
@@ -1,128 +1,26 @@
 package bptree
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func verifyTree(b *BPTree, count int, t *testing.T) {
-	verifyRoot(b, t)
+func verifyTree(b *BPTree[int, string], count int, t *testing.T) {
+	assert.NoError(t, b.Verify())
 
-	for i := 0; i < b.root.(*internalNode).count; i++ {
-		verifyNode(b.root.(*internalNode).kcs[i].child, b.root.(*internalNode), t)
-	}
-
-	leftMost := findLeftMost(b.root)
-
-	verifyLeaf(leftMost, count, t)
-}
-
-// min child: 1
-// max child: MaxKC
-func verifyRoot(b *BPTree, t *testing.T) {
-	if b.Empty() {
-		t.Logf("empty tree")
-		return
-	}
-	if b.root.parent() != nil {
-		t.Errorf("root.parent: want = nil, got = %p", b.root.parent())
-	}
-
-	if b.root.getSize() < 1 {
-		t.Errorf("root.min.child: want >=1, got = %d", b.root.(*internalNode).count)
-	}
-
-	if b.root.getSize() > b.root.getMaxSize() {
-		t.Errorf("root.max.child: want <= %d, got = %d", b.root.(*internalNode).max, b.root.(*internalNode).count)
-	}
-}
-
-func verifyNode(n node, parent *internalNode, t *testing.T) {
-	switch nn := n.(type) {
-	case *internalNode:
-		if !nn.halfFull() {
-			t.Errorf("internal.min.child: want >= %d, got = %d", nn.max/2, nn.count)
-		}
-
-		if nn.getSize() > nn.getMaxSize() {
-			t.Errorf("internal.max.child: want <= %d, got = %d", nn.max, nn.count)
-		}
-
-		if nn.parent() != parent {
-			t.Errorf("internal.parent: want = %p, got = %p", parent, nn.parent())
-		}
-
-		for i := 0; i < nn.count; i++ {
-			if i > 0 {
-				if nn.kcs[i].key < nn.kcs[i-1].key {
-					t.Errorf("right = %d must bigger than left = %d", nn.kcs[i].key, nn.kcs[i-1].key)
-				}
-			}
-			verifyNode(nn.kcs[i].child, nn, t)
-		}
-
-	case *leafNode:
-		if nn.parent() != parent {
-			t.Errorf("leaf.parent: want = %p, got = %p", parent, nn.parent())
-		}
-
-		if !nn.halfFull() {
-			t.Errorf("leaf.min.child: want >= %d, got = %d", nn.max/2, nn.count)
-		}
-
-		if nn.getSize() > nn.getMaxSize() {
-			t.Errorf("leaf.max.child: want <= %d, got = %d", nn.max, nn.count)
-		}
-
-		for i := 0; i < nn.count; i++ {
-			if i > 0 {
-				if nn.kvs[i].key < nn.kvs[i-1].key {
-					t.Errorf("right = %d must bigger than left = %d", nn.kvs[i].key, nn.kvs[i-1].key)
-				}
-			}
-		}
-	}
-}
-
-func verifyLeaf(leftMost *leafNode, count int, t *testing.T) {
-	curr := leftMost
-	last := 0
 	c := 0
-
-	for curr != nil {
-		for i := 0; i < curr.count; i++ {
-			key := curr.kvs[i].key
-
-			if key <= last {
-				t.Errorf("leaf.sort.key: want > %d, got = %d", last, key)
-			}
-			last = key
-			c++
-		}
-		curr = curr.next
-	}
-
-	if c != count {
-		t.Errorf("leaf.count: want = %d, got = %d", count, c)
-	}
-}
-
-func findLeftMost(n node) *leafNode {
-	switch nn := n.(type) {
-	case *internalNode:
-		return findLeftMost(nn.kcs[0].child)
-	case *leafNode:
-		return nn
-	default:
-		panic("unknown node type")
+	for range b.All() {
+		c++
 	}
+	assert.Equal(t, count, c)
 }
 
 func TestBTree_Insert1(t1 *testing.T) {
 	keys := []int{1, 5, 12, 18, 21, 22, 23}
-	bt := NewBPTree(MaxInternal(3), MaxLeaf(3))
+	bt := NewBPTree[int, string](MaxInternal(3), MaxLeaf(3))
 
 	for _, key := range keys {
 		bt.Insert(key, fmt.Sprintf("%d", key))
@@ -132,7 +30,7 @@ func TestBTree_Insert1(t1 *testing.T) {
 
 func TestBTree_Insert2(t1 *testing.T) {
 	keys := []int{1, 5, 12, 18, 21, 22, 23}
-	bt := NewBPTree(MaxInternal(6), MaxLeaf(6))
+	bt := NewBPTree[int, string](MaxInternal(6), MaxLeaf(6))
 
 	for _, key := range keys {
 		bt.Insert(key, fmt.Sprintf("%d", key))
@@ -142,7 +40,7 @@ func TestBTree_Insert2(t1 *testing.T) {
 
 func TestBTree_Search1(t1 *testing.T) {
 	assert := assert.New(t1)
-	bt := NewBPTree(MaxInternal(10), MaxLeaf(10))
+	bt := NewBPTree[int, string](MaxInternal(10), MaxLeaf(10))
 	count := 1000
 
 	for i := 1; i <= count; i++ {
@@ -162,7 +60,7 @@ func TestBTree_Search1(t1 *testing.T) {
 
 func TestBTree_Search2(t1 *testing.T) {
 	assert := assert.New(t1)
-	bt := NewBPTree()
+	bt := NewBPTree[int, string]()
 	count := 100000
 
 	for i := 1; i <= count; i++ {
@@ -179,7 +77,7 @@ func TestBTree_Search2(t1 *testing.T) {
 
 func TestBTree_Search3(t1 *testing.T) {
 	assert := assert.New(t1)
-	bt := NewBPTree()
+	bt := NewBPTree[int, string]()
 	count := 100000
 
 	for i := 1; i <= count; i++ {
@@ -205,7 +103,7 @@ func TestBTree_Search3(t1 *testing.T) {
 
 func TestBTree_Delete2(t1 *testing.T) {
 	//assert := assert.New(t1)
-	bt := NewBPTree(MaxInternal(10), MaxLeaf(10))
+	bt := NewBPTree[int, string](MaxInternal(10), MaxLeaf(10))
 	count := 100
 
 	for i := 1; i <= count; i++ {
@@ -224,7 +122,7 @@ func TestBTree_Delete2(t1 *testing.T) {
 
 func TestBTree_Delete1(t *testing.T) {
 	keys := []int{1, 5, 12, 18, 21, 22, 23}
-	bt := NewBPTree(MaxInternal(6), MaxLeaf(6))
+	bt := NewBPTree[int, string](MaxInternal(6), MaxLeaf(6))
 
 	for _, key := range keys {
 		bt.Insert(key, fmt.Sprintf("%d", key))
@@ -238,3 +136,59 @@ func TestBTree_Delete1(t *testing.T) {
 		bt.printTree()
 	}
 }
+
+func TestBTree_NegativeKeys(t1 *testing.T) {
+	assert := assert.New(t1)
+	bt := NewBPTree[int, string](MaxInternal(4), MaxLeaf(4))
+	count := 500
+
+	for i := count; i >= -count; i-- {
+		bt.Insert(i, fmt.Sprintf("%d", i))
+	}
+
+	for i := -count; i <= count; i++ {
+		got, ok := bt.Search(i)
+		assert.True(ok, "expect=%d, but got=%s", i, got)
+		assert.Equal(fmt.Sprintf("%d", i), got)
+	}
+}
+
+func TestBTree_CompareFunc(t1 *testing.T) {
+	assert := assert.New(t1)
+	bt := NewBPTreeFunc[[]byte, int](bytes.Compare, MaxInternal(4), MaxLeaf(4))
+	count := 1000
+
+	for i := 0; i < count; i++ {
+		bt.Insert([]byte(fmt.Sprintf("key-%04d", i)), i)
+	}
+
+	for i := 0; i < count; i++ {
+		got, ok := bt.Search([]byte(fmt.Sprintf("key-%04d", i)))
+		assert.True(ok)
+		assert.Equal(i, got)
+	}
+
+	_, ok := bt.Search([]byte("key-"))
+	assert.False(ok)
+
+	leaf := bt.First()
+	assert.Equal([]byte("key-0000"), leaf.kvs[0].key)
+}
+
+// MaxInternal(3) 的内部节点可能只有一个孩子，下溢的孩子要通过祖父节点重组
+func TestBTree_DeleteSingleChild(t1 *testing.T) {
+	assert := assert.New(t1)
+	bt := NewBPTree[int, int](MaxLeaf(4), MaxInternal(3))
+	for _, key := range []int{33, 26, 35, 16, 7, 37, 8, 27, 19, 28, 32, 6} {
+		bt.Insert(key, key)
+	}
+	for _, key := range []int{31, 10, 14, 35, 28, 26} {
+		bt.Delete(key)
+		assert.NoError(bt.Verify(), "delete %d", key)
+	}
+	var keys []int
+	for key := range bt.All() {
+		keys = append(keys, key)
+	}
+	assert.Equal([]int{6, 7, 8, 16, 19, 27, 32, 33, 37}, keys)
+}
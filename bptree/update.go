@@ -0,0 +1,155 @@
+package bptree
+
+// Put stores key->value and returns the previous value of the key and whether
+// the key existed. It is not supported in multimap mode
+func (t *BPTree[K, V]) Put(key K, value V) (old V, loaded bool) {
+	t.mustNotMulti("Put")
+	t.upsert(key, func(v V, exists bool) (V, bool) {
+		old, loaded = v, exists
+		return value, true
+	}, false)
+	return old, loaded
+}
+
+// GetOrInsert returns the value of the key if it exists, otherwise it stores
+// value and returns it. loaded reports whether the key existed.
+// It is not supported in multimap mode
+func (t *BPTree[K, V]) GetOrInsert(key K, value V) (actual V, loaded bool) {
+	t.mustNotMulti("GetOrInsert")
+	t.upsert(key, func(v V, exists bool) (V, bool) {
+		actual, loaded = value, exists
+		if exists {
+			actual = v
+		}
+		return actual, true
+	}, false)
+	return actual, loaded
+}
+
+// CompareAndSwap replaces the value of the key with new if the key exists and
+// its value equals old, and reports whether it did. Values are compared with ==,
+// so V must be comparable at runtime, as for sync.Map.
+// It is not supported in multimap mode
+func (t *BPTree[K, V]) CompareAndSwap(key K, old, new V) (swapped bool) {
+	t.mustNotMulti("CompareAndSwap")
+	t.upsert(key, func(v V, exists bool) (V, bool) {
+		if exists && any(v) == any(old) {
+			swapped = true
+			return new, true
+		}
+		return v, exists
+	}, false)
+	return swapped
+}
+
+// Update calls fn with the value of the key and whether the key exists, fn
+// returns the new value and whether the key should be kept: the key is
+// inserted or replaced when it returns true and deleted when it returns false.
+// fn is called exactly once and in concurrent mode while the leaf is latched,
+// so it must not use the tree. It is not supported in multimap mode
+func (t *BPTree[K, V]) Update(key K, fn func(old V, exists bool) (V, bool)) {
+	t.mustNotMulti("Update")
+	t.upsert(key, fn, true)
+}
+
+func (t *BPTree[K, V]) mustNotMulti(op string) {
+	if t.equal != nil {
+		panic("bptree: " + op + " is not supported in multimap mode")
+	}
+}
+
+// upsert 一次下降完成读-改-写，mayDelete 表示 fn 可能删除已有的 key
+func (t *BPTree[K, V]) upsert(key K, fn func(old V, exists bool) (V, bool), mayDelete bool) {
+	if t.concurrent {
+		t.upsertConcurrent(key, fn, mayDelete)
+		return
+	}
+	if t.root == nil {
+		var zero V
+		if value, keep := fn(zero, false); keep {
+			t.startRoot(key, value)
+		}
+		return
+	}
+	t.upsertLeaf(t.findLeaf(key), key, fn, nil)
+}
+
+// upsertLeaf 在 key 所在的叶子节点上调用 fn，并按结果替换、插入或者删除
+func (t *BPTree[K, V]) upsertLeaf(leaf *leafNode[K, V], key K, fn func(old V, exists bool) (V, bool), s *latchSet[K, V]) {
+	idx, ok := leaf.find(key)
+	var old V
+	if ok {
+		old = leaf.kvs[idx].value
+	}
+	value, keep := fn(old, ok)
+	switch {
+	case ok && keep:
+		leaf.kvs[idx].value = value
+	case keep:
+		t.insertIntoLeafNode(leaf, key, value)
+		if s == nil {
+			// 并发模式下下降时已经调低了 k0，祖先的锁也可能已经释放
+			t.lowerFirstKeys(leaf, key)
+		}
+		if leaf.full() {
+			t.splitLeaf(leaf)
+		}
+	case ok:
+		t.deleteFromLeaf(leaf, key, nil, s)
+	}
+}
+
+// upsertConcurrent 与 insertConcurrent 相同，维护子树计数时先乐观地加锁，
+// key 已经存在时只替换 value，否则持有整条路径的锁重试。
+// fn 只在确定能完成修改时调用，保证只调用一次。fn 可能删除 key 时，
+// 孩子既不会分裂也不会下溢才释放祖先的锁
+func (t *BPTree[K, V]) upsertConcurrent(key K, fn func(old V, exists bool) (V, bool), mayDelete bool) {
+	t.batchLatch.RLock()
+	defer t.batchLatch.RUnlock()
+	if (!mayDelete || !t.counts) && t.upsertLatched(key, fn, mayDelete, false) {
+		return
+	}
+	t.upsertLatched(key, fn, mayDelete, true)
+}
+
+// upsertLatched 与 insertLatched 相同，维护子树计数时，
+// 祖先的锁已经释放并且 key 不存在，不调用 fn 并返回 false
+func (t *BPTree[K, V]) upsertLatched(key K, fn func(old V, exists bool) (V, bool), mayDelete, keep bool) bool {
+	s := &latchSet[K, V]{tree: t, root: true, keep: keep}
+	t.rootLatch.Lock()
+	defer s.releaseAll()
+	if t.root == nil {
+		var zero V
+		if value, keep := fn(zero, false); keep {
+			t.startRoot(key, value)
+		}
+		return true
+	}
+	n := t.root
+	for {
+		s.lock(n)
+		if n.getSize()+1 < n.getMaxSize() && (!mayDelete || t.deleteSafe(n)) {
+			s.releaseAncestors()
+		}
+		inter, ok := n.(*internalNode[K, V])
+		if !ok {
+			break
+		}
+		if t.compare(key, inter.kcs[0].key) < 0 {
+			inter.kcs[0].key = key
+		}
+		n = inter.lookup(key)
+	}
+	leaf := n.(*leafNode[K, V])
+	if !s.root && t.counts {
+		idx, ok := leaf.find(key)
+		if !ok {
+			return false
+		}
+		// 乐观路径上 fn 不会删除 key
+		leaf.kvs[idx].value, _ = fn(leaf.kvs[idx].value, true)
+		return true
+	}
+	t.upsertLeaf(leaf, key, fn, s)
+	return true
+}
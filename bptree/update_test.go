@@ -0,0 +1,133 @@
+package bptree
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBPTree_Put(t *testing.T) {
+	assert := assert.New(t)
+	bt := NewBPTree[int, string](MaxInternal(3), MaxLeaf(3))
+
+	old, loaded := bt.Put(1, "a")
+	assert.False(loaded)
+	assert.Equal("", old)
+	old, loaded = bt.Put(1, "b")
+	assert.True(loaded)
+	assert.Equal("a", old)
+
+	actual, loaded := bt.GetOrInsert(1, "c")
+	assert.True(loaded)
+	assert.Equal("b", actual)
+	actual, loaded = bt.GetOrInsert(2, "c")
+	assert.False(loaded)
+	assert.Equal("c", actual)
+
+	assert.False(bt.CompareAndSwap(1, "a", "x"))
+	assert.False(bt.CompareAndSwap(3, "", "x"))
+	assert.True(bt.CompareAndSwap(1, "b", "x"))
+	v, _ := bt.Search(1)
+	assert.Equal("x", v)
+	assert.Equal(2, bt.Len())
+
+	for i := 0; i < 50; i++ {
+		bt.Put(i, "v")
+	}
+	assert.NoError(bt.Verify())
+	assert.Equal(50, bt.Len())
+}
+
+func TestBPTree_Update(t *testing.T) {
+	assert := assert.New(t)
+	bt := NewBPTree[int, int](MaxInternal(3), MaxLeaf(3))
+
+	incr := func(old int, exists bool) (int, bool) { return old + 1, true }
+	for i := 0; i < 100; i++ {
+		bt.Update(i%10, incr)
+	}
+	assert.Equal(10, bt.Len())
+	for i := 0; i < 10; i++ {
+		v, _ := bt.Search(i)
+		assert.Equal(10, v)
+	}
+
+	// 返回 false 时删除 key，不存在的 key 保持不存在
+	drop := func(old int, exists bool) (int, bool) { return 0, false }
+	for i := 0; i < 20; i += 2 {
+		bt.Update(i, drop)
+	}
+	assert.Equal(5, bt.Len())
+	_, ok := bt.Search(2)
+	assert.False(ok)
+	assert.NoError(bt.Verify())
+
+	for i := 0; i < 10; i++ {
+		bt.Update(i, drop)
+	}
+	assert.True(bt.Empty())
+	bt.Update(7, incr)
+	v, _ := bt.Search(7)
+	assert.Equal(1, v)
+}
+
+func TestMultiBPTree_PutPanics(t *testing.T) {
+	bt := NewMultiBPTree[int, int]()
+	assert.Panics(t, func() { bt.Put(1, 1) })
+	assert.Panics(t, func() { bt.Update(1, func(int, bool) (int, bool) { return 0, true }) })
+}
+
+// 多个 goroutine 同时对同一组计数器加一，结果不丢失
+func TestBPTree_ConcurrentUpdate(t *testing.T) {
+	const (
+		workers  = 8
+		counters = 200
+		rounds   = 10
+	)
+	bt := NewBPTree[int, int](MaxInternal(4), MaxLeaf(4), Concurrent())
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				for i := 0; i < counters; i++ {
+					key := (i*7 + w) % counters
+					if w%2 == 0 {
+						bt.Update(key, func(old int, exists bool) (int, bool) { return old + 1, true })
+						continue
+					}
+					for {
+						old, loaded := bt.GetOrInsert(key, 1)
+						if !loaded || bt.CompareAndSwap(key, old, old+1) {
+							break
+						}
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	assert.NoError(t, bt.Verify())
+	assert.Equal(t, counters, bt.Len())
+	for i := 0; i < counters; i++ {
+		v, _ := bt.Search(i)
+		assert.Equal(t, workers*rounds, v, "counter %d", i)
+	}
+
+	// 每个 goroutine 删除自己的计数器
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < counters; i += workers {
+				bt.Update(i, func(old int, exists bool) (int, bool) { return 0, false })
+			}
+		}(w)
+	}
+	wg.Wait()
+	assert.NoError(t, bt.Verify())
+	assert.True(t, bt.Empty())
+}
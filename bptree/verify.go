@@ -0,0 +1,126 @@
+package bptree
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrCorrupt = errors.New("bptree: tree is corrupt")
+
+// Verify checks the structural invariants of the tree: keys are ordered
+// within and across nodes, non-root nodes are at least half full and never
+// full, parent pointers are consistent, all leaves are at the same depth,
+// the sibling chain links every leaf in key order and subtree counts add up.
+// It must not run concurrently with writers
+func (t *BPTree[K, V]) Verify() error {
+	if t.root == nil {
+		return nil
+	}
+	if t.root.parent() != nil {
+		return fmt.Errorf("%w: root has a parent", ErrCorrupt)
+	}
+	if t.root.getSize() < 1 || (!t.root.isLeaf() && t.root.getSize() < 2) {
+		return fmt.Errorf("%w: root %s has %d entries", ErrCorrupt, t.root.id(), t.root.getSize())
+	}
+	v := &verifier[K, V]{tree: t, depth: -1}
+	if err := v.verify(t.root, nil, nil, 0); err != nil {
+		return err
+	}
+	total := 0
+	for _, leaf := range v.leaves {
+		total += leaf.getTotal()
+	}
+	if total != t.Len() {
+		return fmt.Errorf("%w: leaves count %d pairs, Len is %d", ErrCorrupt, total, t.Len())
+	}
+	return v.verifyLinks()
+}
+
+type verifier[K, V any] struct {
+	tree   *BPTree[K, V]
+	depth  int               // 叶子节点的深度
+	leaves []*leafNode[K, V] // 中序遍历得到的叶子节点
+}
+
+// verify 检查以 n 为根的子树，子树中的 key 都在 [lo, hi) 之内，nil 表示没有边界
+func (v *verifier[K, V]) verify(n node[K, V], lo, hi *K, depth int) error {
+	if n.full() {
+		return fmt.Errorf("%w: node %s is full", ErrCorrupt, n.id())
+	}
+	if !n.isRoot() && n.getSize() < n.getMinSize() {
+		return fmt.Errorf("%w: node %s has %d entries, want >= %d",
+			ErrCorrupt, n.id(), n.getSize(), n.getMinSize())
+	}
+	total := 0
+	switch nn := n.(type) {
+	case *internalNode[K, V]:
+		for i := 0; i < nn.count; i++ {
+			total += nn.kcs[i].child.getTotal()
+			if err := v.checkKey(nn.kcs[i].key, lo, hi, nn); err != nil {
+				return err
+			}
+			if i > 0 && v.tree.compare(nn.kcs[i-1].key, nn.kcs[i].key) >= 0 {
+				return fmt.Errorf("%w: keys of node %s are out of order", ErrCorrupt, nn.id())
+			}
+		}
+		for i := 0; i < nn.count; i++ {
+			child := nn.kcs[i].child
+			if child.parent() != nn {
+				return fmt.Errorf("%w: node %s has a wrong parent", ErrCorrupt, child.id())
+			}
+			// k0 只是下界，孩子中的 key 不小于它
+			clo, chi := &nn.kcs[i].key, hi
+			if i+1 < nn.count {
+				chi = &nn.kcs[i+1].key
+			}
+			if err := v.verify(child, clo, chi, depth+1); err != nil {
+				return err
+			}
+		}
+	case *leafNode[K, V]:
+		for i := 0; i < nn.count; i++ {
+			total += nn.kvs[i].weight()
+			if err := v.checkKey(nn.kvs[i].key, lo, hi, nn); err != nil {
+				return err
+			}
+			if i > 0 && v.tree.compare(nn.kvs[i-1].key, nn.kvs[i].key) >= 0 {
+				return fmt.Errorf("%w: keys of node %s are out of order", ErrCorrupt, nn.id())
+			}
+		}
+		if v.depth == -1 {
+			v.depth = depth
+		} else if v.depth != depth {
+			return fmt.Errorf("%w: leaves at depth %d and %d", ErrCorrupt, v.depth, depth)
+		}
+		v.leaves = append(v.leaves, nn)
+	}
+	// 并发模式下没有维护计数时，只有叶子节点的计数是准确的
+	if (n.isLeaf() || v.tree.counted()) && n.getTotal() != total {
+		return fmt.Errorf("%w: node %s counts %d pairs, want %d", ErrCorrupt, n.id(), n.getTotal(), total)
+	}
+	return nil
+}
+
+func (v *verifier[K, V]) checkKey(key K, lo, hi *K, n node[K, V]) error {
+	if (lo != nil && v.tree.compare(key, *lo) < 0) || (hi != nil && v.tree.compare(key, *hi) >= 0) {
+		return fmt.Errorf("%w: key %v of node %s is out of its parent range", ErrCorrupt, key, n.id())
+	}
+	return nil
+}
+
+// verifyLinks 叶子链表正反两个方向都要与中序遍历的顺序一致
+func (v *verifier[K, V]) verifyLinks() error {
+	for i, leaf := range v.leaves {
+		var prev, next *leafNode[K, V]
+		if i > 0 {
+			prev = v.leaves[i-1]
+		}
+		if i+1 < len(v.leaves) {
+			next = v.leaves[i+1]
+		}
+		if leaf.prev != prev || leaf.next != next {
+			return fmt.Errorf("%w: sibling links of leaf %s are broken", ErrCorrupt, leaf.id())
+		}
+	}
+	return nil
+}
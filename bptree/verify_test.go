@@ -0,0 +1,48 @@
+package bptree
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBPTree_VerifyCorrupt(t *testing.T) {
+	build := func() *BPTree[int, int] {
+		bt := NewBPTree[int, int](MaxInternal(4), MaxLeaf(4))
+		for i := 0; i < 50; i++ {
+			bt.Insert(i, i)
+		}
+		assert.NoError(t, bt.Verify())
+		return bt
+	}
+
+	corruptions := map[string]func(bt *BPTree[int, int]){
+		"leaf order": func(bt *BPTree[int, int]) {
+			leaf := bt.First()
+			leaf.kvs[0], leaf.kvs[1] = leaf.kvs[1], leaf.kvs[0]
+		},
+		"parent range": func(bt *BPTree[int, int]) {
+			bt.First().kvs[0].key = 100
+		},
+		"parent pointer": func(bt *BPTree[int, int]) {
+			bt.First().next.p = nil
+		},
+		"next link": func(bt *BPTree[int, int]) {
+			bt.First().next = bt.First().next.next
+		},
+		"prev link": func(bt *BPTree[int, int]) {
+			bt.First().next.prev = nil
+		},
+		"underflow": func(bt *BPTree[int, int]) {
+			bt.First().count = 1
+		},
+		"total": func(bt *BPTree[int, int]) {
+			bt.First().addTotal(1)
+		},
+	}
+	for name, corrupt := range corruptions {
+		bt := build()
+		corrupt(bt)
+		assert.ErrorIs(t, bt.Verify(), ErrCorrupt, name)
+	}
+}
@@ -1,5 +1,7 @@
 package btree
 
+import "github.com/pedrogao/btrees/common"
+
 var DefaultMin = 128
 
 type Item struct {
@@ -8,17 +10,43 @@ type Item struct {
 }
 
 type Node struct {
-	bucket *BTree // 归属树
+	bucket *BTree       // 归属树
+	cow    *copyOnWrite // 与归属树的 cow 相同时才能原地修改
 	// todo 新增 parent, 分类、合并、左旋、右旋都通过 parent 来
 	// todo 如果是递归合并、分裂之类的，依赖递归来做
 	items    []*Item // 节点kv对
 	children []*Node // 孩子节点
+	count    int     // 子树中 item 的数量
 }
 
 type BTree struct {
-	root *Node
-	min  int
-	max  int
+	root    *Node
+	min     int
+	max     int
+	cow     *copyOnWrite
+	changes common.ChangeTrace[*Node] // 最近一次分裂或者合并修改的节点，导出时高亮
+}
+
+var (
+	_ common.OrderedMap[string, interface{}]      = (*BTree)(nil)
+	_ common.OrderStatistics[string, interface{}] = (*BTree)(nil)
+	_ common.Navigable[string, interface{}]       = (*BTree)(nil)
+)
+
+// copyOnWrite 标记节点可以被哪棵树原地修改。Clone 之后两棵树各自换上新的标记，
+// 原有节点对两棵树都是只读的，修改前先复制，即路径复制
+type copyOnWrite struct {
+	_ byte // 非零大小，保证每次分配的地址都不同
+}
+
+// Key returns the key of the item
+func (i *Item) Key() string {
+	return i.key
+}
+
+// Value returns the value of the item
+func (i *Item) Value() interface{} {
+	return i.value
 }
 
 func newItem(key string, value interface{}) *Item {
@@ -31,8 +59,11 @@ func newItem(key string, value interface{}) *Item {
 func newTreeWithRoot(root *Node, min int) *BTree {
 	bucket := &BTree{
 		root: root,
+		cow:  new(copyOnWrite),
 	}
 	bucket.root.bucket = bucket
+	bucket.root.cow = bucket.cow
+	bucket.root.recountAll()
 	bucket.min = min
 	bucket.max = min * 2
 	return bucket
@@ -42,18 +73,41 @@ func NewTree(min int) *BTree {
 	return newTreeWithRoot(NewEmptyNode(), min)
 }
 
+// Clone returns a snapshot of the tree in O(1). The two trees share all nodes
+// and copy a node the first time they modify it, so writes to one tree are
+// never visible in the other. Each tree may be used by a different goroutine
+func (b *BTree) Clone() *BTree {
+	b.cow = new(copyOnWrite)
+	return &BTree{
+		root: b.root,
+		min:  b.min,
+		max:  b.max,
+		cow:  new(copyOnWrite),
+	}
+}
+
 // Put adds a key to the tree. It finds the correct node and the insertion index and adds the item. When performing the
 // search, the ancestors are returned as well. This way we can iterate over them to check which nodes were modified and
 // rebalance by splitting them accordingly. If the root has too many items, then a new root of a new layer is
 // created and the created nodes from the split are added as children.
 func (b *BTree) Put(key string, value interface{}) {
+	b.changes.Begin()
 	// Find the path to the node where the insertion should happen
 	i := newItem(key, value)
 	insertionIndex, nodeToInsertIn, ancestorsIndexes := b.findKey(i.key, false)
+	ancestors := b.getMutableNodes(ancestorsIndexes)
+	nodeToInsertIn = ancestors[len(ancestors)-1]
+	// key 已存在，替换 item 而不是修改它，item 可能被快照共享
+	if insertionIndex < len(nodeToInsertIn.items) && nodeToInsertIn.items[insertionIndex].key == key {
+		nodeToInsertIn.items[insertionIndex] = i
+		return
+	}
 	// Add item to the leaf node
 	nodeToInsertIn.addItem(i, insertionIndex)
+	for _, node := range ancestors {
+		node.count++
+	}
 
-	ancestors := b.getNodes(ancestorsIndexes)
 	// Rebalance the nodes all the way up. Start From one node before the last and go all the way up.
 	// Exclude root.
 	for i := len(ancestors) - 2; i >= 0; i-- {
@@ -69,6 +123,7 @@ func (b *BTree) Put(key string, value interface{}) {
 	if b.root.half() {
 		newRoot := NewNode(b, []*Item{}, []*Node{b.root})
 		newRoot.split(b.root, 0)
+		newRoot.recount()
 		b.root = newRoot
 	}
 }
@@ -79,8 +134,14 @@ func (b *BTree) Put(key string, value interface{}) {
 // siblings don't have enough items, then merging occurs. If the root is without items after a split, then the root is
 // removed and the tree is one level shorter.
 func (b *BTree) Remove(key string) {
+	b.changes.Begin()
 	// Find the path to the node where the deletion should happen
 	removeItemIndex, nodeToRemoveFrom, ancestorsIndexes := b.findKey(key, true)
+	if nodeToRemoveFrom == nil {
+		return
+	}
+	nodes := b.getMutableNodes(ancestorsIndexes)
+	nodeToRemoveFrom = nodes[len(nodes)-1]
 
 	if nodeToRemoveFrom.isLeaf() {
 		nodeToRemoveFrom.removeItemFromLeaf(removeItemIndex)
@@ -90,6 +151,10 @@ func (b *BTree) Remove(key string) {
 	}
 
 	ancestors := b.getNodes(ancestorsIndexes)
+	// 从根节点到被删除 item 所在叶子节点的路径上，子树都少了一个 item
+	for _, node := range ancestors {
+		node.count--
+	}
 	// Re-balance the nodes all the way up. Start From one node before the last and go all the way up. Exclude root.
 	for i := len(ancestors) - 2; i >= 0; i-- {
 		pnode := ancestors[i]
@@ -100,7 +165,7 @@ func (b *BTree) Remove(key string) {
 	}
 	// If the root has no items after re-balancing
 	if len(b.root.items) == 0 && len(b.root.children) > 0 {
-		b.root = ancestors[1]
+		b.root = b.root.children[0]
 	}
 }
 
@@ -115,7 +180,7 @@ func (b *BTree) Find(key string) *Item {
 
 // findKey finds the node with the key, it's index in the parent's items and a list of its ancestors (not including the
 // node itself). The parent's items and key are used later for operations such as searching, adding and removing and list
-//of ancestors is used for re-balancing. It's also known as breadcrumbs.
+// of ancestors is used for re-balancing. It's also known as breadcrumbs.
 // When the item isn't found, if exact is true, then a falsey answer is returned. If exact is false, then the index
 // where the item should have been is returned (Used for insertion)
 func (b *BTree) findKey(key string, exact bool) (int, *Node, []int) {
@@ -143,10 +208,12 @@ func (b *BTree) findKey(key string, exact bool) (int, *Node, []int) {
 }
 
 // getNodes returns a list of nodes based on their indexes (the breadcrumbs) from the root
-//           p
-//       /       \
-//     a          b
-//  /     \     /   \
+//
+//	         p
+//	     /       \
+//	   a          b
+//	/     \     /   \
+//
 // c       d   e     f
 // For [0,1,0] -> p,b,e
 func (b *BTree) getNodes(indexes []int) []*Node {
@@ -159,6 +226,90 @@ func (b *BTree) getNodes(indexes []int) []*Node {
 	return nodes
 }
 
+// getMutableNodes is like getNodes, but nodes shared with a snapshot are copied
+// first, so that every returned node can be modified in place
+func (b *BTree) getMutableNodes(indexes []int) []*Node {
+	b.root = b.root.mutableFor(b)
+	nodes := []*Node{b.root}
+	child := b.root
+	for i := 1; i < len(indexes); i++ {
+		child = child.mutableChild(indexes[i])
+		nodes = append(nodes, child)
+	}
+	return nodes
+}
+
+// Get returns the value of key and whether the key exists
+func (b *BTree) Get(key string) (interface{}, bool) {
+	item := b.Find(key)
+	if item == nil {
+		return nil, false
+	}
+	return item.value, true
+}
+
+// Set stores key->value, it is the same as Put
+func (b *BTree) Set(key string, value interface{}) {
+	b.Put(key, value)
+}
+
+// Delete removes key and reports whether it existed
+func (b *BTree) Delete(key string) bool {
+	count := b.root.count
+	b.Remove(key)
+	return b.root.count < count
+}
+
+// Len returns the number of items
+func (b *BTree) Len() int {
+	return b.root.count
+}
+
+// Min returns the smallest key and its value, false if the tree is empty
+func (b *BTree) Min() (string, interface{}, bool) {
+	n := b.root
+	for !n.isLeaf() {
+		n = n.children[0]
+	}
+	if len(n.items) == 0 {
+		return "", nil, false
+	}
+	return n.items[0].key, n.items[0].value, true
+}
+
+// Max returns the largest key and its value, false if the tree is empty
+func (b *BTree) Max() (string, interface{}, bool) {
+	n := b.root
+	for !n.isLeaf() {
+		n = n.children[len(n.children)-1]
+	}
+	if len(n.items) == 0 {
+		return "", nil, false
+	}
+	item := n.items[len(n.items)-1]
+	return item.key, item.value, true
+}
+
+// Ascend calls fn for every key/value pair in key order until fn returns false
+func (b *BTree) Ascend(fn func(key string, value interface{}) bool) {
+	b.root.ascend(fn)
+}
+
+func (n *Node) ascend(fn func(key string, value interface{}) bool) bool {
+	for i, item := range n.items {
+		if !n.isLeaf() && !n.children[i].ascend(fn) {
+			return false
+		}
+		if !fn(item.key, item.value) {
+			return false
+		}
+	}
+	if !n.isLeaf() {
+		return n.children[len(n.children)-1].ascend(fn)
+	}
+	return true
+}
+
 func NewEmptyNode() *Node {
 	return &Node{
 		items:    []*Item{},
@@ -168,10 +319,35 @@ func NewEmptyNode() *Node {
 
 func NewNode(bucket *BTree, value []*Item, childNodes []*Node) *Node {
 	return &Node{
-		bucket,
-		value,
-		childNodes,
+		bucket:   bucket,
+		cow:      bucket.cow,
+		items:    value,
+		children: childNodes,
+	}
+}
+
+// mutableFor returns n if it belongs to b, otherwise a copy of n that does
+func (n *Node) mutableFor(b *BTree) *Node {
+	if n.cow == b.cow {
+		return n
 	}
+	c := &Node{
+		bucket:   b,
+		cow:      b.cow,
+		items:    make([]*Item, len(n.items), cap(n.items)),
+		children: make([]*Node, len(n.children), cap(n.children)),
+		count:    n.count,
+	}
+	copy(c.items, n.items)
+	copy(c.children, n.children)
+	return c
+}
+
+// mutableChild makes the i-th child writable, n itself must be writable
+func (n *Node) mutableChild(i int) *Node {
+	child := n.children[i].mutableFor(n.bucket)
+	n.children[i] = child
+	return child
 }
 
 func isLast(index int, parentNode *Node) bool {
@@ -240,11 +416,12 @@ func (n *Node) addChild(node *Node, insertionIndex int) {
 // didn't exceed the maximum number of elements. If it did, then it has to be split and rebalanced. The transformation
 // is depicted in the graph below. If it's not a leaf node, then the children has to be moved as well as shown.
 // This may leave the parent unbalanced by having too many items so re-balancing has to be checked for all the ancestors.
-// 	           n                                        n
-//                 3                                       3,6
-//	      /        \           ------>       /          |          \
-//	   a           modifiedNode            a       modifiedNode     c
-//   1,2                 4,5,6,7,8            1,2          4,5         7,8
+//
+//		           n                                        n
+//	                3                                       3,6
+//		      /        \           ------>       /          |          \
+//		   a           modifiedNode            a       modifiedNode     c
+//	  1,2                 4,5,6,7,8            1,2          4,5         7,8
 func (n *Node) split(modifiedNode *Node, insertionIndex int) {
 	i := 0
 	nodeSize := n.bucket.min
@@ -252,11 +429,13 @@ func (n *Node) split(modifiedNode *Node, insertionIndex int) {
 	for modifiedNode.half() {
 		middleItem := modifiedNode.items[nodeSize]
 		var newNode *Node
+		// 新节点使用独立的底层数组，否则 modifiedNode 之后的 append 会覆盖新节点的数据
+		items := append([]*Item(nil), modifiedNode.items[nodeSize+1:]...)
 		if modifiedNode.isLeaf() {
-			newNode = NewNode(n.bucket, modifiedNode.items[nodeSize+1:], []*Node{})
+			newNode = NewNode(n.bucket, items, []*Node{})
 			modifiedNode.items = modifiedNode.items[:nodeSize]
 		} else {
-			newNode = NewNode(n.bucket, modifiedNode.items[nodeSize+1:], modifiedNode.children[i+1:])
+			newNode = NewNode(n.bucket, items, append([]*Node(nil), modifiedNode.children[nodeSize+1:]...))
 			modifiedNode.items = modifiedNode.items[:nodeSize]
 			modifiedNode.children = modifiedNode.children[:nodeSize+1]
 		}
@@ -268,6 +447,9 @@ func (n *Node) split(modifiedNode *Node, insertionIndex int) {
 			n.children[insertionIndex+1] = newNode
 		}
 
+		modifiedNode.recount()
+		newNode.recount()
+		n.bucket.changes.Touch(n, modifiedNode, newNode)
 		insertionIndex += 1
 		i += 1
 		modifiedNode = newNode
@@ -287,6 +469,7 @@ func (n *Node) rebalanceRemove(unbalancedNodeIndex int) {
 	if unbalancedNodeIndex != 0 {
 		leftNode = pNode.children[unbalancedNodeIndex-1]
 		if len(leftNode.items) > n.bucket.min {
+			leftNode = pNode.mutableChild(unbalancedNodeIndex - 1)
 			rotateRight(leftNode, pNode, unbalancedNode, unbalancedNodeIndex)
 			return
 		}
@@ -297,6 +480,7 @@ func (n *Node) rebalanceRemove(unbalancedNodeIndex int) {
 	if unbalancedNodeIndex != len(pNode.children)-1 {
 		rightNode = pNode.children[unbalancedNodeIndex+1]
 		if len(rightNode.items) > n.bucket.min {
+			rightNode = pNode.mutableChild(unbalancedNodeIndex + 1)
 			rotateLeft(unbalancedNode, pNode, rightNode, unbalancedNodeIndex)
 			return
 		}
@@ -321,10 +505,10 @@ func (n *Node) removeItemFromInternal(index int) []int {
 	affectedNodes := make([]int, 0)
 	affectedNodes = append(affectedNodes, index)
 
-	aNode := n.children[index]
+	aNode := n.mutableChild(index)
 	for !aNode.isLeaf() {
-		traversingIndex := len(n.children) - 1
-		aNode = n.children[traversingIndex]
+		traversingIndex := len(aNode.children) - 1
+		aNode = aNode.mutableChild(traversingIndex)
 		affectedNodes = append(affectedNodes, traversingIndex)
 	}
 
@@ -361,6 +545,8 @@ func rotateRight(aNode, pNode, bNode *Node, bNodeIndex int) {
 		aNode.children = aNode.children[:len(aNode.children)-1]
 		bNode.children = append([]*Node{childNodeToShift}, bNode.children...)
 	}
+	aNode.recount()
+	bNode.recount()
 }
 
 func rotateLeft(aNode, pNode, bNode *Node, bNodeIndex int) {
@@ -391,6 +577,8 @@ func rotateLeft(aNode, pNode, bNode *Node, bNodeIndex int) {
 		bNode.children = bNode.children[1:]
 		aNode.children = append(aNode.children, childNodeToShift)
 	}
+	aNode.recount()
+	bNode.recount()
 }
 
 func merge(pNode *Node, unbalancedNodeIndex int) {
@@ -415,6 +603,8 @@ func merge(pNode *Node, unbalancedNodeIndex int) {
 		if !bNode.isLeaf() {
 			aNode.children = append(aNode.children, bNode.children...)
 		}
+		aNode.recount()
+		pNode.bucket.changes.Touch(pNode, aNode)
 	} else {
 		// 	               p                                     p
 		//                    3,5                                    5
@@ -422,7 +612,7 @@ func merge(pNode *Node, unbalancedNodeIndex int) {
 		//           a   b(unbalanced)   c                    a            c
 		//          1,2         4        6,7                 1,2,3,4         6,7
 		bNode := unbalancedNode
-		aNode := pNode.children[unbalancedNodeIndex-1]
+		aNode := pNode.mutableChild(unbalancedNodeIndex - 1)
 
 		// Take the item from the parent, remove it and add it to the unbalanced node
 		pNodeItem := pNode.items[unbalancedNodeIndex-1]
@@ -432,7 +622,25 @@ func merge(pNode *Node, unbalancedNodeIndex int) {
 		aNode.items = append(aNode.items, bNode.items...)
 		pNode.children = append(pNode.children[:unbalancedNodeIndex], pNode.children[unbalancedNodeIndex+1:]...)
 		if !aNode.isLeaf() {
-			bNode.children = append(aNode.children, bNode.children...)
+			aNode.children = append(aNode.children, bNode.children...)
 		}
+		aNode.recount()
+		pNode.bucket.changes.Touch(pNode, aNode)
+	}
+}
+
+// recount 根据 items 和孩子的计数重新计算 count
+func (n *Node) recount() {
+	n.count = len(n.items)
+	for _, child := range n.children {
+		n.count += child.count
+	}
+}
+
+// recountAll 重新计算整棵子树的计数，用于直接构造出来的树
+func (n *Node) recountAll() {
+	for _, child := range n.children {
+		child.recountAll()
 	}
+	n.recount()
 }
@@ -0,0 +1,122 @@
+package btree
+
+import (
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// checkTree 树结构正确，树中的 item 与 m 一致，且按 key 有序
+func checkTree(t *testing.T, b *BTree, m map[string]string) {
+	assert.NoError(t, b.Verify())
+
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var got []string
+	b.Ascend(func(key string, value interface{}) bool {
+		assert.Equal(t, m[key], value, "key %s", key)
+		got = append(got, key)
+		return true
+	})
+	assert.Equal(t, len(m), b.Len())
+	if len(keys) == 0 {
+		assert.Empty(t, got)
+		return
+	}
+	assert.Equal(t, keys, got)
+	for _, k := range keys {
+		item := b.Find(k)
+		if assert.NotNil(t, item, "key %s", k) {
+			assert.Equal(t, m[k], item.Value())
+		}
+	}
+}
+
+func randomOps(r *rand.Rand, b *BTree, m map[string]string, ops int, tag string) {
+	for i := 0; i < ops; i++ {
+		k := strconv.Itoa(r.Intn(500))
+		if r.Intn(3) == 0 {
+			b.Remove(k)
+			delete(m, k)
+		} else {
+			v := tag + strconv.Itoa(i)
+			b.Put(k, v)
+			m[k] = v
+		}
+	}
+}
+
+func copyMap(m map[string]string) map[string]string {
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func Test_BucketRandom(t *testing.T) {
+	for _, min := range []int{1, 2, 3, 5} {
+		r := rand.New(rand.NewSource(int64(min)))
+		b := NewTree(min)
+		m := map[string]string{}
+		randomOps(r, b, m, 5000, "v")
+		checkTree(t, b, m)
+		for i := 0; i < 500; i++ {
+			if _, ok := m[strconv.Itoa(i)]; !ok {
+				assert.Nil(t, b.Find(strconv.Itoa(i)))
+			}
+		}
+	}
+}
+
+func Test_BucketClone(t *testing.T) {
+	for _, min := range []int{1, 2, 3, 5} {
+		r := rand.New(rand.NewSource(int64(min)))
+		b := NewTree(min)
+		m := map[string]string{}
+		randomOps(r, b, m, 2000, "v")
+
+		// 快照不受原树之后修改的影响，反之亦然
+		snapshot := b.Clone()
+		sm := copyMap(m)
+		snapshot2 := snapshot.Clone()
+		sm2 := copyMap(m)
+		randomOps(r, b, m, 2000, "b")
+		randomOps(r, snapshot, sm, 2000, "s")
+
+		checkTree(t, b, m)
+		checkTree(t, snapshot, sm)
+		checkTree(t, snapshot2, sm2)
+	}
+}
+
+// 读者遍历快照的同时写者继续修改原树，用 -race 检查没有共享写
+func Test_BucketCloneConcurrent(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	b := NewTree(2)
+	m := map[string]string{}
+	randomOps(r, b, m, 2000, "v")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		snapshot, sm := b.Clone(), copyMap(m)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				checkTree(t, snapshot, sm)
+			}
+		}()
+		randomOps(r, b, m, 500, "w")
+	}
+	wg.Wait()
+	checkTree(t, b, m)
+}
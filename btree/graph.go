@@ -0,0 +1,42 @@
+package btree
+
+import "github.com/pedrogao/btrees/common"
+
+// Dot renders the tree in the Graphviz dot language. The nodes have no parent
+// pointers, common.ShowParents is ignored
+func (b *BTree) Dot(options ...common.GraphOption[string]) string {
+	return b.graph(options).Dot()
+}
+
+// Mermaid renders the tree as a Mermaid flowchart, with the same options as Dot
+func (b *BTree) Mermaid(options ...common.GraphOption[string]) string {
+	return b.graph(options).Mermaid()
+}
+
+func (b *BTree) graph(options []common.GraphOption[string]) *common.Graph {
+	opts := common.NewGraphOptions(options...)
+	path := map[*Node]bool{}
+	if opts.Path {
+		// 查找路径上的节点，key 不存在时一直到叶子节点
+		_, _, indexes := b.findKey(opts.Key, false)
+		for _, n := range b.getNodes(indexes) {
+			path[n] = true
+		}
+	}
+	var changed map[*Node]bool
+	if opts.Changes {
+		changed = b.changes.Nodes()
+	}
+	return common.BuildGraph(b.root, common.GraphSource[*Node]{
+		Children: func(n *Node) []*Node {
+			return n.children
+		},
+		Keys: func(n *Node) []string {
+			keys := make([]string, len(n.items))
+			for i, item := range n.items {
+				keys[i] = item.key
+			}
+			return keys
+		},
+	}, path, changed, opts.ParentPointers)
+}
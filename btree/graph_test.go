@@ -0,0 +1,68 @@
+package btree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBTree_Graph(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tree := NewTree(minItems)
+	for i := 0; i < 30; i++ {
+		tree.Put(fmt.Sprintf("%02d", i), i)
+	}
+	require.NoError(tree.Verify())
+
+	// 查找路径从根节点到叶子节点，每一层一个节点
+	g := tree.graph([]common.GraphOption[string]{common.HighlightPath("07")})
+	var path []common.GraphNode
+	for _, n := range g.Nodes {
+		if n.Path {
+			path = append(path, n)
+		}
+		assert.False(n.Changed)
+		assert.Zero(n.Parent)
+	}
+	require.NotEmpty(path)
+	assert.Equal(1, path[0].ID)
+	for i := 1; i < len(path); i++ {
+		assert.Contains(path[i-1].Children, path[i].ID)
+	}
+	assert.Contains(path[len(path)-1].Keys, "07")
+
+	// 根节点分裂时新的根节点和分裂出的两个节点都被修改
+	tree = NewTree(minItems)
+	for i := 0; len(tree.root.children) == 0; i++ {
+		tree.Put(fmt.Sprintf("%02d", i), i)
+	}
+	g = tree.graph([]common.GraphOption[string]{common.HighlightChanges[string]()})
+	require.Len(g.Nodes, 3)
+	for _, n := range g.Nodes {
+		assert.True(n.Changed)
+	}
+
+	// 不分裂的插入保留上一次分裂的节点
+	tree.Put("zz", 0)
+	assert.Contains(tree.Dot(common.HighlightChanges[string]()), "fillcolor")
+
+	// 删除到根节点合并
+	for len(tree.root.children) > 0 {
+		key, _, _ := tree.Min()
+		tree.Remove(key)
+	}
+	g = tree.graph([]common.GraphOption[string]{common.HighlightChanges[string]()})
+	require.Len(g.Nodes, 1)
+	assert.True(g.Nodes[0].Changed)
+
+	mermaid := tree.Mermaid(common.HighlightPath("zz"), common.HighlightChanges[string]())
+	assert.True(strings.HasPrefix(mermaid, "graph TD\n"))
+	assert.Contains(mermaid, "class n1 path")
+	assert.Contains(mermaid, "class n1 changed")
+}
@@ -0,0 +1,30 @@
+package btree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/pedrogao/btrees/internal/modeltest"
+)
+
+func modelTarget(min int) modeltest.Target[string] {
+	return modeltest.Target[string]{
+		New: func() common.OrderedMap[string, interface{}] {
+			return NewTree(min)
+		},
+		Key: func(i int) string {
+			return fmt.Sprintf("%03d", i)
+		},
+	}
+}
+
+func TestBTree_Model(t *testing.T) {
+	for _, min := range []int{1, 2, 5} {
+		modeltest.RandomTest(t, modelTarget(min), 5, 2000)
+	}
+}
+
+func FuzzBTree(f *testing.F) {
+	modeltest.Fuzz(f, modelTarget(2))
+}
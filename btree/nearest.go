@@ -0,0 +1,92 @@
+package btree
+
+// Floor returns the largest key that is less than or equal to key
+func (b *BTree) Floor(key string) (string, interface{}, bool) {
+	return b.seekBackward(key, false)
+}
+
+// Prev returns the largest key that is less than key
+func (b *BTree) Prev(key string) (string, interface{}, bool) {
+	return b.seekBackward(key, true)
+}
+
+// Ceil returns the smallest key that is greater than or equal to key
+func (b *BTree) Ceil(key string) (string, interface{}, bool) {
+	return b.seekForward(key, false)
+}
+
+// Next returns the smallest key that is greater than key
+func (b *BTree) Next(key string) (string, interface{}, bool) {
+	return b.seekForward(key, true)
+}
+
+// seekForward 从根节点下降，记录路径上最后一个大于 key 的 item 作为候选
+func (b *BTree) seekForward(key string, strict bool) (string, interface{}, bool) {
+	var candidate *Item
+	n := b.root
+	for {
+		found, i := n.findKey(key)
+		if found {
+			if !strict {
+				return n.items[i].key, n.items[i].value, true
+			}
+			// 等于 key 的 item 右边的子树中都是大于 key 的 item
+			i++
+		}
+		if i < len(n.items) {
+			candidate = n.items[i]
+		}
+		if n.isLeaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	if candidate == nil {
+		return "", nil, false
+	}
+	return candidate.key, candidate.value, true
+}
+
+// seekBackward 与 seekForward 对称，记录路径上最后一个小于 key 的 item
+func (b *BTree) seekBackward(key string, strict bool) (string, interface{}, bool) {
+	var candidate *Item
+	n := b.root
+	for {
+		found, i := n.findKey(key)
+		if found && !strict {
+			return n.items[i].key, n.items[i].value, true
+		}
+		// 没找到时 i 是插入位置，找到时 children[i] 中都是小于 key 的 item
+		if i > 0 {
+			candidate = n.items[i-1]
+		}
+		if n.isLeaf() {
+			break
+		}
+		n = n.children[i]
+	}
+	if candidate == nil {
+		return "", nil, false
+	}
+	return candidate.key, candidate.value, true
+}
+
+// DeleteMin deletes the smallest key and returns it with its value,
+// false if the tree is empty
+func (b *BTree) DeleteMin() (string, interface{}, bool) {
+	key, value, ok := b.Min()
+	if ok {
+		b.Remove(key)
+	}
+	return key, value, ok
+}
+
+// DeleteMax deletes the largest key and returns it with its value,
+// false if the tree is empty
+func (b *BTree) DeleteMax() (string, interface{}, bool) {
+	key, value, ok := b.Max()
+	if ok {
+		b.Remove(key)
+	}
+	return key, value, ok
+}
@@ -0,0 +1,54 @@
+package btree
+
+// Rank returns the number of items whose key is less than key
+func (b *BTree) Rank(key string) int {
+	rank := 0
+	n := b.root
+	for {
+		found, i := n.findKey(key)
+		rank += i
+		for _, child := range n.children[:min(i, len(n.children))] {
+			rank += child.count
+		}
+		if found {
+			// 被查找的 item 左边的子树也都小于 key
+			if !n.isLeaf() {
+				rank += n.children[i].count
+			}
+			return rank
+		}
+		if n.isLeaf() {
+			return rank
+		}
+		n = n.children[i]
+	}
+}
+
+// Select returns the i-th smallest key and its value counting from 0,
+// false if i is out of range
+func (b *BTree) Select(i int) (string, interface{}, bool) {
+	if i < 0 || i >= b.root.count {
+		return "", nil, false
+	}
+	n := b.root
+	for !n.isLeaf() {
+		j := 0
+		for ; i >= n.children[j].count; j++ {
+			i -= n.children[j].count
+			if i == 0 {
+				return n.items[j].key, n.items[j].value, true
+			}
+			i--
+		}
+		n = n.children[j]
+	}
+	return n.items[i].key, n.items[i].value, true
+}
+
+// CountRange returns the number of items whose key is in [lo, hi)
+func (b *BTree) CountRange(lo, hi string) int {
+	if lo >= hi {
+		return 0
+	}
+	return b.Rank(hi) - b.Rank(lo)
+}
@@ -0,0 +1,41 @@
+package btree
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBTree_Rank(t *testing.T) {
+	assert := assert.New(t)
+	b := NewTree(2)
+	for i := 0; i < 100; i++ {
+		k := fmt.Sprintf("%03d", i*2)
+		b.Put(k, i)
+	}
+	for i := 0; i < 100; i++ {
+		assert.Equal(i, b.Rank(fmt.Sprintf("%03d", i*2)))
+		assert.Equal(i+1, b.Rank(fmt.Sprintf("%03d", i*2+1)))
+		key, value, ok := b.Select(i)
+		assert.True(ok)
+		assert.Equal(fmt.Sprintf("%03d", i*2), key)
+		assert.Equal(i, value)
+	}
+	_, _, ok := b.Select(100)
+	assert.False(ok)
+	assert.Equal(5, b.CountRange("010", "020"))
+	assert.Equal(0, b.CountRange("020", "010"))
+
+	// 克隆之后两棵树的计数互不影响
+	c := b.Clone()
+	for i := 0; i < 100; i += 2 {
+		b.Delete(fmt.Sprintf("%03d", i*2))
+	}
+	assert.NoError(b.Verify())
+	assert.NoError(c.Verify())
+	assert.Equal(25, b.Rank("100"))
+	assert.Equal(50, c.Rank("100"))
+	key, _, _ := b.Select(0)
+	assert.Equal("002", key)
+}
@@ -0,0 +1,69 @@
+package btree
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrCorrupt = errors.New("btree: tree is corrupt")
+
+// Verify checks the structural invariants of the tree: keys are ordered
+// within and across nodes, every internal node has one child more than items,
+// non-root nodes hold between min and max items, all leaves are at the same
+// depth and subtree counts add up. Nodes have no parent pointers, so there
+// are none to check
+func (b *BTree) Verify() error {
+	if !b.root.isLeaf() && len(b.root.items) == 0 {
+		return fmt.Errorf("%w: internal root has no items", ErrCorrupt)
+	}
+	depth := -1
+	return b.verify(b.root, nil, nil, 0, &depth)
+}
+
+// verify 检查以 n 为根的子树，子树中的 key 都在 (lo, hi) 之内，nil 表示没有边界
+func (b *BTree) verify(n *Node, lo, hi *string, level int, depth *int) error {
+	if len(n.items) > b.max {
+		return fmt.Errorf("%w: node has %d items, want <= %d", ErrCorrupt, len(n.items), b.max)
+	}
+	if n != b.root && len(n.items) < b.min {
+		return fmt.Errorf("%w: node has %d items, want >= %d", ErrCorrupt, len(n.items), b.min)
+	}
+	total := len(n.items)
+	for _, child := range n.children {
+		total += child.count
+	}
+	if n.count != total {
+		return fmt.Errorf("%w: node counts %d items, want %d", ErrCorrupt, n.count, total)
+	}
+	prev := lo
+	for _, item := range n.items {
+		if (prev != nil && item.key <= *prev) || (hi != nil && item.key >= *hi) {
+			return fmt.Errorf("%w: key %q is out of order", ErrCorrupt, item.key)
+		}
+		prev = &item.key
+	}
+	if n.isLeaf() {
+		if *depth == -1 {
+			*depth = level
+		} else if *depth != level {
+			return fmt.Errorf("%w: leaves at depth %d and %d", ErrCorrupt, *depth, level)
+		}
+		return nil
+	}
+	if len(n.children) != len(n.items)+1 {
+		return fmt.Errorf("%w: node has %d items and %d children", ErrCorrupt, len(n.items), len(n.children))
+	}
+	for i, child := range n.children {
+		clo, chi := lo, hi
+		if i > 0 {
+			clo = &n.items[i-1].key
+		}
+		if i < len(n.items) {
+			chi = &n.items[i].key
+		}
+		if err := b.verify(child, clo, chi, level+1, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package btree
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBTree_VerifyCorrupt(t *testing.T) {
+	build := func() *BTree {
+		b := NewTree(2)
+		for i := 0; i < 50; i++ {
+			k := strconv.Itoa(100 + i)
+			b.Put(k, k)
+		}
+		assert.NoError(t, b.Verify())
+		return b
+	}
+
+	corruptions := map[string]func(b *BTree){
+		"node order": func(b *BTree) {
+			items := b.root.items
+			items[0], items[1] = items[1], items[0]
+		},
+		"parent range": func(b *BTree) {
+			b.root.children[0].items[0] = newItem("999", nil)
+		},
+		"children": func(b *BTree) {
+			b.root.children = b.root.children[:len(b.root.children)-1]
+		},
+		"underflow": func(b *BTree) {
+			child := b.root.children[0]
+			child.items = child.items[:1]
+			child.children = child.children[:2]
+		},
+		"depth": func(b *BTree) {
+			b.root.children[0] = b.root.children[0].children[0]
+		},
+		"count": func(b *BTree) {
+			b.root.children[0].count++
+		},
+	}
+	for name, corrupt := range corruptions {
+		b := build()
+		corrupt(b)
+		assert.ErrorIs(t, b.Verify(), ErrCorrupt, name)
+	}
+}
@@ -8,7 +8,7 @@ import (
 )
 
 func main() {
-	tree := bptree.NewBPTree()
+	tree := bptree.NewBPTree[int, string]()
 	for i := 1; i <= 1000; i++ {
 		tree.Insert(i, strconv.Itoa(i))
 	}
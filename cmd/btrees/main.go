@@ -0,0 +1,376 @@
+// Command btrees creates, queries and inspects the tree files of the disk package.
+//
+// Usage:
+//
+//	btrees <command> [flags] <file> [args]
+//
+// Existing files are opened as a disk.BytesTree or a disk.Tree depending on
+// their root page, new files are BytesTrees unless -uint32 is given.
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/pedrogao/btrees/disk"
+)
+
+const usage = `usage: btrees <command> [flags] <file> [args]
+
+commands:
+  put   [-uint32] <file> <key> <value>     insert or replace a key
+  get   <file> <key>                       print the value of a key
+  del   <file> <key>                       delete a key
+  scan  [-from key] [-to key] [-limit n] <file>
+                                           print the keys in [from, to)
+  load  [-uint32] [-format csv|jsonl] <file> <input>
+                                           load key/value records, "-" reads stdin
+  dump  [-format csv|jsonl] <file>         print all records, load reads them back
+  stats <file>                             print page and key counts
+  check <file>                             verify the integrity of the tree
+  dot   <file>                             print the tree for Graphviz
+
+A new file is a tree with byte string keys, -uint32 creates a tree with
+uint32 keys and values of at most 100 bytes.
+
+Values are base64 in jsonl, so any bytes survive a dump and load. csv holds
+text only, like the keys in both formats. load commits the records in
+batches, when a record fails the records before it are still loaded.
+`
+
+// load 一个批次的大小，修改的页不超过默认缓冲池的四分之一，和 disk.BulkLoad 一样
+const loadBatch = common.DefaultPoolSize / 4
+
+var loadBatchBytes = loadBatch * int(disk.PageSize)
+
+// 命令的输入和输出，测试时替换
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// errNotFound get 和 del 找不到 key，退出码为 1，但不是错误
+var errNotFound = errors.New("key not found")
+
+// command 一个子命令，create 表示文件不存在时创建
+type command struct {
+	args   string // 文件之后的参数
+	nargs  int
+	create bool
+	run    func(s store, flags *flagValues, args []string) error
+}
+
+type flagValues struct {
+	uint32Keys bool
+	from, to   string
+	limit      int
+	format     string
+}
+
+var commands = map[string]command{
+	"put":   {"<key> <value>", 2, true, runPut},
+	"get":   {"<key>", 1, false, runGet},
+	"del":   {"<key>", 1, false, runDel},
+	"scan":  {"", 0, false, runScan},
+	"load":  {"<input>", 1, true, runLoad},
+	"dump":  {"", 0, false, runDump},
+	"stats": {"", 0, false, runStats},
+	"check": {"", 0, false, runCheck},
+	"dot":   {"", 0, false, runDot},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	name := os.Args[1]
+	cmd, ok := commands[name]
+	if !ok {
+		if name != "help" && name != "-h" && name != "--help" {
+			fmt.Fprintf(os.Stderr, "btrees: unknown command %q\n", name)
+		}
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var values flagValues
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: btrees %s [flags] <file> %s\n", name, cmd.args)
+		fs.PrintDefaults()
+	}
+	switch name {
+	case "put", "load":
+		fs.BoolVar(&values.uint32Keys, "uint32", false, "create a tree with uint32 keys")
+	case "scan":
+		fs.StringVar(&values.from, "from", "", "first key, inclusive")
+		fs.StringVar(&values.to, "to", "", "last key, exclusive")
+		fs.IntVar(&values.limit, "limit", 0, "print at most n keys, 0 means no limit")
+	}
+	if name == "load" || name == "dump" {
+		fs.StringVar(&values.format, "format", "", "csv or jsonl, load guesses it from the file extension")
+	}
+	fs.Parse(os.Args[2:])
+	if fs.NArg() != cmd.nargs+1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(cmd, &values, fs.Arg(0), fs.Args()[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "btrees: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(cmd command, values *flagValues, path string, args []string) error {
+	s, err := openStore(path, cmd.create, values.uint32Keys)
+	if err != nil {
+		return err
+	}
+	err = cmd.run(s, values, args)
+	if cerr := s.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func runPut(s store, _ *flagValues, args []string) error {
+	return s.Put(args[0], []byte(args[1]))
+}
+
+func runGet(s store, _ *flagValues, args []string) error {
+	value, ok, err := s.Get(args[0])
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errNotFound
+	}
+	_, err = fmt.Fprintf(stdout, "%s\n", value)
+	return err
+}
+
+func runDel(s store, _ *flagValues, args []string) error {
+	ok, err := s.Delete(args[0])
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errNotFound
+	}
+	return nil
+}
+
+func runScan(s store, values *flagValues, _ []string) error {
+	w := bufio.NewWriter(stdout)
+	n := 0
+	// 和 dump 一样，写入失败时停止遍历
+	var werr error
+	err := s.Scan(values.from, values.to, func(key string, value []byte) bool {
+		_, werr = fmt.Fprintf(w, "%s\t%s\n", key, value)
+		n++
+		return werr == nil && (values.limit <= 0 || n < values.limit)
+	})
+	if err != nil {
+		return err
+	}
+	if werr != nil {
+		return werr
+	}
+	return w.Flush()
+}
+
+// record load 和 dump 使用的 JSONL 格式，[]byte 编码为 base64，value 可以是任意字节
+type record struct {
+	Key   string `json:"key"`
+	Value []byte `json:"value"`
+}
+
+// format 文件格式，没有指定时按扩展名判断
+func format(values *flagValues, path string) (string, error) {
+	f := values.format
+	if f == "" {
+		f = "jsonl"
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			f = "csv"
+		}
+	}
+	if f != "csv" && f != "jsonl" {
+		return "", fmt.Errorf("unknown format %q", f)
+	}
+	return f, nil
+}
+
+func runLoad(s store, values *flagValues, args []string) error {
+	f, err := format(values, args[0])
+	if err != nil {
+		return err
+	}
+	in := stdin
+	if args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+	// 每 loadBatch 条记录或者 loadBatchBytes 字节提交一次，而不是每条记录 fsync 一次
+	var (
+		b       = s.NewBatch()
+		n, size int
+		applied int
+	)
+	apply := func() error {
+		if err := b.Apply(); err != nil {
+			return fmt.Errorf("records %d-%d: %w", applied+1, n, err)
+		}
+		applied, size = n, 0
+		return nil
+	}
+	put := func(key string, value []byte) error {
+		if err := b.Put(key, value); err != nil {
+			return fmt.Errorf("record %d: %w", n+1, err)
+		}
+		n++
+		size += len(key) + len(value)
+		if b.Len() >= loadBatch || size >= loadBatchBytes {
+			return apply()
+		}
+		return nil
+	}
+	if f == "csv" {
+		err = loadCSV(in, put)
+	} else {
+		err = loadJSONL(in, put)
+	}
+	// 出错的记录之前的记录仍然写入，和逐条写入时一样
+	if b.Len() > 0 {
+		if aerr := apply(); err == nil {
+			err = aerr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stderr, "loaded %d records\n", n)
+	return nil
+}
+
+// loadCSV 每行两列 key,value，第一行是 key,value 时作为表头跳过
+func loadCSV(in io.Reader, put func(key string, value []byte) error) error {
+	r := csv.NewReader(in)
+	r.FieldsPerRecord = 2
+	for line := 0; ; line++ {
+		fields, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if line == 0 && fields[0] == "key" && fields[1] == "value" {
+			continue
+		}
+		if err := put(fields[0], []byte(fields[1])); err != nil {
+			return err
+		}
+	}
+}
+
+// loadJSONL 每行一个 {"key": ..., "value": ...}，跳过空行
+func loadJSONL(in io.Reader, put func(key string, value []byte) error) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var r record
+		if err := json.Unmarshal([]byte(text), &r); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := put(r.Key, r.Value); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+func runDump(s store, values *flagValues, _ []string) error {
+	f, err := format(values, "")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(stdout)
+	c := csv.NewWriter(w)
+	enc := json.NewEncoder(w)
+	// 写入失败时停止遍历，Scan 本身没有出错
+	var werr error
+	err = s.Scan("", "", func(key string, value []byte) bool {
+		if f == "csv" {
+			werr = c.Write([]string{key, string(value)})
+		} else {
+			werr = enc.Encode(record{Key: key, Value: value})
+		}
+		return werr == nil
+	})
+	if err != nil {
+		return err
+	}
+	if werr != nil {
+		return werr
+	}
+	c.Flush()
+	if err := c.Error(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func runStats(s store, _ *flagValues, _ []string) error {
+	stats, err := s.Stats()
+	if err != nil {
+		return err
+	}
+	// bufio.Writer 记住第一个写入错误，由 Flush 返回
+	w := bufio.NewWriter(stdout)
+	fmt.Fprintf(w, "kind:           %s\n", s.Kind())
+	fmt.Fprintf(w, "keys:           %d\n", stats.Keys)
+	fmt.Fprintf(w, "height:         %d\n", stats.Height)
+	fmt.Fprintf(w, "pages:          %d\n", stats.Pages)
+	fmt.Fprintf(w, "internal pages: %d\n", stats.InternalPages)
+	fmt.Fprintf(w, "leaf pages:     %d\n", stats.LeafPages)
+	fmt.Fprintf(w, "overflow pages: %d\n", stats.OverflowPages)
+	fmt.Fprintf(w, "free pages:     %d\n", stats.FreePages)
+	fmt.Fprintf(w, "leaf fill:      %.1f%%\n", stats.Fill*100)
+	return w.Flush()
+}
+
+func runCheck(s store, _ *flagValues, _ []string) error {
+	if err := s.Check(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(stdout, "ok")
+	return err
+}
+
+func runDot(s store, _ *flagValues, _ []string) error {
+	w := bufio.NewWriter(stdout)
+	if err := s.WriteDot(w); err != nil {
+		return err
+	}
+	return w.Flush()
+}
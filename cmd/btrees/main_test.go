@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runCommand 执行一个子命令，input 作为标准输入，返回标准输出
+func runCommand(t *testing.T, name string, values flagValues, path string, input string, args ...string) (string, error) {
+	cmd, ok := commands[name]
+	require.True(t, ok, name)
+	require.Len(t, args, cmd.nargs, name)
+	var out bytes.Buffer
+	stdin, stdout, stderr = strings.NewReader(input), &out, io.Discard
+	t.Cleanup(func() { stdin, stdout, stderr = os.Stdin, os.Stdout, os.Stderr })
+	err := run(cmd, &values, path, args)
+	return out.String(), err
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	bytesFile := filepath.Join(dir, "bytes.db")
+	treeFile := filepath.Join(dir, "tree.db")
+	// 按扩展名判断格式
+	input := filepath.Join(dir, "input.jsonl")
+	require.NoError(t, os.WriteFile(input, []byte("{\"key\":\"g\",\"value\":\"Nw==\"}\n"), 0o644))
+	// 每一步在前面步骤的结果上执行
+	steps := []struct {
+		name   string
+		cmd    string
+		values flagValues
+		file   string
+		input  string
+		args   []string
+		out    string
+		err    error // 期望的错误
+		fails  bool  // 出错，不检查是哪个错误
+	}{
+		{name: "get missing file", cmd: "get", file: bytesFile, args: []string{"a"}, err: os.ErrNotExist},
+		{name: "put", cmd: "put", file: bytesFile, args: []string{"a", "1"}},
+		{name: "put spaces", cmd: "put", file: bytesFile, args: []string{"c", "x y"}},
+		{name: "put replace", cmd: "put", file: bytesFile, args: []string{"a", "2"}},
+		{name: "get", cmd: "get", file: bytesFile, args: []string{"a"}, out: "2\n"},
+		{name: "get missing key", cmd: "get", file: bytesFile, args: []string{"b"}, err: errNotFound},
+		{name: "del missing key", cmd: "del", file: bytesFile, args: []string{"b"}, err: errNotFound},
+		{
+			name: "load csv", cmd: "load", values: flagValues{format: "csv"}, file: bytesFile, args: []string{"-"},
+			input: "key,value\nb,3\nd,\"4,5\"\n",
+		},
+		{
+			name: "load jsonl", cmd: "load", file: bytesFile, args: []string{"-"},
+			input: "{\"key\":\"e\",\"value\":\"/wA=\"}\n\n{\"key\":\"f\",\"value\":\"Ng==\"}\n",
+		},
+		{name: "load file", cmd: "load", file: bytesFile, args: []string{input}},
+		{name: "get binary", cmd: "get", file: bytesFile, args: []string{"e"}, out: "\xff\x00\n"},
+		{name: "del", cmd: "del", file: bytesFile, args: []string{"c"}},
+		{name: "get deleted", cmd: "get", file: bytesFile, args: []string{"c"}, err: errNotFound},
+		{name: "scan", cmd: "scan", file: bytesFile, out: "a\t2\nb\t3\nd\t4,5\ne\t\xff\x00\nf\t6\ng\t7\n"},
+		{name: "scan range", cmd: "scan", values: flagValues{from: "b", to: "e"}, file: bytesFile, out: "b\t3\nd\t4,5\n"},
+		{name: "scan limit", cmd: "scan", values: flagValues{from: "b", limit: 1}, file: bytesFile, out: "b\t3\n"},
+		{
+			name: "dump jsonl", cmd: "dump", file: bytesFile,
+			out: "{\"key\":\"a\",\"value\":\"Mg==\"}\n{\"key\":\"b\",\"value\":\"Mw==\"}\n" +
+				"{\"key\":\"d\",\"value\":\"NCw1\"}\n{\"key\":\"e\",\"value\":\"/wA=\"}\n{\"key\":\"f\",\"value\":\"Ng==\"}\n" +
+				"{\"key\":\"g\",\"value\":\"Nw==\"}\n",
+		},
+		{name: "check", cmd: "check", file: bytesFile, out: "ok\n"},
+
+		{name: "put uint32", cmd: "put", values: flagValues{uint32Keys: true}, file: treeFile, args: []string{"7", "seven"}},
+		{name: "put bad key", cmd: "put", file: treeFile, args: []string{"x", "1"}, fails: true},
+		{name: "load uint32", cmd: "load", values: flagValues{format: "csv"}, file: treeFile, args: []string{"-"}, input: "3,three\n9,nine\n"},
+		{name: "load bad key", cmd: "load", values: flagValues{format: "csv"}, file: treeFile, args: []string{"-"}, input: "5,five\nx,1\n", fails: true},
+		{name: "get uint32", cmd: "get", file: treeFile, args: []string{"7"}, out: "seven\n"},
+		{name: "get uint32 missing", cmd: "get", file: treeFile, args: []string{"8"}, err: errNotFound},
+		{name: "del uint32", cmd: "del", file: treeFile, args: []string{"9"}},
+		{name: "scan uint32", cmd: "scan", file: treeFile, out: "3\tthree\n5\tfive\n7\tseven\n"},
+		{name: "dump csv", cmd: "dump", values: flagValues{format: "csv"}, file: treeFile, out: "3,three\n5,five\n7,seven\n"},
+	}
+	for _, step := range steps {
+		out, err := runCommand(t, step.cmd, step.values, step.file, step.input, step.args...)
+		switch {
+		case step.err != nil:
+			require.ErrorIs(t, err, step.err, step.name)
+		case step.fails:
+			require.Error(t, err, step.name)
+		default:
+			require.NoError(t, err, step.name)
+		}
+		assert.Equal(t, step.out, out, step.name)
+	}
+}
+
+func TestRun_DumpLoad(t *testing.T) {
+	// 超过一个批次的记录，jsonl 中的 value 是任意字节
+	n := loadBatch*2 + 10
+	var csvInput, jsonlInput bytes.Buffer
+	w, enc := csv.NewWriter(&csvInput), json.NewEncoder(&jsonlInput)
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("key%05d", i)
+		require.NoError(t, w.Write([]string{key, fmt.Sprintf("value %d", i)}))
+		require.NoError(t, enc.Encode(record{Key: key, Value: []byte{byte(i), 0xff, 0}}))
+	}
+	w.Flush()
+
+	for format, input := range map[string]string{"csv": csvInput.String(), "jsonl": jsonlInput.String()} {
+		dir := t.TempDir()
+		src, dst := filepath.Join(dir, "src.db"), filepath.Join(dir, "dst.db")
+		values := flagValues{format: format}
+		_, err := runCommand(t, "load", values, src, input, "-")
+		require.NoError(t, err, format)
+		dump, err := runCommand(t, "dump", values, src, "")
+		require.NoError(t, err, format)
+		assert.Equal(t, input, dump, format)
+
+		_, err = runCommand(t, "load", values, dst, dump, "-")
+		require.NoError(t, err, format)
+		got, err := runCommand(t, "dump", values, dst, "")
+		require.NoError(t, err, format)
+		assert.Equal(t, dump, got, format)
+		out, err := runCommand(t, "check", flagValues{}, dst, "")
+		require.NoError(t, err, format)
+		assert.Equal(t, "ok\n", out, format)
+	}
+}
+
+// errWriter 写入总是失败
+type errWriter struct{}
+
+var errWrite = errors.New("write failed")
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errWrite
+}
+
+// 写输出失败时命令返回错误，退出码不为 0
+func TestRun_WriteError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	// 输出超过 bufio.Writer 的缓冲区，scan 和 dump 在遍历中就会写入失败
+	var b strings.Builder
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&b, "key%05d,value\n", i)
+	}
+	_, err := runCommand(t, "load", flagValues{format: "csv"}, path, b.String(), "-")
+	require.NoError(t, err)
+
+	for _, name := range []string{"get", "scan", "dump", "stats", "check", "dot"} {
+		var args []string
+		if name == "get" {
+			args = []string{"key00001"}
+		}
+		cmd := commands[name]
+		stdout = errWriter{}
+		err := run(cmd, &flagValues{}, path, args)
+		stdout = os.Stdout
+		assert.ErrorIs(t, err, errWrite, name)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/pedrogao/btrees/disk"
+)
+
+// store 命令行使用的树，key 和 value 都是文本形式
+type store interface {
+	Get(key string) ([]byte, bool, error)
+	Put(key string, value []byte) error
+	Delete(key string) (bool, error)
+	// NewBatch 返回一个空的批次，Apply 时作为一个事务提交
+	NewBatch() batch
+	// Scan 遍历 [from, to) 中的 key，空字符串表示没有边界
+	Scan(from, to string, fn func(key string, value []byte) bool) error
+	Stats() (disk.Stats, error)
+	Check() error
+	WriteDot(w io.Writer) error
+	Kind() string
+	Close() error
+}
+
+// batch 批量写入，Put 只检查 key 和 value，Apply 写入并清空批次
+type batch interface {
+	Put(key string, value []byte) error
+	Len() int
+	Apply() error
+}
+
+// openStore 打开 path 中的树，已有的文件自动识别类型，新文件由 create 决定是否创建以及 key 的类型
+func openStore(path string, create bool, uint32Keys bool) (store, error) {
+	if _, err := os.Stat(path); err != nil {
+		if !os.IsNotExist(err) || !create {
+			return nil, err
+		}
+		if uint32Keys {
+			return openTree(path)
+		}
+		return openBytes(path)
+	}
+	s, err := openBytes(path)
+	if errors.Is(err, disk.ErrNotBytesTree) {
+		return openTree(path)
+	}
+	return s, err
+}
+
+func openBytes(path string) (store, error) {
+	tree, err := disk.OpenBytes(path)
+	if err != nil {
+		return nil, err
+	}
+	return bytesStore{tree}, nil
+}
+
+func openTree(path string) (store, error) {
+	tree, err := disk.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return treeStore{tree}, nil
+}
+
+// bytesStore disk.BytesTree，key 和 value 都是任意的字节
+type bytesStore struct {
+	*disk.BytesTree
+}
+
+func (s bytesStore) Kind() string {
+	return "bytes"
+}
+
+func (s bytesStore) Get(key string) ([]byte, bool, error) {
+	return s.BytesTree.Get([]byte(key))
+}
+
+func (s bytesStore) Put(key string, value []byte) error {
+	return s.BytesTree.Put([]byte(key), value)
+}
+
+func (s bytesStore) Delete(key string) (bool, error) {
+	return s.BytesTree.Delete([]byte(key))
+}
+
+func (s bytesStore) NewBatch() batch {
+	return &bytesBatch{tree: s.BytesTree}
+}
+
+type bytesBatch struct {
+	tree *disk.BytesTree
+	disk.BytesWriteBatch
+}
+
+func (b *bytesBatch) Put(key string, value []byte) error {
+	if uintptr(len(key)) > disk.SlottedMaxKeySize {
+		return disk.ErrKeyTooLarge
+	}
+	b.BytesWriteBatch.Put([]byte(key), value)
+	return nil
+}
+
+func (b *bytesBatch) Apply() error {
+	defer b.Reset()
+	return b.BytesWriteBatch.Apply(b.tree)
+}
+
+func (s bytesStore) Scan(from, to string, fn func(key string, value []byte) bool) error {
+	var end []byte
+	if to != "" {
+		end = []byte(to)
+	}
+	return s.AscendRange([]byte(from), end, func(key, value []byte) bool {
+		return fn(string(key), value)
+	})
+}
+
+// treeStore disk.Tree，key 是十进制的 uint32，value 最长 RowSize 字节，末尾补 0
+type treeStore struct {
+	*disk.Tree
+}
+
+func (s treeStore) Kind() string {
+	return "uint32"
+}
+
+func parseKey(key string) (uint32, error) {
+	n, err := strconv.ParseUint(key, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("key %q is not a uint32", key)
+	}
+	return uint32(n), nil
+}
+
+// trimRow 去掉 row 末尾补的 0
+func trimRow(row []byte) []byte {
+	return bytes.TrimRight(row, "\x00")
+}
+
+func (s treeStore) Get(key string) ([]byte, bool, error) {
+	k, err := parseKey(key)
+	if err != nil {
+		return nil, false, err
+	}
+	row, ok, err := s.Search(k)
+	return trimRow(row), ok, err
+}
+
+func (s treeStore) Put(key string, value []byte) error {
+	k, err := parseKey(key)
+	if err != nil {
+		return err
+	}
+	return s.Insert(k, value)
+}
+
+func (s treeStore) Delete(key string) (bool, error) {
+	k, err := parseKey(key)
+	if err != nil {
+		return false, err
+	}
+	return s.Tree.Delete(k)
+}
+
+func (s treeStore) NewBatch() batch {
+	return &treeBatch{tree: s.Tree}
+}
+
+type treeBatch struct {
+	tree *disk.Tree
+	disk.WriteBatch
+}
+
+func (b *treeBatch) Put(key string, value []byte) error {
+	k, err := parseKey(key)
+	if err != nil {
+		return err
+	}
+	if uintptr(len(value)) > disk.LeafNodeValueSize {
+		return disk.ErrValueTooLarge
+	}
+	b.WriteBatch.Put(k, value)
+	return nil
+}
+
+func (b *treeBatch) Apply() error {
+	defer b.Reset()
+	return b.WriteBatch.Apply(b.tree)
+}
+
+func (s treeStore) Scan(from, to string, fn func(key string, value []byte) bool) error {
+	var lo uint32
+	hi := uint64(1) << 32
+	if from != "" {
+		k, err := parseKey(from)
+		if err != nil {
+			return err
+		}
+		lo = k
+	}
+	if to != "" {
+		k, err := parseKey(to)
+		if err != nil {
+			return err
+		}
+		hi = uint64(k)
+	}
+	return s.Ascend(lo, func(key uint32, row []byte) bool {
+		if uint64(key) >= hi {
+			return false
+		}
+		return fn(strconv.FormatUint(uint64(key), 10), trimRow(row))
+	})
+}
@@ -1,181 +1,198 @@
+// Command disk is the interactive shell of the db_tutorial on top of disk.Tree.
+//
+// Usage:
+//
+//	disk [-leaf-cells n] [-internal-cells n] [file]
+//
+// Statements are "insert <id> <username> <email>" and "select", meta commands
+// are ".btree", ".constants" and ".exit".
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"unsafe"
-)
-
-// common
-var (
-	PageSize         = uintptr(os.Getpagesize())
-	RowSize  uintptr = 100
-)
-
-type NodeType uint8
-
-const (
-	NodeInternal NodeType = iota + 1
-	NodeLeaf
-)
+	"strconv"
+	"strings"
 
-/*
- * Common Node Header Layout
- * 1. 节点类型，叶子节点、内部节点, uint8
- * 2. 是否根节点，uint8
- * 3. 父节点Id指针，uint32
- */
-var (
-	NodeTypeSize         = unsafe.Sizeof(uint8(0))
-	NodeTypeOffset       = 0
-	IsRootSize           = unsafe.Sizeof(uint8(0))
-	IsRootOffset         = NodeTypeSize
-	ParentPointerSize    = unsafe.Sizeof(uint32(0))
-	ParentPointerOffset  = IsRootOffset + IsRootSize
-	CommonNodeHeaderSize = NodeTypeSize + IsRootSize + ParentPointerSize
+	"github.com/pedrogao/btrees/disk"
 )
 
-/*
- * Leaf Node Header Layout
- */
-var (
-	LeafNodeNumCellsSize   = unsafe.Sizeof(uint32(0))
-	LeafNodeNumCellsOffset = CommonNodeHeaderSize
-	LeafNodeHeaderSize     = CommonNodeHeaderSize + LeafNodeNumCellsSize
-	LeafNodeNextLeafOffset = LeafNodeNumCellsOffset + LeafNodeNumCellsSize
-)
-
-/*
- * Leaf Node Body Layout
- */
-var (
-	LeafNodeKeySize               = unsafe.Sizeof(uint32(0))
-	LeafNodeKeyOffset     uintptr = 0
-	LeafNodeValueSize             = RowSize
-	LeafNodeValueOffset           = LeafNodeKeyOffset + LeafNodeKeySize
-	LeafNodeCellSize              = LeafNodeKeySize + LeafNodeValueSize
-	LeafNodeSpaceForCells         = PageSize - LeafNodeHeaderSize
-	LeafNodeMaxCells              = LeafNodeSpaceForCells / LeafNodeCellSize
-)
+// 每一行由 username 和 email 组成，id 是 key
+const usernameSize = 32
 
-/*
- * Internal Node Header Layout
- */
-var (
-	InternalNodeNumKeysSize      = unsafe.Sizeof(uint32(0))
-	InternalNodeNumKeysOffset    = CommonNodeHeaderSize
-	InternalNodeRightChildSize   = unsafe.Sizeof(uint32(0)) // 右孩子
-	InternalNodeRightChildOffset = InternalNodeNumKeysOffset + InternalNodeNumKeysSize
-	InternalNodeHeaderSize       = CommonNodeHeaderSize + InternalNodeNumKeysSize + InternalNodeRightChildSize
-)
+var emailSize = int(disk.RowSize) - usernameSize
 
-/*
- * Internal Node Body Layout
- */
-var (
-	InternalNodeKeySize   = unsafe.Sizeof(uint32(0))
-	InternalNodeChildSize = unsafe.Sizeof(uint32(0))
-	InternalNodeCellSize  = InternalNodeChildSize + InternalNodeKeySize
-)
-
-func getNodeType(node uintptr) NodeType {
-	value := *(*uint8)(unsafe.Pointer(node + uintptr(NodeTypeOffset)))
-	return NodeType(value)
-}
+// statementError 语句本身的错误，打印出来之后继续读取下一行，消息和 db_tutorial 一致
+type statementError string
 
-func setNodeType(node uintptr, typ NodeType) {
-	*(*uint8)(unsafe.Pointer(node + uintptr(NodeTypeOffset))) = uint8(typ)
+func (e statementError) Error() string {
+	return string(e)
 }
 
-func isNodeRoot(node uintptr) bool {
-	value := *(*uint8)(unsafe.Pointer(node + IsRootOffset))
-	return value == 1
-}
+const (
+	errSyntax      statementError = "Syntax error. Could not parse statement."
+	errStringLong  statementError = "String is too long."
+	errNegativeID  statementError = "ID must be positive."
+	errDuplicateID statementError = "Error: Duplicate key."
+)
 
-func setNodeRoot(node uintptr, isRoot bool) {
-	value := uint8(0)
-	if isRoot {
-		value = 1
+func main() {
+	leafCells := flag.Int("leaf-cells", 0, "maximum cells of a leaf node, 0 means disk.LeafNodeMaxCells")
+	internalCells := flag.Int("internal-cells", 0, "maximum keys of an internal node, 0 means disk.InternalNodeMaxCells")
+	flag.Parse()
+	path := "disk.db"
+	if flag.NArg() > 0 {
+		path = flag.Arg(0)
+	}
+	tree, err := disk.Open(path, disk.MaxLeafCells(*leafCells), disk.MaxInternalCells(*internalCells))
+	if err != nil {
+		log.Fatal(err)
+	}
+	r := &repl{tree: tree, out: os.Stdout}
+	err = r.run(os.Stdin)
+	if cerr := tree.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatal(err)
 	}
-	*(*uint8)(unsafe.Pointer(node + IsRootOffset)) = value
-}
-
-func nodeParent(node uintptr) *uint32 {
-	return (*uint32)(unsafe.Pointer(node + ParentPointerOffset))
-}
-
-func internalNodeNumKeys(node uintptr) *uint32 {
-	return (*uint32)(unsafe.Pointer(node + InternalNodeNumKeysOffset))
-}
-
-func internalNodeRightChild(node uintptr) *uint32 {
-	return (*uint32)(unsafe.Pointer(node + InternalNodeRightChildSize))
 }
 
-func internalNodeCell(node uintptr, cellNum uint32) *uint32 {
-	return (*uint32)(unsafe.Pointer(node + InternalNodeHeaderSize + uintptr(cellNum)*InternalNodeCellSize))
+type repl struct {
+	tree *disk.Tree
+	out  io.Writer
+}
+
+// run 逐行读取输入，直到 .exit 或者输入结束
+func (r *repl) run(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(r.out, "db > ")
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line[0] == '.' {
+			if line == ".exit" {
+				return nil
+			}
+			if err := r.meta(line); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.statement(line); err != nil {
+			return err
+		}
+	}
 }
 
-func internalNodeChild(node uintptr, childNum uint32) *uint32 {
-	numKeys := *internalNodeNumKeys(node)
-	if childNum > numKeys {
-		log.Fatalf("Tried to access child_num %d > num_keys %d\n", childNum, numKeys)
+// meta 执行以 . 开头的命令
+func (r *repl) meta(line string) error {
+	switch line {
+	case ".btree":
+		fmt.Fprintln(r.out, "Tree:")
+		return r.tree.PrintTree(r.out)
+	case ".constants":
+		r.constants()
 		return nil
-	} else if childNum == numKeys {
-		return internalNodeRightChild(node)
-	} else {
-		return internalNodeCell(node, childNum)
 	}
-}
-
-func internalNodeKey(node uintptr, keyNum uint32) *uint32 {
-	return (*uint32)(unsafe.Pointer(uintptr(*internalNodeCell(node, keyNum)) + InternalNodeChildSize))
-}
-
-func leafNodeNumCells(node uintptr) *uint32 {
-	return (*uint32)(unsafe.Pointer(node + LeafNodeNumCellsOffset))
-}
-
-func leafNodeCell(node uintptr, cellNum uint32) uintptr {
-	return node + LeafNodeHeaderSize + uintptr(cellNum)*LeafNodeCellSize
-}
-
-func leafNodeKey(node uintptr, cellNum uint32) *uint32 {
-	return (*uint32)(unsafe.Pointer(leafNodeCell(node, cellNum)))
-}
-
-func leafNodeValue(node uintptr, cellNum uint32) uintptr {
-	return leafNodeCell(node, cellNum) + LeafNodeKeySize
-}
-
-func leafNodeNextLeaf(node uintptr) *uint32 {
-	return (*uint32)(unsafe.Pointer(node + LeafNodeNextLeafOffset))
-}
-
-func getNodeMaxKey(node uintptr) uint32 {
-	switch getNodeType(node) {
-	case NodeInternal:
-		return *internalNodeKey(node, *internalNodeNumKeys(node)-1)
-	case NodeLeaf:
-		return *leafNodeKey(node, *leafNodeNumCells(node)-1)
+	fmt.Fprintf(r.out, "Unrecognized command '%s'\n", line)
+	return nil
+}
+
+func (r *repl) constants() {
+	fmt.Fprintln(r.out, "Constants:")
+	for _, c := range []struct {
+		name  string
+		value uintptr
+	}{
+		{"PageSize", disk.PageSize},
+		{"RowSize", disk.RowSize},
+		{"CommonNodeHeaderSize", disk.CommonNodeHeaderSize},
+		{"LeafNodeHeaderSize", disk.LeafNodeHeaderSize},
+		{"LeafNodeCellSize", disk.LeafNodeCellSize},
+		{"LeafNodeSpaceForCells", disk.LeafNodeSpaceForCells},
+		{"LeafNodeMaxCells", disk.LeafNodeMaxCells},
+		{"InternalNodeHeaderSize", disk.InternalNodeHeaderSize},
+		{"InternalNodeCellSize", disk.InternalNodeCellSize},
+		{"InternalNodeSpaceForCells", disk.InternalNodeSpaceForCells},
+		{"InternalNodeMaxCells", disk.InternalNodeMaxCells},
+	} {
+		fmt.Fprintf(r.out, "%s: %d\n", c.name, c.value)
 	}
-	panic("invalid node type")
-}
-
-func initializeLeafNode(node uintptr) {
-	setNodeType(node, NodeLeaf)
-	setNodeRoot(node, false)
-	*leafNodeNumCells(node) = 0
-	*leafNodeNextLeaf(node) = 0 // 0 represents no sibling
 }
 
-func initializeInternalNode(node uintptr) {
-	setNodeType(node, NodeInternal)
-	setNodeRoot(node, false)
-	*internalNodeNumKeys(node) = 0
+// statement 执行 insert 和 select
+func (r *repl) statement(line string) error {
+	var (
+		err error
+		se  statementError
+	)
+	switch fields := strings.Fields(line); fields[0] {
+	case "insert":
+		err = r.insert(fields[1:])
+	case "select":
+		err = r.selectAll()
+	default:
+		fmt.Fprintf(r.out, "Unrecognized keyword at start of '%s'.\n", line)
+		return nil
+	}
+	switch {
+	case err == nil:
+		fmt.Fprintln(r.out, "Executed.")
+	case errors.As(err, &se):
+		fmt.Fprintln(r.out, se)
+	default:
+		return err
+	}
+	return nil
 }
 
-func main() {
-	fmt.Println("hell disk")
+func (r *repl) insert(args []string) error {
+	if len(args) != 3 {
+		return errSyntax
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return errSyntax
+	}
+	if id < 0 {
+		return errNegativeID
+	}
+	if id > 1<<32-1 {
+		return errSyntax
+	}
+	username, email := args[1], args[2]
+	if len(username) > usernameSize || len(email) > emailSize {
+		return errStringLong
+	}
+	if _, ok, err := r.tree.Search(uint32(id)); err != nil {
+		return err
+	} else if ok {
+		return errDuplicateID
+	}
+	row := make([]byte, disk.RowSize)
+	copy(row, username)
+	copy(row[usernameSize:], email)
+	return r.tree.Insert(uint32(id), row)
+}
+
+func (r *repl) selectAll() error {
+	return r.tree.Ascend(0, func(key uint32, row []byte) bool {
+		username := bytes.TrimRight(row[:usernameSize], "\x00")
+		email := bytes.TrimRight(row[usernameSize:], "\x00")
+		fmt.Fprintf(r.out, "(%d, %s, %s)\n", key, username, email)
+		return true
+	})
 }
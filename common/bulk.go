@@ -0,0 +1,53 @@
+package common
+
+// DefaultFillFactor leaves some room in bulk loaded nodes, so the first
+// inserts after loading do not split every node
+const DefaultFillFactor = 0.9
+
+// FillCount returns the number of entries a bulk loaded node holds at the fill
+// factor, clamped to [min, max]. Fill factors outside (0, 1] mean DefaultFillFactor
+func FillCount(fill float64, min, max int) int {
+	if fill <= 0 || fill > 1 {
+		fill = DefaultFillFactor
+	}
+	per := int(fill * float64(max))
+	if per < min {
+		per = min
+	}
+	if per > max {
+		per = max
+	}
+	if per < 1 {
+		per = 1
+	}
+	return per
+}
+
+// FillSizes splits n entries into nodes of per entries from left to right.
+// The remainder is merged into or balanced against the node before it, so
+// that every node holds between min and max entries when there is more than
+// one node. It requires min <= per <= max and 2*min <= max+1
+func FillSizes(n, per, min, max int) []int {
+	if n <= 0 {
+		return nil
+	}
+	sizes := make([]int, n/per, n/per+1)
+	for i := range sizes {
+		sizes[i] = per
+	}
+	rem := n % per
+	switch {
+	case rem == 0:
+	case rem >= min || len(sizes) == 0:
+		sizes = append(sizes, rem)
+	case per+rem <= max:
+		// 剩余的项并入最后一个节点
+		sizes[len(sizes)-1] += rem
+	default:
+		// 最后两个节点平分
+		total := per + rem
+		sizes[len(sizes)-1] = total - total/2
+		sizes = append(sizes, total/2)
+	}
+	return sizes
+}
@@ -0,0 +1,43 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFillSizes(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(FillSizes(0, 4, 2, 4))
+	assert.Equal([]int{1}, FillSizes(1, 4, 2, 4))
+	assert.Equal([]int{4, 4}, FillSizes(8, 4, 2, 4))
+	assert.Equal([]int{3, 3, 2}, FillSizes(8, 3, 2, 4))
+	// 剩余 1 项，并入最后一个节点
+	assert.Equal([]int{3, 4}, FillSizes(7, 3, 2, 4))
+	// 剩余 1 项，最后一个节点已满，两个节点平分
+	assert.Equal([]int{4, 3, 2}, FillSizes(9, 4, 2, 4))
+
+	for n := 1; n < 200; n++ {
+		for max := 2; max < 12; max++ {
+			min := (max + 1) / 2
+			for per := min; per <= max; per++ {
+				sizes := FillSizes(n, per, min, max)
+				total := 0
+				for _, size := range sizes {
+					total += size
+					assert.LessOrEqual(size, max)
+					if len(sizes) > 1 {
+						assert.GreaterOrEqual(size, min)
+					}
+				}
+				assert.Equal(n, total)
+			}
+		}
+	}
+
+	assert.Equal(9, FillCount(0, 2, 10))
+	assert.Equal(10, FillCount(1, 2, 10))
+	assert.Equal(5, FillCount(0.5, 2, 10))
+	assert.Equal(2, FillCount(0.01, 2, 10))
+}
@@ -0,0 +1,23 @@
+package common
+
+import (
+	"io"
+	"os"
+)
+
+// File is the part of *os.File used by the pager and the WAL
+type File interface {
+	io.ReaderAt
+	io.WriterAt
+	Stat() (os.FileInfo, error)
+	Sync() error
+	Truncate(size int64) error
+	Close() error
+}
+
+// OpenFileFunc opens the file in path for reading and writing, creating it if needed
+type OpenFileFunc func(path string) (File, error)
+
+func openOSFile(path string) (File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+}
@@ -0,0 +1,269 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Graph is a tree prepared for export, nodes are numbered from 1 in
+// breadth-first order and 0 means no node
+type Graph struct {
+	Nodes          []GraphNode
+	ParentPointers bool // 是否输出父节点指针
+}
+
+// GraphNode is a node of a Graph
+type GraphNode struct {
+	ID       int
+	Keys     []string
+	Leaf     bool
+	Children []int
+	Parent   int  // 父节点指针，树没有父节点指针时为 0
+	Next     int  // 叶子节点的兄弟
+	Path     bool // 在查找路径上
+	Changed  bool // 最近一次分裂或者合并修改过
+}
+
+// GraphSource describes how to walk the nodes of a tree, the zero value of N
+// means no node. Parent and Next may be nil for trees without those pointers
+type GraphSource[N comparable] struct {
+	Children func(n N) []N
+	Keys     func(n N) []string
+	Parent   func(n N) N
+	Next     func(n N) N
+}
+
+// BuildGraph walks the tree from root in breadth-first order, nodes in path
+// and changed are highlighted
+func BuildGraph[N comparable](root N, src GraphSource[N], path, changed map[N]bool, parents bool) *Graph {
+	var zero N
+	g := &Graph{ParentPointers: parents && src.Parent != nil}
+	if root == zero {
+		return g
+	}
+	ids := map[N]int{root: 1}
+	order := []N{root}
+	for i := 0; i < len(order); i++ {
+		for _, child := range src.Children(order[i]) {
+			ids[child] = len(order) + 1
+			order = append(order, child)
+		}
+	}
+	for i, n := range order {
+		node := GraphNode{
+			ID:      i + 1,
+			Keys:    src.Keys(n),
+			Path:    path[n],
+			Changed: changed[n],
+		}
+		for _, child := range src.Children(n) {
+			node.Children = append(node.Children, ids[child])
+		}
+		node.Leaf = len(node.Children) == 0
+		if src.Parent != nil {
+			node.Parent = ids[src.Parent(n)]
+		}
+		if src.Next != nil {
+			node.Next = ids[src.Next(n)]
+		}
+		g.Nodes = append(g.Nodes, node)
+	}
+	return g
+}
+
+// GraphOptions selects what an exported tree highlights
+type GraphOptions[K any] struct {
+	Path           bool
+	Key            K
+	Changes        bool
+	ParentPointers bool
+}
+
+type GraphOption[K any] func(opts *GraphOptions[K])
+
+// HighlightPath highlights the nodes visited when searching key
+func HighlightPath[K any](key K) GraphOption[K] {
+	return func(opts *GraphOptions[K]) {
+		opts.Path = true
+		opts.Key = key
+	}
+}
+
+// HighlightChanges highlights the nodes touched by the last split or merge
+func HighlightChanges[K any]() GraphOption[K] {
+	return func(opts *GraphOptions[K]) {
+		opts.Changes = true
+	}
+}
+
+// ShowParents draws the parent pointers of the nodes, trees without parent
+// pointers ignore it
+func ShowParents[K any]() GraphOption[K] {
+	return func(opts *GraphOptions[K]) {
+		opts.ParentPointers = true
+	}
+}
+
+// NewGraphOptions applies the options
+func NewGraphOptions[K any](options ...GraphOption[K]) GraphOptions[K] {
+	var opts GraphOptions[K]
+	for _, option := range options {
+		option(&opts)
+	}
+	return opts
+}
+
+// ChangeTrace records the nodes touched by the last split or merge. Begin is
+// called when a write starts, the nodes touched during the same write are
+// kept together and replace the ones of an earlier write. With concurrent
+// writers the nodes of writes running at the same time may be mixed
+type ChangeTrace[N comparable] struct {
+	op    atomic.Uint64
+	mu    sync.Mutex
+	seen  uint64 // nodes 所属的写操作
+	nodes []N
+}
+
+// Begin starts a new write
+func (c *ChangeTrace[N]) Begin() {
+	c.op.Add(1)
+}
+
+// Touch records nodes modified by a split or a merge
+func (c *ChangeTrace[N]) Touch(nodes ...N) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if op := c.op.Load(); c.seen != op {
+		c.seen = op
+		c.nodes = nil
+	}
+	c.nodes = append(c.nodes, nodes...)
+}
+
+// Nodes returns the recorded nodes as a set
+func (c *ChangeTrace[N]) Nodes() map[N]bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	set := make(map[N]bool, len(c.nodes))
+	for _, n := range c.nodes {
+		set[n] = true
+	}
+	return set
+}
+
+const (
+	pathColor    = "#1f77b4"
+	changedColor = "#ff7f0e"
+	parentColor  = "#7f7f7f"
+)
+
+// Dot renders the graph in the Graphviz dot language. Nodes on the search path
+// and the edges between them are blue, changed nodes are filled orange,
+// sibling links are dashed and parent pointers are dotted
+func (g *Graph) Dot() string {
+	var b strings.Builder
+	b.WriteString("digraph G {\n\tnode [shape=record];\n")
+	for _, n := range g.Nodes {
+		fields := make([]string, len(n.Keys))
+		for i, key := range n.Keys {
+			fields[i] = dotEscape(key)
+		}
+		attrs := fmt.Sprintf("label=\"%s\"", strings.Join(fields, " | "))
+		if len(n.Keys) == 0 {
+			attrs = `label=" "`
+		}
+		if n.Path {
+			attrs += fmt.Sprintf(" color=\"%s\" penwidth=2", pathColor)
+		}
+		if n.Changed {
+			attrs += fmt.Sprintf(" style=filled fillcolor=\"%s\"", changedColor)
+		}
+		fmt.Fprintf(&b, "\tn%d [%s];\n", n.ID, attrs)
+	}
+	for _, n := range g.Nodes {
+		for _, child := range n.Children {
+			if n.Path && g.Nodes[child-1].Path {
+				fmt.Fprintf(&b, "\tn%d -> n%d [color=\"%s\" penwidth=2];\n", n.ID, child, pathColor)
+			} else {
+				fmt.Fprintf(&b, "\tn%d -> n%d;\n", n.ID, child)
+			}
+		}
+		if n.Next != 0 {
+			fmt.Fprintf(&b, "\tn%d -> n%d [style=dashed constraint=false];\n", n.ID, n.Next)
+		}
+		if g.ParentPointers && n.Parent != 0 {
+			fmt.Fprintf(&b, "\tn%d -> n%d [style=dotted color=\"%s\" constraint=false];\n", n.ID, n.Parent, parentColor)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart with the same highlights as Dot
+func (g *Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("graph TD\n")
+	for _, n := range g.Nodes {
+		fmt.Fprintf(&b, "\tn%d[\"%s\"]\n", n.ID, mermaidEscape(strings.Join(n.Keys, " | ")))
+	}
+	// linkStyle 按边出现的顺序编号
+	var (
+		edges     int
+		pathEdges []string
+	)
+	for _, n := range g.Nodes {
+		for _, child := range n.Children {
+			fmt.Fprintf(&b, "\tn%d --> n%d\n", n.ID, child)
+			if n.Path && g.Nodes[child-1].Path {
+				pathEdges = append(pathEdges, fmt.Sprint(edges))
+			}
+			edges++
+		}
+		if n.Next != 0 {
+			fmt.Fprintf(&b, "\tn%d -.-> n%d\n", n.ID, n.Next)
+			edges++
+		}
+		if g.ParentPointers && n.Parent != 0 {
+			fmt.Fprintf(&b, "\tn%d -. parent .-> n%d\n", n.ID, n.Parent)
+			edges++
+		}
+	}
+	var path, changed []string
+	for _, n := range g.Nodes {
+		if n.Path {
+			path = append(path, fmt.Sprintf("n%d", n.ID))
+		}
+		if n.Changed {
+			changed = append(changed, fmt.Sprintf("n%d", n.ID))
+		}
+	}
+	if len(path) > 0 {
+		fmt.Fprintf(&b, "\tclassDef path stroke:%s,stroke-width:3px\n", pathColor)
+		fmt.Fprintf(&b, "\tclass %s path\n", strings.Join(path, ","))
+	}
+	if len(pathEdges) > 0 {
+		fmt.Fprintf(&b, "\tlinkStyle %s stroke:%s,stroke-width:3px\n", strings.Join(pathEdges, ","), pathColor)
+	}
+	if len(changed) > 0 {
+		fmt.Fprintf(&b, "\tclassDef changed fill:%s\n", changedColor)
+		fmt.Fprintf(&b, "\tclass %s changed\n", strings.Join(changed, ","))
+	}
+	return b.String()
+}
+
+// dotEscape 转义 record 标签中的特殊字符
+func dotEscape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`, `"`, `\"`, `{`, `\{`, `}`, `\}`, `|`, `\|`, `<`, `\<`, `>`, `\>`, "\n", `\n`,
+	).Replace(s)
+}
+
+// mermaidEscape 标签放在双引号中，只需要转义双引号
+func mermaidEscape(s string) string {
+	if s == "" {
+		return " "
+	}
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
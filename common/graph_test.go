@@ -0,0 +1,108 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testNode 测试用的树节点
+type testNode struct {
+	key      string
+	parent   *testNode
+	next     *testNode
+	children []*testNode
+}
+
+func testGraph(parents bool) *Graph {
+	root := &testNode{key: "b"}
+	a := &testNode{key: "a", parent: root}
+	c := &testNode{key: "c|d", parent: root}
+	a.next = c
+	root.children = []*testNode{a, c}
+	return BuildGraph(root, GraphSource[*testNode]{
+		Children: func(n *testNode) []*testNode { return n.children },
+		Keys:     func(n *testNode) []string { return []string{n.key} },
+		Parent:   func(n *testNode) *testNode { return n.parent },
+		Next:     func(n *testNode) *testNode { return n.next },
+	}, nil, nil, parents)
+}
+
+func TestBuildGraph(t *testing.T) {
+	assert := assert.New(t)
+
+	g := testGraph(true)
+	assert.True(g.ParentPointers)
+	assert.Len(g.Nodes, 3)
+	assert.Equal(GraphNode{ID: 1, Keys: []string{"b"}, Children: []int{2, 3}}, g.Nodes[0])
+	assert.Equal(GraphNode{ID: 2, Keys: []string{"a"}, Leaf: true, Parent: 1, Next: 3}, g.Nodes[1])
+	assert.Equal(GraphNode{ID: 3, Keys: []string{"c|d"}, Leaf: true, Parent: 1}, g.Nodes[2])
+
+	empty := BuildGraph[*testNode](nil, GraphSource[*testNode]{}, nil, nil, true)
+	assert.Empty(empty.Nodes)
+	assert.False(empty.ParentPointers)
+	assert.Equal("digraph G {\n\tnode [shape=record];\n}\n", empty.Dot())
+}
+
+func TestGraph_Dot(t *testing.T) {
+	g := testGraph(true)
+	g.Nodes[0].Path = true
+	g.Nodes[2].Path = true
+	g.Nodes[1].Changed = true
+	assert.Equal(t, `digraph G {
+	node [shape=record];
+	n1 [label="b" color="#1f77b4" penwidth=2];
+	n2 [label="a" style=filled fillcolor="#ff7f0e"];
+	n3 [label="c\|d" color="#1f77b4" penwidth=2];
+	n1 -> n2;
+	n1 -> n3 [color="#1f77b4" penwidth=2];
+	n2 -> n3 [style=dashed constraint=false];
+	n2 -> n1 [style=dotted color="#7f7f7f" constraint=false];
+	n3 -> n1 [style=dotted color="#7f7f7f" constraint=false];
+}
+`, g.Dot())
+}
+
+func TestGraph_Mermaid(t *testing.T) {
+	g := testGraph(false)
+	g.Nodes[0].Path = true
+	g.Nodes[2].Path = true
+	g.Nodes[1].Changed = true
+	assert.Equal(t, `graph TD
+	n1["b"]
+	n2["a"]
+	n3["c|d"]
+	n1 --> n2
+	n1 --> n3
+	n2 -.-> n3
+	classDef path stroke:#1f77b4,stroke-width:3px
+	class n1,n3 path
+	linkStyle 1 stroke:#1f77b4,stroke-width:3px
+	classDef changed fill:#ff7f0e
+	class n2 changed
+`, g.Mermaid())
+}
+
+func TestChangeTrace(t *testing.T) {
+	assert := assert.New(t)
+
+	var c ChangeTrace[int]
+	assert.Empty(c.Nodes())
+	c.Begin()
+	c.Touch(1, 2)
+	c.Touch(3)
+	assert.Equal(map[int]bool{1: true, 2: true, 3: true}, c.Nodes())
+	// 新的写操作没有分裂或者合并时保留上一次的节点
+	c.Begin()
+	assert.Equal(map[int]bool{1: true, 2: true, 3: true}, c.Nodes())
+	c.Touch(4)
+	assert.Equal(map[int]bool{4: true}, c.Nodes())
+}
+
+func TestGraphOptions(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(GraphOptions[int]{}, NewGraphOptions[int]())
+	opts := NewGraphOptions(HighlightPath(5), HighlightChanges[int](), ShowParents[int]())
+	assert.Equal(GraphOptions[int]{Path: true, Key: 5, Changes: true, ParentPointers: true}, opts)
+}
@@ -17,6 +17,27 @@ func MmapAnonymous(size int) ([]byte, error) {
 	return mm, nil
 }
 
+// MmapFile maps the first size bytes of the file, size may be larger than the
+// file but the part past the end must not be accessed. Writes to the mapping
+// are copy-on-write and never reach the file
+func MmapFile(fd uintptr, size int) ([]byte, error) {
+	// MAP_PRIVATE：修改映射区时拷贝一份私有的页，文件只通过 WriteAt 修改
+	prot, flags := syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_PRIVATE
+
+	mm, err := syscall.Mmap(int(fd), 0, size, prot, flags)
+	if err != nil {
+		return nil, fmt.Errorf("mmap err: %s", err)
+	}
+
+	return mm, nil
+}
+
+// Madvise gives the kernel a hint about how the mapping will be accessed,
+// e.g. syscall.MADV_RANDOM
+func Madvise(b []byte, advice int) error {
+	return syscall.Madvise(b, advice)
+}
+
 func Unmap(b []byte) error {
 	return syscall.Munmap(b)
 }
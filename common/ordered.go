@@ -0,0 +1,49 @@
+package common
+
+// OrderedMap is the api shared by the in-memory trees, keys are kept in
+// ascending order so the trees can be swapped for each other
+type OrderedMap[K, V any] interface {
+	// Get returns the value of key and whether the key exists
+	Get(key K) (V, bool)
+	// Set stores value under key, the value of an existing key is replaced
+	Set(key K, value V)
+	// Delete removes key and reports whether it existed
+	Delete(key K) bool
+	// Len returns the number of key/value pairs
+	Len() int
+	// Min returns the smallest key and its value, false if the map is empty
+	Min() (K, V, bool)
+	// Max returns the largest key and its value, false if the map is empty
+	Max() (K, V, bool)
+	// Ascend calls fn for every key/value pair in ascending order until fn returns false
+	Ascend(fn func(key K, value V) bool)
+}
+
+// OrderStatistics answers positional queries in O(log n) with the subtree
+// counts kept in the nodes
+type OrderStatistics[K, V any] interface {
+	// Rank returns the number of pairs whose key is less than key
+	Rank(key K) int
+	// Select returns the i-th smallest pair counting from 0, false if i is out of range
+	Select(i int) (K, V, bool)
+	// CountRange returns the number of pairs whose key is in [lo, hi)
+	CountRange(lo, hi K) int
+}
+
+// Navigable answers nearest-key queries, e.g. the latest sample at or before
+// a timestamp. In multimap mode the backward queries return the last value of
+// the key and the forward queries the first one, as Max and Min do
+type Navigable[K, V any] interface {
+	// Floor returns the largest key that is less than or equal to key
+	Floor(key K) (K, V, bool)
+	// Ceil returns the smallest key that is greater than or equal to key
+	Ceil(key K) (K, V, bool)
+	// Prev returns the largest key that is less than key
+	Prev(key K) (K, V, bool)
+	// Next returns the smallest key that is greater than key
+	Next(key K) (K, V, bool)
+	// DeleteMin deletes the smallest pair and returns it, false if the map is empty
+	DeleteMin() (K, V, bool)
+	// DeleteMax deletes the largest pair and returns it, false if the map is empty
+	DeleteMax() (K, V, bool)
+}
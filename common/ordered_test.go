@@ -0,0 +1,209 @@
+package common_test
+
+import (
+	"cmp"
+	"maps"
+	"math/rand"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/pedrogao/btrees/b2"
+	"github.com/pedrogao/btrees/bptree"
+	"github.com/pedrogao/btrees/btree"
+	"github.com/pedrogao/btrees/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// 同一套用例跑在三棵树上
+func TestOrderedMap(t *testing.T) {
+	intKey := func(i int) int { return i - 250 }
+	strKey := func(i int) string { return strconv.Itoa(i) }
+
+	t.Run("bptree", func(t *testing.T) {
+		testOrderedMap(t, func() common.OrderedMap[int, any] {
+			return bptree.NewBPTree[int, any](bptree.MaxLeaf(4), bptree.MaxInternal(4))
+		}, intKey)
+	})
+	t.Run("bptree concurrent", func(t *testing.T) {
+		testOrderedMap(t, func() common.OrderedMap[int, any] {
+			return bptree.NewBPTree[int, any](bptree.MaxLeaf(5), bptree.MaxInternal(3), bptree.Concurrent())
+		}, intKey)
+	})
+	t.Run("btree", func(t *testing.T) {
+		testOrderedMap(t, func() common.OrderedMap[string, any] {
+			return btree.NewTree(2)
+		}, strKey)
+	})
+	t.Run("b2", func(t *testing.T) {
+		testOrderedMap(t, func() common.OrderedMap[int, any] {
+			return b2.NewBTree(2)
+		}, intKey)
+	})
+}
+
+func testOrderedMap[K cmp.Ordered](t *testing.T, newMap func() common.OrderedMap[K, any], key func(i int) K) {
+	m := newMap()
+	_, _, ok := m.Min()
+	assert.False(t, ok)
+	_, _, ok = m.Max()
+	assert.False(t, ok)
+	_, ok = m.Get(key(1))
+	assert.False(t, ok)
+	assert.False(t, m.Delete(key(1)))
+	checkOrderedMap(t, m, map[K]any{})
+
+	m.Set(key(1), "a")
+	m.Set(key(1), "b")
+	v, ok := m.Get(key(1))
+	assert.True(t, ok)
+	assert.Equal(t, "b", v)
+	assert.Equal(t, 1, m.Len())
+	assert.True(t, m.Delete(key(1)))
+	assert.False(t, m.Delete(key(1)))
+
+	r := rand.New(rand.NewSource(1))
+	want := map[K]any{}
+	for i := 0; i < 5000; i++ {
+		k := key(r.Intn(500))
+		if r.Intn(3) == 0 {
+			_, ok := want[k]
+			assert.Equal(t, ok, m.Delete(k), "delete %v", k)
+			delete(want, k)
+		} else {
+			m.Set(k, i)
+			want[k] = i
+		}
+		if i%500 == 0 {
+			checkOrderedMap(t, m, want)
+		}
+	}
+	checkOrderedMap(t, m, want)
+
+	for k := range want {
+		assert.True(t, m.Delete(k))
+	}
+	checkOrderedMap(t, m, map[K]any{})
+}
+
+func checkOrderedMap[K cmp.Ordered](t *testing.T, m common.OrderedMap[K, any], want map[K]any) {
+	keys := slices.Sorted(maps.Keys(want))
+	assert.Equal(t, len(keys), m.Len())
+
+	var got []K
+	m.Ascend(func(key K, value any) bool {
+		assert.Equal(t, want[key], value, "key %v", key)
+		got = append(got, key)
+		return true
+	})
+	assert.Equal(t, len(keys), len(got))
+	assert.True(t, slices.Equal(keys, got))
+
+	for _, k := range keys {
+		v, ok := m.Get(k)
+		assert.True(t, ok, "key %v", k)
+		assert.Equal(t, want[k], v, "key %v", k)
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	k, v, ok := m.Min()
+	assert.True(t, ok)
+	assert.Equal(t, keys[0], k)
+	assert.Equal(t, want[k], v)
+	k, v, ok = m.Max()
+	assert.True(t, ok)
+	assert.Equal(t, keys[len(keys)-1], k)
+	assert.Equal(t, want[k], v)
+
+	// fn 返回 false 时停止遍历
+	n := 0
+	m.Ascend(func(K, any) bool {
+		n++
+		return n < 3
+	})
+	assert.Equal(t, min(3, len(keys)), n)
+}
+
+type navigableMap[K any] interface {
+	common.OrderedMap[K, any]
+	common.Navigable[K, any]
+}
+
+func TestNavigable(t *testing.T) {
+	t.Run("bptree", func(t *testing.T) {
+		testNavigable(t, bptree.NewBPTree[int, any](bptree.MaxLeaf(4), bptree.MaxInternal(4)))
+	})
+	t.Run("bptree concurrent", func(t *testing.T) {
+		testNavigable(t, bptree.NewBPTree[int, any](bptree.MaxLeaf(5), bptree.MaxInternal(3), bptree.Concurrent()))
+	})
+	t.Run("b2", func(t *testing.T) {
+		testNavigable(t, b2.NewBTree(2))
+	})
+	t.Run("btree", func(t *testing.T) {
+		b := btree.NewTree(2)
+		for i := 0; i < 100; i++ {
+			b.Set(strconv.Itoa(1000+i*10), i)
+		}
+		k, _, ok := b.Floor("1055")
+		assert.True(t, ok)
+		assert.Equal(t, "1050", k)
+		k, _, ok = b.Next("1050")
+		assert.True(t, ok)
+		assert.Equal(t, "1060", k)
+		k, _, _ = b.DeleteMin()
+		assert.Equal(t, "1000", k)
+		k, _, _ = b.DeleteMax()
+		assert.Equal(t, "1990", k)
+		assert.Equal(t, 98, b.Len())
+	})
+}
+
+// 模拟按时间戳查询：key 是 0, 10, 20 ... 990
+func testNavigable(t *testing.T, m navigableMap[int]) {
+	_, _, ok := m.Floor(0)
+	assert.False(t, ok)
+	_, _, ok = m.DeleteMin()
+	assert.False(t, ok)
+	for _, i := range rand.New(rand.NewSource(1)).Perm(100) {
+		m.Set(i*10, i)
+	}
+
+	for ts := -5; ts <= 1000; ts += 5 {
+		k, v, ok := m.Floor(ts)
+		assert.Equal(t, ts >= 0, ok, "floor %d", ts)
+		if ok {
+			assert.Equal(t, min(990, ts/10*10), k, "floor %d", ts)
+			assert.Equal(t, k/10, v)
+		}
+		k, _, ok = m.Prev(ts)
+		assert.Equal(t, ts > 0, ok, "prev %d", ts)
+		if ok {
+			assert.Equal(t, (ts-1)/10*10, k, "prev %d", ts)
+		}
+		k, _, ok = m.Ceil(ts)
+		assert.Equal(t, ts <= 990, ok, "ceil %d", ts)
+		if ok {
+			assert.Equal(t, max(0, (ts+9)/10*10), k, "ceil %d", ts)
+		}
+		k, _, ok = m.Next(ts)
+		assert.Equal(t, ts < 990, ok, "next %d", ts)
+		if ok {
+			assert.Equal(t, max(0, (ts+10)/10*10), k, "next %d", ts)
+		}
+	}
+
+	for i := 0; i < 50; i++ {
+		k, v, ok := m.DeleteMin()
+		assert.True(t, ok)
+		assert.Equal(t, i*10, k)
+		assert.Equal(t, i, v)
+		k, _, ok = m.DeleteMax()
+		assert.True(t, ok)
+		assert.Equal(t, 990-i*10, k)
+	}
+	assert.Equal(t, 0, m.Len())
+	_, _, ok = m.DeleteMax()
+	assert.False(t, ok)
+}
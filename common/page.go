@@ -1,17 +1,47 @@
 package common
 
+import "container/list"
+
 type (
 	// Page of disk
 	Page struct {
 		Id    uint32
 		Flags uint32
-		Data  uintptr
+		Data  []byte
+
+		pins      int           // 引用计数，大于 0 时不能被淘汰
+		dirty     bool          // 是否需要写回磁盘
+		elem      *list.Element // 在 LRU 链表中的位置，被 pin 住时为 nil
+		orig      []byte        // 事务中第一次修改前的数据，用于回滚
+		origDirty bool          // 事务中第一次修改前是否为脏页
+		buf       []byte        // 整个页，Data 之后是校验和
+		mapped    bool          // buf 指向文件的映射
 	}
 
+	// PageProvider manages fixed-size pages of a file.
+	// Pages returned by Allocate and Fetch are pinned and must be released
 	PageProvider interface {
-		Fetch() *Page
+		// Allocate returns a new zeroed page
+		Allocate() (*Page, error)
+		// Fetch returns the page with the id
+		Fetch(id uint32) (*Page, error)
+		// Write marks the page dirty, it is written back on eviction or Flush
 		Write(page *Page)
-		Flush() *Page
-		Delete() *Page
+		// Release unpins the page
+		Release(page *Page)
+		// Flush writes all dirty pages back and syncs the file
+		Flush() error
+		// Delete frees the page, the id can be reused by Allocate
+		Delete(id uint32) error
 	}
 )
+
+// Dirty reports whether the page has been modified since it was read
+func (p *Page) Dirty() bool {
+	return p.dirty
+}
+
+// Pinned reports whether the page is in use
+func (p *Page) Pinned() bool {
+	return p.pins > 0
+}
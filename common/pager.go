@@ -0,0 +1,755 @@
+package common
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+var (
+	ErrInvalidPage  = errors.New("common: invalid page id")
+	ErrPoolFull     = errors.New("common: all pages in the buffer pool are pinned")
+	ErrPagePinned   = errors.New("common: page is pinned")
+	ErrCorruptFile  = errors.New("common: file is not a whole number of pages")
+	ErrNotPagerFile = errors.New("common: file is not a pager file")
+	ErrVersion      = errors.New("common: unsupported file format version")
+	ErrPageSize     = errors.New("common: file page size does not match")
+	ErrChecksum     = errors.New("common: page checksum mismatch")
+)
+
+const (
+	DefaultPoolSize         = 1024
+	DefaultCheckpointFrames = 1000
+	// FormatVersion is the version of the file format written by the pager
+	FormatVersion = 1
+	// 映射从 1MB 开始按倍数增长，超过 1GB 之后每次增长 1GB
+	minMmapSize = 1 << 20
+	maxMmapStep = 1 << 30
+	// PageTrailerSize is the number of bytes at the end of every page used
+	// by the pager for the checksum, Page.Data does not include them
+	PageTrailerSize = 4
+)
+
+/*
+ * Pager Header Layout, page 0
+ * 1. magic，"btrees\x00\x00"，8 字节
+ * 2. 文件格式版本，uint32
+ * 3. 页大小，uint32
+ * 4. 文件中的页数，包括 header 页，uint32
+ * 5. 空闲页链表的第一页，0 表示没有空闲页，uint32
+ * 6. 空闲页的数量，uint32
+ * 7. 根节点所在的页，由上层的树设置，uint32
+ *
+ * Page Trailer Layout，所有页的最后 4 字节，包括 header 页
+ * 1. 页号和页内容的 CRC32C，uint32，全 0 的页表示还没有写过
+ *
+ * Free Page Layout
+ * 1. 下一个空闲页，uint32
+ */
+const (
+	headerMagicOffset     = 0
+	headerVersionOffset   = 8
+	headerPageSizeOffset  = 12
+	headerNumPagesOffset  = 16
+	headerFreeHeadOffset  = 20
+	headerFreeCountOffset = 24
+	headerRootOffset      = 28
+	freeNextOffset        = 0
+)
+
+var magic = []byte("btrees\x00\x00")
+
+// Pager is a PageProvider backed by a file, pages are cached in a bounded
+// buffer pool and the least recently used unpinned page is evicted when the
+// pool is full. Page 0 holds the pager header and is never handed out.
+// Every page ends with a CRC32C that is checked when it is read from the file.
+// Deleted pages are linked into a free list stored in the file, Allocate
+// reuses them before growing the file.
+//
+// With WithMmap pages that are not cached are read from a mapping of the file
+// without a copy.
+//
+// With WithWAL the pager is transactional: pages modified since the last
+// Commit stay in memory, Commit appends their images to the WAL and Rollback
+// restores them, the data file is only updated by committed pages. The
+// modified pages do not count against the pool size, so the pool grows with
+// a large transaction and shrinks back when it ends
+type Pager struct {
+	file     File
+	pageSize int
+	numPages uint32 // 已分配的页数，包括 header 页
+	poolSize int
+	openFile OpenFileFunc
+	fileSize int64 // 数据文件的大小，映射中超出文件的部分不能访问
+
+	mmap       bool
+	mmapAdvice int
+	fd         uintptr
+	data       []byte // 文件的映射，长度可以超过文件
+
+	pages     map[uint32]*Page
+	lru       *list.List // 未被 pin 住的页，越靠前越久未使用
+	freeHead  uint32     // 空闲页链表，Allocate 时优先复用
+	freeCount uint32
+	root      uint32
+
+	wal              *WAL
+	checkpointFrames int
+	txn              map[uint32]*Page // 本次事务中修改过的页
+	txnNumPages      uint32           // 事务开始时的页数
+	txnFreeHead      uint32           // 事务开始时的空闲页链表
+	txnFreeCount     uint32
+	txnRoot          uint32
+}
+
+var _ PageProvider = (*Pager)(nil)
+
+type PagerOption func(p *Pager)
+
+// PoolSize sets the number of pages cached in memory
+func PoolSize(size int) PagerOption {
+	return func(p *Pager) {
+		p.poolSize = size
+	}
+}
+
+// WithWAL makes the pager write committed pages to a write-ahead log in
+// path + "-wal" before they reach the data file. The log is checkpointed
+// into the data file after checkpointFrames frames, 0 means DefaultCheckpointFrames
+func WithWAL(checkpointFrames int) PagerOption {
+	return func(p *Pager) {
+		p.checkpointFrames = checkpointFrames
+		if p.checkpointFrames <= 0 {
+			p.checkpointFrames = DefaultCheckpointFrames
+		}
+	}
+}
+
+// WithMmap reads pages from a private mapping of the data file instead of
+// copying them into the buffer pool, advice is passed to madvise for the whole
+// mapping. The mapping grows with the file, it is remapped when none of the
+// mapped pages is pinned. Modified pages are copy-on-write and are still
+// written back with WriteAt. The file must have an Fd method
+func WithMmap(advice int) PagerOption {
+	return func(p *Pager) {
+		p.mmap = true
+		p.mmapAdvice = advice
+	}
+}
+
+// WithOpenFile replaces the function used to open the data file and the WAL
+func WithOpenFile(openFile OpenFileFunc) PagerOption {
+	return func(p *Pager) {
+		p.openFile = openFile
+	}
+}
+
+// OpenPager opens the file in path, the file is created if it does not exist.
+// If a WAL is enabled, transactions committed to it are replayed into the file
+func OpenPager(path string, options ...PagerOption) (*Pager, error) {
+	p := &Pager{
+		pageSize: os.Getpagesize(),
+		openFile: openOSFile,
+		pages:    make(map[uint32]*Page),
+		lru:      list.New(),
+		txn:      make(map[uint32]*Page),
+	}
+	for _, option := range options {
+		option(p)
+	}
+	if p.poolSize <= 0 {
+		p.poolSize = DefaultPoolSize
+	}
+	file, err := p.openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p.file = file
+	if p.mmap {
+		f, ok := file.(interface{ Fd() uintptr })
+		if !ok {
+			file.Close()
+			return nil, errors.New("common: mmap needs a file with an Fd method")
+		}
+		p.fd = f.Fd()
+	}
+	if p.checkpointFrames > 0 {
+		if err := p.openWAL(path + "-wal"); err != nil {
+			file.Close()
+			return nil, err
+		}
+	}
+	if err := p.readHeader(true); err != nil {
+		p.closeFiles()
+		return nil, err
+	}
+	p.begin()
+	return p, nil
+}
+
+// openWAL 打开 WAL，将已经提交的事务写回数据文件，然后清空 WAL
+func (p *Pager) openWAL(path string) error {
+	file, err := p.openFile(path)
+	if err != nil {
+		return err
+	}
+	wal, err := openWAL(file, p.pageSize)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	p.wal = wal
+	numPages, err := wal.recover(func(id uint32, data []byte) error {
+		if _, err := p.file.WriteAt(data, int64(id)*int64(p.pageSize)); err != nil {
+			return fmt.Errorf("common: replay page %d: %w", id, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if numPages == 0 {
+		return nil
+	}
+	// header 页和其他页一样由 WAL 恢复，页数以最后一次提交为准。
+	// checkpoint 时崩溃可能只写了半个 header 页，它马上会被重写，不检查校验和
+	if err := p.readHeader(false); err != nil {
+		return err
+	}
+	p.numPages = numPages
+	if err := p.writeHeader(); err != nil {
+		return err
+	}
+	if err := p.file.Sync(); err != nil {
+		return err
+	}
+	return wal.reset()
+}
+
+func (p *Pager) readHeader(verify bool) error {
+	info, err := p.file.Stat()
+	if err != nil {
+		return err
+	}
+	p.fileSize = info.Size()
+	if info.Size() > 0 && info.Size() < int64(len(magic)) {
+		return ErrNotPagerFile
+	}
+	if info.Size() == 0 {
+		p.numPages = 1
+		if err := p.writeHeader(); err != nil {
+			return err
+		}
+		return p.file.Sync()
+	}
+	// 先检查 magic，其他程序的文件不一定是整数个页
+	header := make([]byte, p.pageSize)
+	if _, err := p.file.ReadAt(header, 0); err != nil && err != io.EOF {
+		return fmt.Errorf("common: read header: %w", err)
+	}
+	if !bytes.Equal(header[headerMagicOffset:headerMagicOffset+len(magic)], magic) {
+		return ErrNotPagerFile
+	}
+	if size := binary.LittleEndian.Uint32(header[headerPageSizeOffset:]); size != uint32(p.pageSize) {
+		return fmt.Errorf("%w: %d, expected %d", ErrPageSize, size, p.pageSize)
+	}
+	if info.Size()%int64(p.pageSize) != 0 {
+		return ErrCorruptFile
+	}
+	if verify {
+		if err := p.verify(0, header); err != nil {
+			return err
+		}
+	}
+	if version := binary.LittleEndian.Uint32(header[headerVersionOffset:]); version != FormatVersion {
+		return fmt.Errorf("%w: %d", ErrVersion, version)
+	}
+	p.numPages = binary.LittleEndian.Uint32(header[headerNumPagesOffset:])
+	p.freeHead = binary.LittleEndian.Uint32(header[headerFreeHeadOffset:])
+	p.freeCount = binary.LittleEndian.Uint32(header[headerFreeCountOffset:])
+	p.root = binary.LittleEndian.Uint32(header[headerRootOffset:])
+	if p.numPages == 0 {
+		return ErrCorruptFile
+	}
+	// 淘汰的页可能在 header 更新之前就写到了文件末尾
+	if n := uint32(info.Size() / int64(p.pageSize)); n > p.numPages {
+		p.numPages = n
+	}
+	return nil
+}
+
+func (p *Pager) writeHeader() error {
+	if _, err := p.file.WriteAt(p.header(), 0); err != nil {
+		return fmt.Errorf("common: write header: %w", err)
+	}
+	return nil
+}
+
+// header 当前的 header 页
+func (p *Pager) header() []byte {
+	header := make([]byte, p.pageSize)
+	copy(header[headerMagicOffset:], magic)
+	binary.LittleEndian.PutUint32(header[headerVersionOffset:], FormatVersion)
+	binary.LittleEndian.PutUint32(header[headerPageSizeOffset:], uint32(p.pageSize))
+	binary.LittleEndian.PutUint32(header[headerNumPagesOffset:], p.numPages)
+	binary.LittleEndian.PutUint32(header[headerFreeHeadOffset:], p.freeHead)
+	binary.LittleEndian.PutUint32(header[headerFreeCountOffset:], p.freeCount)
+	binary.LittleEndian.PutUint32(header[headerRootOffset:], p.root)
+	p.seal(0, header)
+	return header
+}
+
+// seal 把页号和页内容的校验和写到页尾
+func (p *Pager) seal(id uint32, buf []byte) {
+	binary.LittleEndian.PutUint32(buf[len(buf)-PageTrailerSize:], pageChecksum(id, buf))
+}
+
+// verify 检查从文件中读出的页，全 0 的页是分配后还没有写回的页
+func (p *Pager) verify(id uint32, buf []byte) error {
+	sum := binary.LittleEndian.Uint32(buf[len(buf)-PageTrailerSize:])
+	if sum == pageChecksum(id, buf) {
+		return nil
+	}
+	if sum == 0 && isZero(buf) {
+		return nil
+	}
+	return fmt.Errorf("%w: page %d", ErrChecksum, id)
+}
+
+func pageChecksum(id uint32, buf []byte) uint32 {
+	var seed [4]byte
+	binary.LittleEndian.PutUint32(seed[:], id)
+	checksum := crc32.Checksum(seed[:], castagnoli)
+	return crc32.Update(checksum, castagnoli, buf[:len(buf)-PageTrailerSize])
+}
+
+func isZero(buf []byte) bool {
+	for _, b := range buf {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// PageSize returns the size of every page in the file,
+// Page.Data is PageTrailerSize bytes shorter
+func (p *Pager) PageSize() int {
+	return p.pageSize
+}
+
+// Root returns the root page recorded in the header, 0 if it was never set
+func (p *Pager) Root() uint32 {
+	return p.root
+}
+
+// SetRoot records the root page in the header, with a WAL the change is
+// part of the current transaction
+func (p *Pager) SetRoot(root uint32) {
+	p.root = root
+}
+
+// NumPages returns the number of allocated pages, including the header page
+func (p *Pager) NumPages() uint32 {
+	return p.numPages
+}
+
+// FreePages returns the number of deleted pages waiting to be reused
+func (p *Pager) FreePages() uint32 {
+	return p.freeCount
+}
+
+// WAL returns the write-ahead log, nil if it is disabled
+func (p *Pager) WAL() *WAL {
+	return p.wal
+}
+
+func (p *Pager) Allocate() (*Page, error) {
+	if p.freeHead != 0 {
+		page, err := p.Fetch(p.freeHead)
+		if err != nil {
+			return nil, err
+		}
+		p.Write(page)
+		p.freeHead = binary.LittleEndian.Uint32(page.Data[freeNextOffset:])
+		p.freeCount--
+		clear(page.Data)
+		return page, nil
+	}
+	page, err := p.newFrame(p.numPages, nil)
+	if err != nil {
+		return nil, err
+	}
+	p.numPages++
+	p.Write(page)
+	return page, nil
+}
+
+func (p *Pager) Fetch(id uint32) (*Page, error) {
+	if id == 0 || id >= p.numPages {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidPage, id)
+	}
+	if page, ok := p.pages[id]; ok {
+		p.pin(page)
+		return page, nil
+	}
+	if page, err := p.fetchMapped(id); page != nil || err != nil {
+		return page, err
+	}
+	page, err := p.newFrame(id, nil)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.file.ReadAt(page.buf, int64(id)*int64(p.pageSize))
+	if err != nil && err != io.EOF {
+		p.drop(page)
+		return nil, fmt.Errorf("common: read page %d: %w", id, err)
+	}
+	if err := p.verify(id, page.buf); err != nil {
+		p.drop(page)
+		return nil, err
+	}
+	return page, nil
+}
+
+// fetchMapped 从映射中读取页，不拷贝。页还不在文件中，或者映射需要扩大但旧映射中有页
+// 被 pin 住时返回 nil，由调用者从文件读取
+func (p *Pager) fetchMapped(id uint32) (*Page, error) {
+	off := int64(id) * int64(p.pageSize)
+	if !p.mmap || off+int64(p.pageSize) > p.fileSize {
+		return nil, nil
+	}
+	if off+int64(p.pageSize) > int64(len(p.data)) {
+		if ok, err := p.remap(); !ok || err != nil {
+			return nil, err
+		}
+	}
+	page, err := p.newFrame(id, p.data[off:off+int64(p.pageSize):off+int64(p.pageSize)])
+	if err != nil {
+		return nil, err
+	}
+	if err := p.verify(id, page.buf); err != nil {
+		p.drop(page)
+		return nil, err
+	}
+	return page, nil
+}
+
+// remap 按文件当前的大小重新映射。缓存中旧映射的页，干净的直接丢弃，脏页拷贝到堆上；
+// 旧映射中的页被 pin 住时不能解除映射，返回 false
+func (p *Pager) remap() (bool, error) {
+	for _, page := range p.pages {
+		if page.mapped && page.pins > 0 {
+			return false, nil
+		}
+	}
+	for _, page := range p.pages {
+		if !page.mapped {
+			continue
+		}
+		if !page.dirty {
+			p.drop(page)
+			continue
+		}
+		page.buf = bytes.Clone(page.buf)
+		page.Data = page.buf[:len(page.Data)]
+		page.mapped = false
+	}
+	if err := p.unmap(); err != nil {
+		return false, err
+	}
+	data, err := MmapFile(p.fd, mmapSize(p.fileSize))
+	if err != nil {
+		return false, err
+	}
+	if err := Madvise(data, p.mmapAdvice); err != nil {
+		Unmap(data)
+		return false, fmt.Errorf("common: madvise: %w", err)
+	}
+	p.data = data
+	return true, nil
+}
+
+func (p *Pager) unmap() error {
+	if p.data == nil {
+		return nil
+	}
+	err := Unmap(p.data)
+	p.data = nil
+	return err
+}
+
+// mmapSize 映射的大小，不小于 size
+func mmapSize(size int64) int {
+	for i := int64(minMmapSize); i <= maxMmapStep; i *= 2 {
+		if size <= i {
+			return int(i)
+		}
+	}
+	return int((size + maxMmapStep - 1) / maxMmapStep * maxMmapStep)
+}
+
+// Write marks the page dirty, it must be called before the page is modified
+// so that the pager can keep the image to roll back to
+func (p *Pager) Write(page *Page) {
+	if p.wal != nil {
+		if _, ok := p.txn[page.Id]; !ok {
+			page.orig = append([]byte(nil), page.Data...)
+			page.origDirty = page.dirty
+			p.txn[page.Id] = page
+		}
+	}
+	page.dirty = true
+}
+
+func (p *Pager) Release(page *Page) {
+	if page.pins <= 0 {
+		return
+	}
+	page.pins--
+	if page.pins == 0 {
+		page.elem = p.lru.PushBack(page)
+	}
+}
+
+// Flush writes all dirty pages back and syncs the file.
+// With a WAL, the current transaction is committed and checkpointed
+func (p *Pager) Flush() error {
+	if p.wal != nil {
+		if err := p.Commit(); err != nil {
+			return err
+		}
+		return p.Checkpoint()
+	}
+	return p.writeBack()
+}
+
+// writeBack 将所有脏页和 header 写回数据文件
+func (p *Pager) writeBack() error {
+	for _, page := range p.pages {
+		if err := p.writePage(page); err != nil {
+			return err
+		}
+	}
+	if err := p.writeHeader(); err != nil {
+		return err
+	}
+	return p.file.Sync()
+}
+
+func (p *Pager) Delete(id uint32) error {
+	if id == 0 || id >= p.numPages {
+		return fmt.Errorf("%w: %d", ErrInvalidPage, id)
+	}
+	if page, ok := p.pages[id]; ok && page.pins > 0 {
+		return fmt.Errorf("%w: %d", ErrPagePinned, id)
+	}
+	// 空闲页本身记录链表中的下一页
+	page, err := p.Fetch(id)
+	if err != nil {
+		return err
+	}
+	p.Write(page)
+	clear(page.Data)
+	binary.LittleEndian.PutUint32(page.Data[freeNextOffset:], p.freeHead)
+	p.Release(page)
+	p.freeHead = id
+	p.freeCount++
+	return nil
+}
+
+// Commit makes the pages modified since the last Commit durable by appending
+// them to the WAL, it does nothing without a WAL
+func (p *Pager) Commit() error {
+	if p.wal == nil || len(p.txn) == 0 && !p.headerChanged() {
+		p.begin()
+		return nil
+	}
+	// 页数记录在提交帧中，空闲页链表或根节点变化时 header 页作为事务的最后一帧
+	frames := make([]*Page, 0, len(p.txn)+1)
+	for _, page := range p.txn {
+		p.seal(page.Id, page.buf)
+		frames = append(frames, page)
+	}
+	if p.headerChanged() {
+		frames = append(frames, &Page{Id: 0, buf: p.header()})
+	}
+	for i, page := range frames {
+		numPages := uint32(0)
+		if i == len(frames)-1 {
+			numPages = p.numPages
+		}
+		if err := p.wal.append(page.Id, page.buf, numPages); err != nil {
+			return err
+		}
+	}
+	if err := p.wal.sync(); err != nil {
+		return err
+	}
+	for _, page := range p.txn {
+		page.orig = nil
+	}
+	p.begin()
+	p.shrink()
+	if p.wal.Frames() >= p.checkpointFrames {
+		return p.Checkpoint()
+	}
+	return nil
+}
+
+// headerChanged 本次事务是否修改了 header 中页数以外的字段
+func (p *Pager) headerChanged() bool {
+	return p.freeHead != p.txnFreeHead || p.freeCount != p.txnFreeCount || p.root != p.txnRoot
+}
+
+// Rollback restores the pages modified since the last Commit,
+// it does nothing without a WAL. Pages allocated since the last Commit are
+// dropped even if they are still pinned and must not be used afterwards
+func (p *Pager) Rollback() {
+	for id, page := range p.txn {
+		if id >= p.txnNumPages {
+			// 本次事务新分配的页，页号会被再次分配，仍然被 pin 住的页也要移除，
+			// 之后对它的 Release 不再起作用
+			p.drop(page)
+			page.pins, page.dirty = 0, false
+			continue
+		}
+		copy(page.Data, page.orig)
+		page.dirty = page.origDirty
+		page.orig = nil
+	}
+	p.numPages = p.txnNumPages
+	p.freeHead, p.freeCount = p.txnFreeHead, p.txnFreeCount
+	p.root = p.txnRoot
+	p.begin()
+	p.shrink()
+}
+
+// Checkpoint writes the committed pages to the data file and truncates the WAL
+func (p *Pager) Checkpoint() error {
+	if p.wal == nil {
+		return p.writeBack()
+	}
+	if len(p.txn) > 0 {
+		return errors.New("common: checkpoint with uncommitted pages")
+	}
+	if err := p.writeBack(); err != nil {
+		return err
+	}
+	return p.wal.reset()
+}
+
+// begin 开始新的事务
+func (p *Pager) begin() {
+	clear(p.txn)
+	p.txnNumPages = p.numPages
+	p.txnFreeHead, p.txnFreeCount = p.freeHead, p.freeCount
+	p.txnRoot = p.root
+}
+
+// Close flushes all pages and closes the file
+func (p *Pager) Close() error {
+	if err := p.Flush(); err != nil {
+		p.closeFiles()
+		return err
+	}
+	return p.closeFiles()
+}
+
+func (p *Pager) closeFiles() error {
+	err := p.unmap()
+	if cerr := p.file.Close(); err == nil {
+		err = cerr
+	}
+	if p.wal != nil {
+		if werr := p.wal.close(); err == nil {
+			err = werr
+		}
+	}
+	return err
+}
+
+// newFrame 为 id 分配一个被 pin 住的页，缓冲池已满时淘汰最久未使用的页。
+// 本次事务修改过的页不能淘汰，也不占用缓冲池的大小。
+// mapped 为映射中的页，nil 时分配一个空页
+func (p *Pager) newFrame(id uint32, mapped []byte) (*Page, error) {
+	if len(p.pages) >= p.poolSize+len(p.txn) {
+		if err := p.evict(); err != nil {
+			return nil, err
+		}
+	}
+	buf := mapped
+	if buf == nil {
+		buf = make([]byte, p.pageSize)
+	}
+	page := &Page{
+		Id:     id,
+		Data:   buf[:p.pageSize-PageTrailerSize],
+		pins:   1,
+		buf:    buf,
+		mapped: mapped != nil,
+	}
+	p.pages[id] = page
+	return page, nil
+}
+
+// evict 淘汰最久未使用的页，本次事务修改过的页还没有提交，不能写回数据文件
+func (p *Pager) evict() error {
+	for elem := p.lru.Front(); elem != nil; elem = elem.Next() {
+		page := elem.Value.(*Page)
+		if _, ok := p.txn[page.Id]; ok {
+			continue
+		}
+		if err := p.writePage(page); err != nil {
+			return err
+		}
+		p.drop(page)
+		return nil
+	}
+	return ErrPoolFull
+}
+
+// shrink 事务结束后淘汰多出 poolSize 的页，写回失败的页留在缓冲池中，之后再淘汰
+func (p *Pager) shrink() {
+	for len(p.pages) > p.poolSize {
+		if p.evict() != nil {
+			return
+		}
+	}
+}
+
+func (p *Pager) pin(page *Page) {
+	if page.elem != nil {
+		p.lru.Remove(page.elem)
+		page.elem = nil
+	}
+	page.pins++
+}
+
+// drop 将页从缓冲池中移除，不写回
+func (p *Pager) drop(page *Page) {
+	if page.elem != nil {
+		p.lru.Remove(page.elem)
+		page.elem = nil
+	}
+	delete(p.pages, page.Id)
+}
+
+func (p *Pager) writePage(page *Page) error {
+	if !page.dirty {
+		return nil
+	}
+	p.seal(page.Id, page.buf)
+	off := int64(page.Id) * int64(p.pageSize)
+	if _, err := p.file.WriteAt(page.buf, off); err != nil {
+		return fmt.Errorf("common: write page %d: %w", page.Id, err)
+	}
+	p.fileSize = max(p.fileSize, off+int64(p.pageSize))
+	page.dirty = false
+	return nil
+}
@@ -0,0 +1,373 @@
+package common
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPager_AllocateFetch(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "pager.db")
+
+	p, err := OpenPager(path, PoolSize(2))
+	require.NoError(t, err)
+	assert.Equal(uint32(1), p.NumPages())
+
+	ids := make([]uint32, 0, 5)
+	for i := 0; i < 5; i++ {
+		page, err := p.Allocate()
+		require.NoError(t, err)
+		assert.Equal(uint32(i+1), page.Id)
+		assert.True(page.Dirty())
+		page.Data[0] = byte(i + 1)
+		p.Release(page)
+		ids = append(ids, page.Id)
+	}
+	assert.Equal(uint32(6), p.NumPages())
+	assert.LessOrEqual(len(p.pages), 2)
+
+	// evicted pages are read back from the file
+	for i, id := range ids {
+		page, err := p.Fetch(id)
+		require.NoError(t, err)
+		assert.Equal(byte(i+1), page.Data[0])
+		assert.False(page.Dirty())
+		p.Release(page)
+	}
+
+	_, err = p.Fetch(0)
+	assert.ErrorIs(err, ErrInvalidPage)
+	_, err = p.Fetch(6)
+	assert.ErrorIs(err, ErrInvalidPage)
+	require.NoError(t, p.Close())
+
+	p, err = OpenPager(path, PoolSize(2))
+	require.NoError(t, err)
+	defer p.Close()
+	assert.Equal(uint32(6), p.NumPages())
+	page, err := p.Fetch(3)
+	require.NoError(t, err)
+	assert.Equal(byte(3), page.Data[0])
+	p.Release(page)
+}
+
+func TestPager_Pin(t *testing.T) {
+	assert := assert.New(t)
+	p, err := OpenPager(filepath.Join(t.TempDir(), "pager.db"), PoolSize(2))
+	require.NoError(t, err)
+	defer p.Close()
+
+	a, err := p.Allocate()
+	require.NoError(t, err)
+	b, err := p.Allocate()
+	require.NoError(t, err)
+	assert.True(a.Pinned())
+
+	// both frames are pinned
+	_, err = p.Allocate()
+	assert.ErrorIs(err, ErrPoolFull)
+	assert.ErrorIs(p.Delete(a.Id), ErrPagePinned)
+
+	// fetching a cached page pins it again
+	a2, err := p.Fetch(a.Id)
+	require.NoError(t, err)
+	assert.Same(a, a2)
+	p.Release(a)
+	assert.True(a.Pinned())
+	p.Release(a)
+	assert.False(a.Pinned())
+
+	// a is the only unpinned page, so it is evicted and written back
+	a.Data[0] = 42
+	p.Write(a)
+	c, err := p.Allocate()
+	require.NoError(t, err)
+	assert.Equal(uint32(3), c.Id)
+	assert.NotContains(p.pages, a.Id)
+	p.Release(c)
+	p.Release(b)
+
+	a, err = p.Fetch(a.Id)
+	require.NoError(t, err)
+	assert.Equal(byte(42), a.Data[0])
+	p.Release(a)
+}
+
+func TestPager_Delete(t *testing.T) {
+	assert := assert.New(t)
+	p, err := OpenPager(filepath.Join(t.TempDir(), "pager.db"))
+	require.NoError(t, err)
+	defer p.Close()
+
+	a, err := p.Allocate()
+	require.NoError(t, err)
+	a.Data[0] = 1
+	p.Release(a)
+	assert.NoError(p.Delete(a.Id))
+	assert.ErrorIs(p.Delete(0), ErrInvalidPage)
+
+	// deleted ids are reused and come back zeroed
+	b, err := p.Allocate()
+	require.NoError(t, err)
+	assert.Equal(a.Id, b.Id)
+	assert.Equal(byte(0), b.Data[0])
+	p.Release(b)
+	assert.Equal(uint32(2), p.NumPages())
+}
+
+func TestPager_FreeList(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "pager.db")
+	p, err := OpenPager(path)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		page, err := p.Allocate()
+		require.NoError(t, err)
+		page.Data[0] = byte(i + 1)
+		p.Release(page)
+	}
+	for id := uint32(2); id <= 10; id += 2 {
+		assert.NoError(p.Delete(id))
+	}
+	assert.Equal(uint32(5), p.FreePages())
+	require.NoError(t, p.Close())
+
+	// 空闲页链表保存在文件中，重新打开后仍然复用
+	p, err = OpenPager(path, PoolSize(4))
+	require.NoError(t, err)
+	defer p.Close()
+	assert.Equal(uint32(5), p.FreePages())
+	var ids []uint32
+	for i := 0; i < 5; i++ {
+		page, err := p.Allocate()
+		require.NoError(t, err)
+		assert.Equal(byte(0), page.Data[0])
+		ids = append(ids, page.Id)
+		p.Release(page)
+	}
+	assert.ElementsMatch([]uint32{2, 4, 6, 8, 10}, ids)
+	assert.Equal(uint32(0), p.FreePages())
+	assert.Equal(uint32(11), p.NumPages())
+	page, err := p.Fetch(3)
+	require.NoError(t, err)
+	assert.Equal(byte(3), page.Data[0])
+	p.Release(page)
+}
+
+func TestPager_Checksum(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "pager.db")
+	p, err := OpenPager(path)
+	require.NoError(t, err)
+	assert.Equal(p.PageSize()-PageTrailerSize, len(mustAllocate(t, p).Data))
+	page := mustAllocate(t, p)
+	page.Data[0] = 1
+	p.Release(page)
+	p.SetRoot(page.Id)
+	require.NoError(t, p.Close())
+
+	p, err = OpenPager(path)
+	require.NoError(t, err)
+	assert.Equal(uint32(2), p.Root())
+	require.NoError(t, p.Close())
+
+	// 修改页中的一个字节，读取时校验和不匹配
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteAt([]byte{2}, int64(2*p.PageSize()))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	p, err = OpenPager(path)
+	require.NoError(t, err)
+	_, err = p.Fetch(1)
+	assert.NoError(err)
+	_, err = p.Fetch(2)
+	assert.ErrorIs(err, ErrChecksum)
+	require.NoError(t, p.Close())
+}
+
+func TestPager_Header(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+
+	foreign := filepath.Join(dir, "foreign.db")
+	require.NoError(t, os.WriteFile(foreign, []byte("SQLite format 3\x00"), 0644))
+	_, err := OpenPager(foreign)
+	assert.ErrorIs(err, ErrNotPagerFile)
+
+	path := filepath.Join(dir, "pager.db")
+	p, err := OpenPager(path)
+	require.NoError(t, err)
+	require.NoError(t, p.Close())
+	header, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	corrupt := func(offset int, value uint32, reseal bool) string {
+		buf := append([]byte(nil), header...)
+		binary.LittleEndian.PutUint32(buf[offset:], value)
+		if reseal {
+			p.seal(0, buf)
+		}
+		path := filepath.Join(dir, fmt.Sprintf("corrupt-%d-%v.db", offset, reseal))
+		require.NoError(t, os.WriteFile(path, buf, 0644))
+		return path
+	}
+	_, err = OpenPager(corrupt(headerVersionOffset, FormatVersion+1, true))
+	assert.ErrorIs(err, ErrVersion)
+	_, err = OpenPager(corrupt(headerPageSizeOffset, uint32(p.PageSize()*2), true))
+	assert.ErrorIs(err, ErrPageSize)
+	_, err = OpenPager(corrupt(headerNumPagesOffset, 7, false))
+	assert.ErrorIs(err, ErrChecksum)
+}
+
+func TestPager_RollbackRoot(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "pager.db")
+	p, err := OpenPager(path, WithWAL(0))
+	require.NoError(t, err)
+	p.SetRoot(1)
+	require.NoError(t, p.Commit())
+	p.SetRoot(5)
+	p.Rollback()
+	assert.Equal(uint32(1), p.Root())
+	p.SetRoot(3)
+	require.NoError(t, p.Commit())
+	assert.Equal(2, p.WAL().Frames())
+	require.NoError(t, p.Close())
+
+	p, err = OpenPager(path, WithWAL(0))
+	require.NoError(t, err)
+	defer p.Close()
+	assert.Equal(uint32(3), p.Root())
+}
+
+func TestPager_LargeTxn(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "pager.db")
+	p, err := OpenPager(path, PoolSize(4), WithWAL(0))
+	require.NoError(t, err)
+	defer p.Close()
+
+	// 事务修改的页超过缓冲池的大小
+	var ids []uint32
+	for i := 0; i < 20; i++ {
+		page := mustAllocate(t, p)
+		page.Data[0] = byte(i)
+		p.Write(page)
+		p.Release(page)
+		ids = append(ids, page.Id)
+	}
+	assert.Greater(len(p.pages), 4)
+	require.NoError(t, p.Commit())
+	assert.LessOrEqual(len(p.pages), 4)
+	for i, id := range ids {
+		page, err := p.Fetch(id)
+		require.NoError(t, err)
+		assert.Equal(byte(i), page.Data[0])
+		p.Release(page)
+	}
+
+	// 回滚后缓冲池同样缩回
+	numPages := p.NumPages()
+	for i := 0; i < 20; i++ {
+		page := mustAllocate(t, p)
+		p.Write(page)
+		p.Release(page)
+	}
+	p.Rollback()
+	assert.LessOrEqual(len(p.pages), 4)
+	assert.Equal(numPages, p.NumPages())
+}
+
+// 回滚时仍然被 pin 住的新页也被移除，页号再次分配时不会有两个 Page
+func TestPager_RollbackPinned(t *testing.T) {
+	assert := assert.New(t)
+	p, err := OpenPager(filepath.Join(t.TempDir(), "pager.db"), WithWAL(0))
+	require.NoError(t, err)
+	defer p.Close()
+
+	old := mustAllocate(t, p)
+	old.Data[0] = 1
+	p.Rollback()
+	page := mustAllocate(t, p)
+	assert.Equal(old.Id, page.Id)
+	assert.NotSame(old, page)
+	assert.Equal(byte(0), page.Data[0])
+
+	// 旧页的 Release 不影响新页
+	p.Release(old)
+	p.Release(old)
+	page.Data[0] = 2
+	p.Release(page)
+	require.NoError(t, p.Commit())
+	got, err := p.Fetch(page.Id)
+	require.NoError(t, err)
+	assert.Same(page, got)
+	assert.Equal(byte(2), got.Data[0])
+	p.Release(got)
+	assert.Equal(1, p.lru.Len())
+}
+
+func mustAllocate(t *testing.T, p *Pager) *Page {
+	page, err := p.Allocate()
+	require.NoError(t, err)
+	return page
+}
+
+func TestPager_Mmap(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "pager.db")
+	p, err := OpenPager(path, PoolSize(8), WithWAL(0), WithMmap(syscall.MADV_RANDOM))
+	require.NoError(t, err)
+	// 超过初始映射的大小，需要重新映射
+	n := minMmapSize/p.PageSize() + 100
+	for i := 0; i < n; i++ {
+		page := mustAllocate(t, p)
+		binary.LittleEndian.PutUint32(page.Data, page.Id)
+		p.Release(page)
+		if i%4 == 3 {
+			require.NoError(t, p.Commit())
+		}
+	}
+	require.NoError(t, p.Flush())
+	for id := uint32(1); id <= uint32(n); id++ {
+		page, err := p.Fetch(id)
+		require.NoError(t, err)
+		assert.Equal(id, binary.LittleEndian.Uint32(page.Data))
+		p.Release(page)
+	}
+	assert.GreaterOrEqual(len(p.data), n*p.PageSize())
+
+	// 映射中的页不拷贝，修改只在事务提交并写回之后才到达文件
+	page, err := p.Fetch(3)
+	require.NoError(t, err)
+	assert.True(page.mapped)
+	assert.Same(&p.data[3*p.PageSize()], &page.Data[0])
+	p.Write(page)
+	page.Data[4] = 7
+	p.Release(page)
+	p.Rollback()
+	page, err = p.Fetch(3)
+	require.NoError(t, err)
+	assert.Equal(byte(0), page.Data[4])
+	p.Write(page)
+	page.Data[4] = 9
+	p.Release(page)
+	require.NoError(t, p.Close())
+
+	p, err = OpenPager(path)
+	require.NoError(t, err)
+	defer p.Close()
+	page, err = p.Fetch(3)
+	require.NoError(t, err)
+	assert.Equal(uint32(3), binary.LittleEndian.Uint32(page.Data))
+	assert.Equal(byte(9), page.Data[4])
+	p.Release(page)
+}
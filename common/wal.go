@@ -0,0 +1,167 @@
+package common
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+)
+
+var ErrCorruptWAL = errors.New("common: wal header does not match the pager")
+
+const walMagic uint32 = 0x377f0682
+
+/*
+ * WAL Header Layout
+ * 1. magic, uint32
+ * 2. 页大小, uint32
+ * 3. salt, 每次重置 WAL 时重新生成，作为校验和的初始值, uint32
+ * 4. 前三项的校验和, uint32
+ *
+ * WAL Frame Layout
+ * 1. 页号, uint32
+ * 2. 提交后文件中的页数，只有事务的最后一帧（提交帧）不为 0, uint32
+ * 3. 校验和，以上一帧的校验和为初始值，覆盖帧头前 8 个字节和页数据, uint32
+ * 4. 页数据
+ */
+const (
+	walHeaderSize      = 16
+	walFrameHeaderSize = 12
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// WAL is a redo log of page images. Frames are appended by Pager.Commit and
+// replayed into the data file when the pager is opened after a crash
+type WAL struct {
+	file     File
+	pageSize int
+	salt     uint32
+	checksum uint32 // 最后一帧的校验和
+	size     int64  // 下一帧写入的位置
+	frames   int    // 上次重置以来写入的帧数
+}
+
+func openWAL(file File, pageSize int) (*WAL, error) {
+	w := &WAL{file: file, pageSize: pageSize}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() < walHeaderSize {
+		return w, w.reset()
+	}
+	header := make([]byte, walHeaderSize)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("common: read wal header: %w", err)
+	}
+	if binary.LittleEndian.Uint32(header[0:]) != walMagic ||
+		binary.LittleEndian.Uint32(header[12:]) != crc32.Checksum(header[:12], castagnoli) {
+		// header 本身不完整，说明 WAL 在重置过程中崩溃，里面没有有效的帧
+		return w, w.reset()
+	}
+	if binary.LittleEndian.Uint32(header[4:]) != uint32(pageSize) {
+		return nil, ErrCorruptWAL
+	}
+	w.salt = binary.LittleEndian.Uint32(header[8:])
+	w.checksum = w.salt
+	w.size = walHeaderSize
+	return w, nil
+}
+
+// append writes a frame, a non-zero numPages marks the last frame of a transaction
+func (w *WAL) append(id uint32, data []byte, numPages uint32) error {
+	frame := make([]byte, walFrameHeaderSize+len(data))
+	binary.LittleEndian.PutUint32(frame[0:], id)
+	binary.LittleEndian.PutUint32(frame[4:], numPages)
+	copy(frame[walFrameHeaderSize:], data)
+	checksum := frameChecksum(w.checksum, frame)
+	binary.LittleEndian.PutUint32(frame[8:], checksum)
+	if _, err := w.file.WriteAt(frame, w.size); err != nil {
+		return fmt.Errorf("common: write wal frame: %w", err)
+	}
+	w.checksum = checksum
+	w.size += int64(len(frame))
+	w.frames++
+	return nil
+}
+
+func (w *WAL) sync() error {
+	return w.file.Sync()
+}
+
+// recover calls apply for the frames of every committed transaction in order,
+// it stops at the first torn or corrupted frame and returns the number of
+// pages recorded by the last commit, 0 if nothing was committed
+func (w *WAL) recover(apply func(id uint32, data []byte) error) (uint32, error) {
+	var (
+		numPages uint32
+		pending  [][]byte // 当前事务中还未提交的帧
+		offset   = int64(walHeaderSize)
+		checksum = w.salt
+	)
+	for {
+		frame := make([]byte, walFrameHeaderSize+w.pageSize)
+		if _, err := w.file.ReadAt(frame, offset); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return 0, fmt.Errorf("common: read wal frame: %w", err)
+		}
+		if binary.LittleEndian.Uint32(frame[8:]) != frameChecksum(checksum, frame) {
+			break
+		}
+		checksum = binary.LittleEndian.Uint32(frame[8:])
+		offset += int64(len(frame))
+		pending = append(pending, frame)
+		if n := binary.LittleEndian.Uint32(frame[4:]); n != 0 {
+			for _, f := range pending {
+				if err := apply(binary.LittleEndian.Uint32(f[0:]), f[walFrameHeaderSize:]); err != nil {
+					return 0, err
+				}
+			}
+			numPages, pending = n, pending[:0]
+		}
+	}
+	return numPages, nil
+}
+
+// reset truncates the log and starts a new generation with a new salt,
+// so frames of the previous generation can never be replayed
+func (w *WAL) reset() error {
+	if err := w.file.Truncate(0); err != nil {
+		return fmt.Errorf("common: truncate wal: %w", err)
+	}
+	w.salt = rand.Uint32()
+	header := make([]byte, walHeaderSize)
+	binary.LittleEndian.PutUint32(header[0:], walMagic)
+	binary.LittleEndian.PutUint32(header[4:], uint32(w.pageSize))
+	binary.LittleEndian.PutUint32(header[8:], w.salt)
+	binary.LittleEndian.PutUint32(header[12:], crc32.Checksum(header[:12], castagnoli))
+	if _, err := w.file.WriteAt(header, 0); err != nil {
+		return fmt.Errorf("common: write wal header: %w", err)
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.checksum = w.salt
+	w.size = walHeaderSize
+	w.frames = 0
+	return nil
+}
+
+// Frames returns the number of frames written since the last checkpoint
+func (w *WAL) Frames() int {
+	return w.frames
+}
+
+func (w *WAL) close() error {
+	return w.file.Close()
+}
+
+func frameChecksum(seed uint32, frame []byte) uint32 {
+	checksum := crc32.Update(seed, castagnoli, frame[:8])
+	return crc32.Update(checksum, castagnoli, frame[walFrameHeaderSize:])
+}
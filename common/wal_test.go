@@ -0,0 +1,158 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPager_CommitRollback(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "pager.db")
+
+	p, err := OpenPager(path, WithWAL(0))
+	require.NoError(t, err)
+	page, err := p.Allocate()
+	require.NoError(t, err)
+	page.Data[0] = 1
+	p.Release(page)
+	require.NoError(t, p.Commit())
+	assert.Equal(1, p.WAL().Frames())
+
+	// 回滚修改和新分配的页
+	page, err = p.Fetch(1)
+	require.NoError(t, err)
+	p.Write(page)
+	page.Data[0] = 2
+	p.Release(page)
+	page, err = p.Allocate()
+	require.NoError(t, err)
+	p.Release(page)
+	assert.Equal(uint32(3), p.NumPages())
+	p.Rollback()
+	assert.Equal(uint32(2), p.NumPages())
+	page, err = p.Fetch(1)
+	require.NoError(t, err)
+	assert.Equal(byte(1), page.Data[0])
+	p.Release(page)
+
+	require.NoError(t, p.Close())
+	assert.Equal(0, p.WAL().Frames())
+}
+
+func TestPager_Recover(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "pager.db")
+
+	p, err := OpenPager(path, WithWAL(0))
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		page, err := p.Allocate()
+		require.NoError(t, err)
+		page.Data[0] = byte(i + 1)
+		p.Release(page)
+	}
+	require.NoError(t, p.Commit())
+	// 未提交的修改在崩溃后丢失
+	page, err := p.Fetch(1)
+	require.NoError(t, err)
+	p.Write(page)
+	page.Data[0] = 9
+	p.Release(page)
+	_, err = p.Allocate()
+	require.NoError(t, err)
+
+	// 模拟崩溃：不关闭 pager，数据文件中只有 header
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(int64(p.PageSize()), info.Size())
+
+	p2, err := OpenPager(path, WithWAL(0))
+	require.NoError(t, err)
+	assert.Equal(uint32(4), p2.NumPages())
+	assert.Equal(0, p2.WAL().Frames())
+	for i := uint32(1); i < 4; i++ {
+		page, err := p2.Fetch(i)
+		require.NoError(t, err)
+		assert.Equal(byte(i), page.Data[0])
+		p2.Release(page)
+	}
+	require.NoError(t, p2.Close())
+	p.closeFiles()
+}
+
+func TestPager_RecoverFreeList(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "pager.db")
+
+	p, err := OpenPager(path, WithWAL(0))
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		page, err := p.Allocate()
+		require.NoError(t, err)
+		p.Release(page)
+	}
+	require.NoError(t, p.Delete(2))
+	require.NoError(t, p.Delete(3))
+	require.NoError(t, p.Commit())
+
+	// 回滚的删除不进入空闲页链表
+	require.NoError(t, p.Delete(4))
+	assert.Equal(uint32(3), p.FreePages())
+	p.Rollback()
+	assert.Equal(uint32(2), p.FreePages())
+	require.NoError(t, p.Delete(1))
+	p.Rollback()
+
+	// 模拟崩溃，空闲页链表由 WAL 恢复
+	p2, err := OpenPager(path, WithWAL(0))
+	require.NoError(t, err)
+	assert.Equal(uint32(5), p2.NumPages())
+	assert.Equal(uint32(2), p2.FreePages())
+	var ids []uint32
+	for i := 0; i < 3; i++ {
+		page, err := p2.Allocate()
+		require.NoError(t, err)
+		ids = append(ids, page.Id)
+		p2.Release(page)
+	}
+	assert.Equal([]uint32{3, 2, 5}, ids)
+	require.NoError(t, p2.Close())
+	p.closeFiles()
+}
+
+func TestWAL_TornFrame(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "wal")
+	file, err := openOSFile(path)
+	require.NoError(t, err)
+	w, err := openWAL(file, 64)
+	require.NoError(t, err)
+
+	data := make([]byte, 64)
+	for i := uint32(1); i <= 3; i++ {
+		data[0] = byte(i)
+		require.NoError(t, w.append(i, data, i+1))
+	}
+	// 截断最后一帧，只有前两个事务有效
+	require.NoError(t, file.Truncate(w.size-1))
+
+	w, err = openWAL(file, 64)
+	require.NoError(t, err)
+	var ids []uint32
+	numPages, err := w.recover(func(id uint32, data []byte) error {
+		assert.Equal(byte(id), data[0])
+		ids = append(ids, id)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal([]uint32{1, 2}, ids)
+	assert.Equal(uint32(3), numPages)
+
+	_, err = openWAL(file, 128)
+	assert.ErrorIs(err, ErrCorruptWAL)
+	require.NoError(t, file.Close())
+}
@@ -0,0 +1,193 @@
+package disk
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+)
+
+// WriteBatch collects puts and deletes and applies them to a Tree as one
+// transaction. The zero value is an empty batch
+type WriteBatch struct {
+	ops []batchOp
+}
+
+type batchOp struct {
+	key    uint32
+	value  []byte
+	delete bool
+}
+
+// Put adds key->value to the batch, the value is copied
+func (b *WriteBatch) Put(key uint32, value []byte) {
+	b.ops = append(b.ops, batchOp{key: key, value: append([]byte(nil), value...)})
+}
+
+// Delete adds the deletion of key to the batch
+func (b *WriteBatch) Delete(key uint32) {
+	b.ops = append(b.ops, batchOp{key: key, delete: true})
+}
+
+// Len returns the number of operations in the batch
+func (b *WriteBatch) Len() int {
+	return len(b.ops)
+}
+
+// Reset empties the batch so it can be reused
+func (b *WriteBatch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+// Apply applies the batch to the tree in key order, operations on the same key
+// keep the order they were added in. The batch is committed to the WAL as one
+// transaction, so after an error or a crash either all or none of it is in the
+// tree. All pages modified by the batch stay in memory until the commit, the
+// buffer pool grows past PoolSize for a batch that touches more pages
+func (b *WriteBatch) Apply(t *Tree) error {
+	for _, op := range b.ops {
+		if uintptr(len(op.value)) > LeafNodeValueSize {
+			return ErrValueTooLarge
+		}
+	}
+	ops := slices.Clone(b.ops)
+	slices.SortStableFunc(ops, func(a, b batchOp) int { return cmp.Compare(a.key, b.key) })
+	return t.commit(t.applyBatch(ops))
+}
+
+// applyBatch 按 key 的顺序修改，下一个 key 仍然落在当前叶子节点中，
+// 并且上一次修改没有分裂或者合并节点时，不需要从根节点重新下降
+func (t *Tree) applyBatch(ops []batchOp) error {
+	var (
+		path []pathEntry
+		node []byte
+	)
+	for _, op := range ops {
+		if node == nil || !leafNodeCovers(node, op.key) {
+			// 修改过的页属于当前事务，不会被淘汰，只读的页可以释放
+			t.release()
+			var err error
+			if path, node, err = t.findLeaf(op.key); err != nil {
+				return err
+			}
+		}
+		if op.delete {
+			ok, err := t.deleteFromLeaf(path, node, op.key)
+			if err != nil {
+				return err
+			}
+			if ok && path[len(path)-1].pageNum != rootPageNum && leafNodeNumCells(node) < t.minSize(node) {
+				node = nil
+			}
+			continue
+		}
+		split := leafNodeNumCells(node) >= t.maxLeafCells
+		if err := t.insertIntoLeaf(path, node, op.key, op.value); err != nil {
+			return err
+		}
+		if split {
+			node = nil
+		}
+	}
+	return nil
+}
+
+// leafNodeCovers key 一定属于这个叶子节点：在第一个和最后一个 key 之间，
+// 或者叶子节点是最右边的叶子节点并且 key 不小于第一个 key
+func leafNodeCovers(node []byte, key uint32) bool {
+	numCells := leafNodeNumCells(node)
+	if numCells == 0 || key < leafNodeKey(node, 0) {
+		return false
+	}
+	return key <= leafNodeKey(node, numCells-1) || leafNodeNextLeaf(node) == 0
+}
+
+// BytesWriteBatch is the WriteBatch of a BytesTree. The zero value is an empty batch
+type BytesWriteBatch struct {
+	ops []bytesBatchOp
+}
+
+type bytesBatchOp struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// Put adds key->value to the batch, the key and the value are copied
+func (b *BytesWriteBatch) Put(key, value []byte) {
+	b.ops = append(b.ops, bytesBatchOp{key: bytes.Clone(key), value: append([]byte(nil), value...)})
+}
+
+// Delete adds the deletion of key to the batch
+func (b *BytesWriteBatch) Delete(key []byte) {
+	b.ops = append(b.ops, bytesBatchOp{key: bytes.Clone(key), delete: true})
+}
+
+// Len returns the number of operations in the batch
+func (b *BytesWriteBatch) Len() int {
+	return len(b.ops)
+}
+
+// Reset empties the batch so it can be reused
+func (b *BytesWriteBatch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+// Apply applies the batch to the tree like WriteBatch.Apply
+func (b *BytesWriteBatch) Apply(t *BytesTree) error {
+	for _, op := range b.ops {
+		if uintptr(len(op.key)) > SlottedMaxKeySize {
+			return ErrKeyTooLarge
+		}
+	}
+	ops := slices.Clone(b.ops)
+	slices.SortStableFunc(ops, func(a, b bytesBatchOp) int { return bytes.Compare(a.key, b.key) })
+	return t.commit(t.applyBatch(ops))
+}
+
+// applyBatch 和 Tree.applyBatch 相同，下一个 key 仍然落在当前叶子节点中，
+// 并且上一次修改没有分裂或者合并节点时，不需要从根节点重新下降
+func (t *BytesTree) applyBatch(ops []bytesBatchOp) error {
+	var (
+		path []pathEntry
+		node []byte
+	)
+	for _, op := range ops {
+		if node == nil || !slottedLeafCovers(node, op.key) {
+			// 修改过的页属于当前事务，不会被淘汰，只读的页可以释放
+			t.release()
+			var err error
+			if path, node, err = t.findLeaf(op.key); err != nil {
+				return err
+			}
+		}
+		if op.delete {
+			ok, err := t.deleteFromLeaf(path, node, op.key)
+			if err != nil {
+				return err
+			}
+			// 与 rebalance 的条件相同，节点可能已经合并
+			space := uintptr(len(node)) - SlottedHeaderSize
+			if ok && path[len(path)-1].pageNum != rootPageNum && slottedUsedSpace(node) < space/4 {
+				node = nil
+			}
+			continue
+		}
+		split, err := t.putIntoLeaf(path, op.key, bytes.NewReader(op.value))
+		if err != nil {
+			return err
+		}
+		if split {
+			node = nil
+		}
+	}
+	return nil
+}
+
+// slottedLeafCovers 与 leafNodeCovers 相同，key 一定属于这个叶子节点
+func slottedLeafCovers(node []byte, key []byte) bool {
+	numCells := slottedNumCells(node)
+	if numCells == 0 || bytes.Compare(key, slottedLeafKey(node, 0)) < 0 {
+		return false
+	}
+	return bytes.Compare(key, slottedLeafKey(node, numCells-1)) <= 0 || slottedLink(node) == 0
+}
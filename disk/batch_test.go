@@ -0,0 +1,213 @@
+package disk
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteBatch_Random(t *testing.T) {
+	for _, size := range []int{2, 3, 7} {
+		r := rand.New(rand.NewSource(int64(size)))
+		tree, err := Open(filepath.Join(t.TempDir(), "test.db"), MaxLeafCells(size), MaxInternalCells(size))
+		require.NoError(t, err)
+
+		m := map[uint32]bool{}
+		var b WriteBatch
+		for round := 0; round < 30; round++ {
+			b.Reset()
+			for i := 0; i < 100; i++ {
+				key := uint32(r.Intn(1000))
+				if r.Intn(3) == 0 {
+					b.Delete(key)
+					delete(m, key)
+				} else {
+					b.Put(key, row(key))
+					m[key] = true
+				}
+			}
+			require.NoError(t, b.Apply(tree), "size %d round %d", size, round)
+
+			want := make([]uint32, 0, len(m))
+			for key := range m {
+				want = append(want, key)
+			}
+			sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+			require.Equal(t, want, verifyTree(t, tree), "size %d round %d", size, round)
+		}
+		require.NoError(t, tree.Close())
+	}
+}
+
+func TestWriteBatch_Order(t *testing.T) {
+	assert := assert.New(t)
+	tree, err := Open(filepath.Join(t.TempDir(), "test.db"), MaxLeafCells(3), MaxInternalCells(3))
+	require.NoError(t, err)
+	defer tree.Close()
+
+	// 同一个 key 上的操作按加入的顺序执行
+	var b WriteBatch
+	b.Put(2, []byte("a"))
+	b.Put(1, []byte("b"))
+	b.Delete(2)
+	b.Put(2, []byte("c"))
+	b.Delete(1)
+	assert.Equal(5, b.Len())
+	require.NoError(t, b.Apply(tree))
+
+	got, ok, err := tree.Search(2)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal(byte('c'), got[0])
+	_, ok, _ = tree.Search(1)
+	assert.False(ok)
+}
+
+// 出错时整个批次回滚
+func TestWriteBatch_Rollback(t *testing.T) {
+	tree, err := Open(filepath.Join(t.TempDir(), "test.db"), MaxLeafCells(3), MaxInternalCells(3), PoolSize(8))
+	require.NoError(t, err)
+	defer tree.Close()
+	for i := uint32(0); i < 10; i++ {
+		require.NoError(t, tree.Insert(i, row(i)))
+	}
+	keys := verifyTree(t, tree)
+
+	var b WriteBatch
+	b.Put(100, row(100))
+	b.Put(101, make([]byte, RowSize+1))
+	assert.ErrorIs(t, b.Apply(tree), ErrValueTooLarge)
+	assert.Equal(t, keys, verifyTree(t, tree))
+
+	// 修改的页超过缓冲池的大小时缓冲池临时变大
+	b.Reset()
+	for i := uint32(10); i < 200; i++ {
+		b.Put(i, row(i))
+		keys = append(keys, i)
+	}
+	require.NoError(t, b.Apply(tree))
+	assert.Equal(t, keys, verifyTree(t, tree))
+}
+
+func TestWriteBatch_Crash(t *testing.T) {
+	options := []Option{MaxLeafCells(3), MaxInternalCells(3)}
+	src := filepath.Join(t.TempDir(), "test.db")
+	tree, err := Open(src, options...)
+	require.NoError(t, err)
+	var before []uint32
+	for i := uint32(0); i < 40; i += 2 {
+		require.NoError(t, tree.Insert(i, row(i)))
+		before = append(before, i)
+	}
+	require.NoError(t, tree.Close())
+
+	// 插入奇数、删除 4 的倍数，before 和 after 的长度不同
+	var (
+		b     WriteBatch
+		after []uint32
+	)
+	for i := uint32(0); i < 40; i++ {
+		switch {
+		case i%2 == 1:
+			b.Put(i, row(i))
+			after = append(after, i)
+		case i%4 == 0:
+			b.Delete(i)
+		default:
+			after = append(after, i)
+		}
+	}
+	testCrash(t, src, before, after, b.Apply, options...)
+}
+
+func TestBytesWriteBatch_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tree, err := OpenBytes(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer tree.Close()
+
+	// value 大小不一，叶子节点会分裂、合并，偶尔使用溢出页
+	value := func(key string) []byte {
+		n := 50 + len(key)*20
+		if key[len(key)-1] == '7' {
+			n = 2 * int(PageSize)
+		}
+		return bytes.Repeat([]byte(key), n/len(key))
+	}
+	m := map[string]bool{}
+	var b BytesWriteBatch
+	for round := 0; round < 30; round++ {
+		b.Reset()
+		for i := 0; i < 100; i++ {
+			key := fmt.Sprint(r.Intn(1000))
+			if r.Intn(3) == 0 {
+				b.Delete([]byte(key))
+				delete(m, key)
+			} else {
+				b.Put([]byte(key), value(key))
+				m[key] = true
+			}
+		}
+		require.NoError(t, b.Apply(tree), "round %d", round)
+
+		want := make([]string, 0, len(m))
+		for key := range m {
+			want = append(want, key)
+		}
+		sort.Strings(want)
+		require.Equal(t, want, verifyBytesTree(t, tree), "round %d", round)
+		for _, key := range want {
+			got, ok, err := tree.Get([]byte(key))
+			require.NoError(t, err)
+			require.True(t, ok)
+			require.Equal(t, value(key), got, "round %d key %s", round, key)
+		}
+	}
+}
+
+func TestBytesWriteBatch(t *testing.T) {
+	assert := assert.New(t)
+	tree, err := OpenBytes(filepath.Join(t.TempDir(), "test.db"), PoolSize(8))
+	require.NoError(t, err)
+	defer tree.Close()
+	require.NoError(t, tree.Put([]byte("a"), []byte("old")))
+	require.NoError(t, tree.Put([]byte("b"), []byte("old")))
+	numPages := tree.pager.NumPages()
+
+	// key 太长时整个批次都不写入
+	var b BytesWriteBatch
+	b.Put([]byte("c"), []byte("new"))
+	b.Put(make([]byte, SlottedMaxKeySize+1), nil)
+	assert.ErrorIs(b.Apply(tree), ErrKeyTooLarge)
+	assert.Equal([]string{"a", "b"}, verifyBytesTree(t, tree))
+	assert.Equal(numPages, tree.pager.NumPages())
+
+	// 同一个 key 上的操作按加入的顺序执行，修改的页超过缓冲池的大小
+	b.Reset()
+	want := []string{"a"}
+	for i := 0; i < 300; i++ {
+		key := fmt.Sprintf("key%03d", i)
+		b.Put([]byte(key), bytes.Repeat([]byte{byte(i)}, 100))
+		want = append(want, key)
+	}
+	b.Put([]byte("a"), []byte("new"))
+	b.Delete([]byte("b"))
+	b.Put([]byte("b"), []byte("new"))
+	b.Delete([]byte("b"))
+	assert.Equal(304, b.Len())
+	require.NoError(t, b.Apply(tree))
+	assert.Equal(want, verifyBytesTree(t, tree))
+	got, ok, err := tree.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.True(ok)
+	assert.Equal([]byte("new"), got)
+	got, _, err = tree.Get([]byte("key123"))
+	require.NoError(t, err)
+	assert.Equal(bytes.Repeat([]byte{123}, 100), got)
+}
@@ -0,0 +1,249 @@
+package disk
+
+import (
+	"errors"
+	"iter"
+
+	"github.com/pedrogao/btrees/common"
+)
+
+var (
+	ErrNotSorted = errors.New("disk: bulk load keys are not strictly increasing")
+	ErrNotEmpty  = errors.New("disk: bulk load into a non-empty tree")
+)
+
+// BulkLoad builds the tree in path from rows sorted by key. Leaves are filled
+// from left to right to the fill factor, then the internal levels are built
+// bottom-up. The tree in path must be empty; the root page is written last,
+// so a failed load leaves an empty tree. The pages are committed in batches,
+// after an error they are freed again, after a crash they stay allocated but
+// unreachable until Vacuum
+func BulkLoad(path string, seq iter.Seq2[uint32, []byte], options ...Option) (*Tree, error) {
+	t, err := Open(path, options...)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.bulkLoad(seq); err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// bulkEntry 已经写好的节点，以及它的最大 key
+type bulkEntry struct {
+	pageNum uint32
+	maxKey  uint32
+}
+
+// bulkLoader 按顺序写叶子节点，每写 batch 个页提交一次，避免未提交的页占满缓冲池
+type bulkLoader struct {
+	tree    *Tree
+	level   []bulkEntry
+	prev    uint32 // 上一个叶子节点，0 表示还没有
+	written int    // 本次事务中写过的页数
+	batch   int
+	// 本次事务中分配的页，以及之前的批次已经提交的页，加载失败时释放
+	allocated, committed []uint32
+}
+
+func (t *Tree) bulkLoad(seq iter.Seq2[uint32, []byte]) (err error) {
+	root, err := t.getPage(rootPageNum)
+	if err != nil {
+		return t.commit(err)
+	}
+	if getNodeType(root) != NodeLeaf || leafNodeNumCells(root) > 0 {
+		return t.commit(ErrNotEmpty)
+	}
+	poolSize := t.poolSize
+	if poolSize <= 0 {
+		poolSize = common.DefaultPoolSize
+	}
+	b := &bulkLoader{tree: t, batch: max(poolSize/4, 1)}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, b.free())
+		}
+	}()
+
+	var (
+		min   = int(t.maxLeafCells / 2)
+		per   = common.FillCount(t.fill, min, int(t.maxLeafCells))
+		cells [][]byte
+		last  = int64(-1)
+	)
+	for key, value := range seq {
+		if int64(key) <= last {
+			return t.commit(ErrNotSorted)
+		}
+		if uintptr(len(value)) > LeafNodeValueSize {
+			return t.commit(ErrValueTooLarge)
+		}
+		last = int64(key)
+		cell := make([]byte, LeafNodeCellSize)
+		putUint32(cell, LeafNodeKeyOffset, key)
+		copy(cell[LeafNodeValueOffset:], value)
+		cells = append(cells, cell)
+		// 留下至少一个节点的 cell，最后两个节点可能需要平分
+		if len(cells) == 2*per {
+			if err := b.writeLeaf(cells[:per], false); err != nil {
+				return t.commit(err)
+			}
+			cells = append(cells[:0], cells[per:]...)
+		}
+	}
+	sizes := common.FillSizes(len(cells), per, min, int(t.maxLeafCells))
+	for _, size := range sizes {
+		isRoot := len(b.level) == 0 && len(sizes) == 1
+		if err := b.writeLeaf(cells[:size], isRoot); err != nil {
+			return t.commit(err)
+		}
+		cells = cells[size:]
+	}
+	if err := b.commit(); err != nil {
+		return err
+	}
+	return b.buildInternalLevels()
+}
+
+// writeLeaf 写一个叶子节点，根节点总是在 page 1
+func (b *bulkLoader) writeLeaf(cells [][]byte, isRoot bool) error {
+	t := b.tree
+	var (
+		pageNum = rootPageNum
+		node    []byte
+		err     error
+	)
+	if isRoot {
+		node, err = t.getWritablePage(rootPageNum)
+	} else {
+		pageNum, node, err = b.allocatePage()
+	}
+	if err != nil {
+		return err
+	}
+	initializeLeafNode(node)
+	setNodeRoot(node, isRoot)
+	for i, cell := range cells {
+		copy(leafNodeCell(node, uint32(i)), cell)
+	}
+	setLeafNodeNumCells(node, uint32(len(cells)))
+	if b.prev != 0 {
+		prev, err := t.getWritablePage(b.prev)
+		if err != nil {
+			return err
+		}
+		setLeafNodeNextLeaf(prev, pageNum)
+	}
+	b.prev = pageNum
+	b.level = append(b.level, bulkEntry{pageNum: pageNum, maxKey: leafNodeKey(node, uint32(len(cells)-1))})
+	t.release()
+	return b.wrote()
+}
+
+// buildInternalLevels 自底向上逐层构建内部节点，只剩一个节点时它就是根节点
+func (b *bulkLoader) buildInternalLevels() error {
+	t := b.tree
+	// 内部节点的大小以孩子数计算，最多 maxInternalCells 个 key，即多一个孩子
+	var (
+		maxChildren = int(t.maxInternalCells) + 1
+		min         = int(t.maxInternalCells/2) + 1
+		per         = common.FillCount(t.fill, min, maxChildren)
+	)
+	for len(b.level) > 1 {
+		sizes := common.FillSizes(len(b.level), per, min, maxChildren)
+		var parents []bulkEntry
+		for _, size := range sizes {
+			entry, err := b.writeInternal(b.level[:size], len(sizes) == 1)
+			if err != nil {
+				return err
+			}
+			b.level = b.level[size:]
+			parents = append(parents, entry)
+		}
+		b.level = parents
+	}
+	return b.commit()
+}
+
+// writeInternal 写一个内部节点。孩子的父指针先于节点本身写入，
+// 根节点最后提交，因此提交之前所有的页都不可达，可以分批提交
+func (b *bulkLoader) writeInternal(children []bulkEntry, isRoot bool) (bulkEntry, error) {
+	t := b.tree
+	pageNum := rootPageNum
+	if !isRoot {
+		num, _, err := b.allocatePage()
+		if err != nil {
+			return bulkEntry{}, t.commit(err)
+		}
+		pageNum = num
+		t.release()
+	}
+	keys := make([]uint32, 0, len(children)-1)
+	pageNums := make([]uint32, 0, len(children))
+	for i, child := range children {
+		if i < len(children)-1 {
+			keys = append(keys, child.maxKey)
+		}
+		pageNums = append(pageNums, child.pageNum)
+		node, err := t.getWritablePage(child.pageNum)
+		if err != nil {
+			return bulkEntry{}, t.commit(err)
+		}
+		setNodeParent(node, pageNum)
+		t.release()
+		if err := b.wrote(); err != nil {
+			return bulkEntry{}, err
+		}
+	}
+
+	node, err := t.getWritablePage(pageNum)
+	if err != nil {
+		return bulkEntry{}, t.commit(err)
+	}
+	initializeInternalNode(node)
+	setNodeRoot(node, isRoot)
+	t.writeInternalNode(node, pageNum, keys, pageNums)
+	t.release()
+	return bulkEntry{pageNum: pageNum, maxKey: children[len(children)-1].maxKey}, b.wrote()
+}
+
+// wrote 写完了一个页，每写 batch 个页提交一次
+func (b *bulkLoader) wrote() error {
+	b.written++
+	if b.written < b.batch {
+		return nil
+	}
+	return b.commit()
+}
+
+func (b *bulkLoader) commit() error {
+	b.written = 0
+	if err := b.tree.commit(nil); err != nil {
+		return err
+	}
+	b.committed = append(b.committed, b.allocated...)
+	b.allocated = b.allocated[:0]
+	return nil
+}
+
+func (b *bulkLoader) allocatePage() (uint32, []byte, error) {
+	num, node, err := b.tree.allocatePage()
+	if err == nil {
+		b.allocated = append(b.allocated, num)
+	}
+	return num, node, err
+}
+
+// free 加载失败时当前事务已经回滚，释放之前的批次提交的页。
+// 根节点还没有写入，这些页都不可达
+func (b *bulkLoader) free() error {
+	t := b.tree
+	for _, num := range b.committed {
+		if err := t.freePage(num); err != nil {
+			return t.commit(err)
+		}
+	}
+	b.committed = nil
+	return t.commit(nil)
+}
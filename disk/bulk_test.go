@@ -0,0 +1,139 @@
+package disk
+
+import (
+	"iter"
+	"math/rand"
+	"path/filepath"
+	"slices"
+	"sort"
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sortedRows(keys []uint32) iter.Seq2[uint32, []byte] {
+	return func(yield func(uint32, []byte) bool) {
+		for _, key := range keys {
+			if !yield(key, row(key)) {
+				return
+			}
+		}
+	}
+}
+
+func TestBulkLoad(t *testing.T) {
+	for _, size := range []int{3, 4, 7, 0} {
+		for _, fill := range []float64{0.5, 1} {
+			for _, n := range []int{0, 1, 3, 100, 3000} {
+				keys := make([]uint32, n)
+				for i := range keys {
+					keys[i] = uint32(i * 3)
+				}
+				path := filepath.Join(t.TempDir(), "test.db")
+				options := []Option{MaxLeafCells(size), MaxInternalCells(size), FillFactor(fill), PoolSize(64)}
+				tree, err := BulkLoad(path, sortedRows(keys), options...)
+				require.NoError(t, err)
+				got := verifyTree(t, tree)
+				if n == 0 {
+					assert.Empty(t, got)
+				} else {
+					assert.Equal(t, keys, got)
+				}
+
+				// 除了最后两个叶子节点，其余叶子节点都按填充因子装满
+				per := common.FillCount(fill, int(tree.maxLeafCells/2), int(tree.maxLeafCells))
+				var counts []int
+				pageNum := rootPageNum
+				for getNodeType(readPage(t, tree, pageNum)) == NodeInternal {
+					pageNum = internalNodeChild(readPage(t, tree, pageNum), 0)
+				}
+				for pageNum != 0 {
+					node := readPage(t, tree, pageNum)
+					counts = append(counts, int(leafNodeNumCells(node)))
+					pageNum = leafNodeNextLeaf(node)
+				}
+				for i, count := range counts[:max(len(counts)-2, 0)] {
+					assert.Equal(t, per, count, "leaf %d", i)
+				}
+				require.NoError(t, tree.Close())
+
+				// 重新打开后继续修改
+				tree, err = Open(path, options...)
+				require.NoError(t, err)
+				m := map[uint32]bool{}
+				for _, key := range keys {
+					m[key] = true
+				}
+				r := rand.New(rand.NewSource(int64(n)))
+				for i := 0; i < 500; i++ {
+					key := uint32(r.Intn(3*n + 10))
+					if r.Intn(2) == 0 {
+						_, err := tree.Delete(key)
+						require.NoError(t, err)
+						delete(m, key)
+					} else {
+						require.NoError(t, tree.Insert(key, row(key)))
+						m[key] = true
+					}
+				}
+				want := make([]uint32, 0, len(m))
+				for key := range m {
+					want = append(want, key)
+				}
+				sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+				if len(want) == 0 {
+					assert.Empty(t, verifyTree(t, tree))
+				} else {
+					assert.Equal(t, want, verifyTree(t, tree))
+				}
+				require.NoError(t, tree.Close())
+			}
+		}
+	}
+}
+
+func TestBulkLoad_Errors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	_, err := BulkLoad(path, sortedRows([]uint32{1, 3, 2}))
+	assert.ErrorIs(t, err, ErrNotSorted)
+
+	tree, err := BulkLoad(path, sortedRows([]uint32{1, 2, 3}))
+	require.NoError(t, err)
+	require.NoError(t, tree.Close())
+	_, err = BulkLoad(path, sortedRows([]uint32{4}))
+	assert.ErrorIs(t, err, ErrNotEmpty)
+}
+
+// 出错之前已经提交了几个批次，这些页被释放，之后的加载可以复用
+func TestBulkLoad_FreeOnError(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "test.db")
+	options := []Option{MaxLeafCells(4), MaxInternalCells(4), PoolSize(8)}
+	keys := make([]uint32, 1000)
+	for i := range keys {
+		keys[i] = uint32(i)
+	}
+	_, err := BulkLoad(path, sortedRows(append(slices.Clone(keys), 0)), options...)
+	assert.ErrorIs(err, ErrNotSorted)
+
+	tree, err := Open(path, options...)
+	require.NoError(t, err)
+	numPages := tree.pager.NumPages()
+	assert.Greater(numPages, uint32(100))
+	assert.Equal(numPages-2, tree.pager.FreePages())
+	assert.Empty(verifyTree(t, tree))
+	require.NoError(t, tree.Close())
+
+	// 和加载到新文件时的页数相同
+	fresh, err := BulkLoad(filepath.Join(t.TempDir(), "fresh.db"), sortedRows(keys), options...)
+	require.NoError(t, err)
+	defer fresh.Close()
+	tree, err = BulkLoad(path, sortedRows(keys), options...)
+	require.NoError(t, err)
+	defer tree.Close()
+	assert.Equal(keys, verifyTree(t, tree))
+	assert.Equal(uint32(0), tree.pager.FreePages())
+	assert.Equal(fresh.pager.NumPages(), tree.pager.NumPages())
+}
@@ -0,0 +1,431 @@
+package disk
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+var (
+	ErrKeyTooLarge  = errors.New("disk: key is larger than SlottedMaxKeySize")
+	ErrNotBytesTree = errors.New("disk: file does not hold a BytesTree")
+)
+
+// BytesTree is a b+ tree stored in a file with variable-length keys and values,
+// ordered by bytes.Compare. Pages are slotted: cells of any size are packed from
+// the end of the page and found through a sorted array of cell pointers.
+// A value that does not fit in a cell keeps its prefix in the leaf and the rest
+// in a chain of overflow pages.
+// Separators in internal pages are the shortest keys that still split two leaves,
+// and the prefix shared by the keys of an internal page is stored only once
+type BytesTree struct {
+	options
+	pageSet
+}
+
+// OpenBytes opens the BytesTree stored in path, the file is created if it does not exist.
+// Like Tree, every Put and Delete is committed to the write-ahead log
+func OpenBytes(path string, options ...Option) (*BytesTree, error) {
+	t := &BytesTree{}
+	for _, option := range options {
+		option(&t.options)
+	}
+	p, err := t.open(path, t.options)
+	if err != nil {
+		return nil, err
+	}
+	if p.NumPages() > 1 {
+		if err := t.checkRoot(ErrNotBytesTree, NodeSlottedLeaf, NodeSlottedInternal); err != nil {
+			p.Close()
+			return nil, err
+		}
+		return t, nil
+	}
+	_, root, err := t.allocatePage()
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	initializeSlottedNode(root, NodeSlottedLeaf)
+	p.SetRoot(rootPageNum)
+	if err := t.commit(nil); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// Flush writes all pages back to the file
+func (t *BytesTree) Flush() error {
+	return t.pager.Flush()
+}
+
+// Close flushes the tree and closes the file
+func (t *BytesTree) Close() error {
+	return t.pager.Close()
+}
+
+// Get returns a copy of the value of the key
+func (t *BytesTree) Get(key []byte) ([]byte, bool, error) {
+	defer t.release()
+	_, node, err := t.findLeaf(key)
+	if err != nil {
+		return nil, false, err
+	}
+	idx, ok := slottedLeafFind(node, key)
+	if !ok {
+		return nil, false, nil
+	}
+	value, err := t.readValue(slottedCell(node, idx))
+	return value, err == nil, err
+}
+
+// GetReader returns a reader of the value of the key, the overflow pages of a
+// large value are read as the reader is consumed
+func (t *BytesTree) GetReader(key []byte) (*ValueReader, bool, error) {
+	defer t.release()
+	_, node, err := t.findLeaf(key)
+	if err != nil {
+		return nil, false, err
+	}
+	idx, ok := slottedLeafFind(node, key)
+	if !ok {
+		return nil, false, nil
+	}
+	return newValueReader(t.pager, slottedCell(node, idx)), true, nil
+}
+
+// Put key->value, the value of an existing key is replaced.
+// A key can not be longer than SlottedMaxKeySize, a value can be up to 4GiB
+// and is stored in overflow pages when it does not fit in a cell.
+// All pages of a Put are committed together and stay in memory until the commit
+func (t *BytesTree) Put(key, value []byte) error {
+	return t.PutReader(key, bytes.NewReader(value))
+}
+
+// PutReader is like Put, but reads the value from r until io.EOF
+func (t *BytesTree) PutReader(key []byte, r io.Reader) error {
+	if uintptr(len(key)) > SlottedMaxKeySize {
+		return ErrKeyTooLarge
+	}
+	return t.commit(t.put(key, r))
+}
+
+func (t *BytesTree) put(key []byte, r io.Reader) error {
+	path, _, err := t.findLeaf(key)
+	if err != nil {
+		return err
+	}
+	_, err = t.putIntoLeaf(path, key, r)
+	return err
+}
+
+// putIntoLeaf 写入 path 最后的叶子节点，返回叶子节点是否分裂
+func (t *BytesTree) putIntoLeaf(path []pathEntry, key []byte, r io.Reader) (bool, error) {
+	node, err := t.getWritablePage(path[len(path)-1].pageNum)
+	if err != nil {
+		return false, err
+	}
+	idx, ok := slottedLeafFind(node, key)
+	if ok {
+		if err := t.freeOverflow(slottedLeafCellOverflow(slottedCell(node, idx))); err != nil {
+			return false, err
+		}
+		slottedRemoveCell(node, idx)
+	}
+	// 原来的溢出页已经释放，新的 value 可以复用
+	cell, err := t.newLeafCell(key, r)
+	if err != nil {
+		return false, err
+	}
+	if slottedInsertCell(node, idx, cell) {
+		return false, nil
+	}
+	return true, t.splitLeaf(path, node, idx, cell)
+}
+
+// Delete removes the key, it reports whether the key existed
+func (t *BytesTree) Delete(key []byte) (bool, error) {
+	ok, err := t.delete(key)
+	if err := t.commit(err); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+func (t *BytesTree) delete(key []byte) (bool, error) {
+	path, node, err := t.findLeaf(key)
+	if err != nil {
+		return false, err
+	}
+	return t.deleteFromLeaf(path, node, key)
+}
+
+// deleteFromLeaf 从 path 最后的叶子节点 node 中删除 key，返回 key 是否存在
+func (t *BytesTree) deleteFromLeaf(path []pathEntry, node []byte, key []byte) (bool, error) {
+	idx, ok := slottedLeafFind(node, key)
+	if !ok {
+		return false, nil
+	}
+	node, err := t.getWritablePage(path[len(path)-1].pageNum)
+	if err != nil {
+		return false, err
+	}
+	if err := t.freeOverflow(slottedLeafCellOverflow(slottedCell(node, idx))); err != nil {
+		return false, err
+	}
+	slottedRemoveCell(node, idx)
+	return true, t.rebalance(path, node)
+}
+
+// AscendRange calls fn for every key/value pair in [from, to) in ascending order
+// until fn returns false, a nil to means no upper bound.
+// The slices passed to fn are only valid during the call, values stored in
+// overflow pages are read into new slices
+func (t *BytesTree) AscendRange(from, to []byte, fn func(key, value []byte) bool) error {
+	defer t.release()
+	_, node, err := t.findLeaf(from)
+	if err != nil {
+		return err
+	}
+	idx, _ := slottedLeafFind(node, from)
+	for {
+		for i := idx; i < slottedNumCells(node); i++ {
+			key := slottedLeafKey(node, i)
+			if to != nil && bytes.Compare(key, to) >= 0 {
+				return nil
+			}
+			value := slottedLeafValue(node, i)
+			if cell := slottedCell(node, i); slottedLeafCellOverflow(cell) != 0 {
+				if value, err = t.readValue(cell); err != nil {
+					return err
+				}
+			}
+			if !fn(key, value) {
+				return nil
+			}
+		}
+		next := slottedLink(node)
+		if next == 0 {
+			return nil
+		}
+		// 只 pin 住当前的叶子节点
+		t.release()
+		if node, err = t.getPage(next); err != nil {
+			return err
+		}
+		idx = 0
+	}
+}
+
+// findLeaf 从根节点下降到 key 所在的叶子节点，返回下降路径，
+// 路径最后一项是叶子节点本身
+func (t *BytesTree) findLeaf(key []byte) ([]pathEntry, []byte, error) {
+	pageNum := rootPageNum
+	var path []pathEntry
+	for {
+		node, err := t.getPage(pageNum)
+		if err != nil {
+			return nil, nil, err
+		}
+		if getNodeType(node) == NodeSlottedLeaf {
+			return append(path, pathEntry{pageNum: pageNum}), node, nil
+		}
+		childNum := slottedInternalFindChild(node, key)
+		path = append(path, pathEntry{pageNum: pageNum, childNum: childNum})
+		pageNum = slottedInternalChild(node, childNum)
+	}
+}
+
+// slottedLeafCells 叶子节点所有 cell 的副本
+func slottedLeafCells(node []byte) [][]byte {
+	numCells := slottedNumCells(node)
+	cells := make([][]byte, 0, numCells+1)
+	for i := uint32(0); i < numCells; i++ {
+		cells = append(cells, append([]byte(nil), slottedCell(node, i)...))
+	}
+	return cells
+}
+
+func slottedLeafSpace(cells [][]byte) uintptr {
+	space := uintptr(0)
+	for _, cell := range cells {
+		space += SlottedPointerSize + uintptr(len(cell))
+	}
+	return space
+}
+
+// splitIndex 按字节数平分：返回 [1, len(sizes)-1] 中第一个使前 m 项不少于一半的 m
+func splitIndex(sizes []uintptr) int {
+	total := uintptr(0)
+	for _, size := range sizes {
+		total += size
+	}
+	m, acc := 0, uintptr(0)
+	for m < len(sizes)-1 && (m == 0 || acc < total/2) {
+		acc += sizes[m]
+		m++
+	}
+	return m
+}
+
+// splitLeaf 叶子节点放不下新 cell，将所有 cell 按字节数平分到老节点和新节点中，
+// 两者之间最短的分隔 key 插入到父节点
+func (t *BytesTree) splitLeaf(path []pathEntry, node []byte, idx uint32, cell []byte) error {
+	cells := slottedLeafCells(node)
+	cells = append(cells[:idx], append([][]byte{cell}, cells[idx:]...)...)
+	sizes := make([]uintptr, len(cells))
+	for i, cell := range cells {
+		sizes[i] = SlottedPointerSize + uintptr(len(cell))
+	}
+	m := splitIndex(sizes)
+
+	newPageNum, newNode, err := t.allocatePage()
+	if err != nil {
+		return err
+	}
+	initializeSlottedNode(newNode, NodeSlottedLeaf)
+	setSlottedLink(newNode, slottedLink(node))
+	setSlottedLink(node, newPageNum)
+	if err := writeSlottedLeaf(node, cells[:m]); err != nil {
+		return err
+	}
+	if err := writeSlottedLeaf(newNode, cells[m:]); err != nil {
+		return err
+	}
+	sep := separator(slottedLeafCellKey(cells[m-1]), slottedLeafCellKey(cells[m]))
+	return t.insertIntoParent(path, sep, newPageNum)
+}
+
+// insertIntoParent 路径最后一项的节点分裂成了两个节点，右边节点为 rightPageNum，
+// 其中的 key 都不小于 key。父节点放不下时按字节数分裂，中间的 key 上移
+func (t *BytesTree) insertIntoParent(path []pathEntry, key []byte, rightPageNum uint32) error {
+	leftPageNum := path[len(path)-1].pageNum
+	if leftPageNum == rootPageNum {
+		return t.createNewRoot(key, rightPageNum)
+	}
+	parentEntry := path[len(path)-2]
+	parent, err := t.getWritablePage(parentEntry.pageNum)
+	if err != nil {
+		return err
+	}
+	keys, children := slottedInternalKeys(parent)
+	at := parentEntry.childNum
+	keys = append(keys[:at], append([][]byte{key}, keys[at:]...)...)
+	children = append(children[:at+1], append([]uint32{rightPageNum}, children[at+1:]...)...)
+	if slottedInternalSpace(keys) <= uintptr(len(parent))-SlottedHeaderSize {
+		return writeSlottedInternal(parent, keys, children)
+	}
+
+	sizes := make([]uintptr, len(keys))
+	for i, key := range keys {
+		sizes[i] = SlottedPointerSize + SlottedInternalCellHeaderSize + uintptr(len(key))
+	}
+	mid := min(splitIndex(sizes), len(keys)-2)
+	newPageNum, newNode, err := t.allocatePage()
+	if err != nil {
+		return err
+	}
+	if err := writeSlottedInternal(parent, keys[:mid], children[:mid+1]); err != nil {
+		return err
+	}
+	if err := writeSlottedInternal(newNode, keys[mid+1:], children[mid+1:]); err != nil {
+		return err
+	}
+	return t.insertIntoParent(path[:len(path)-1], keys[mid], newPageNum)
+}
+
+// createNewRoot 根节点分裂：根节点的内容拷贝到新的左节点，
+// 根节点重新初始化为内部节点，指向左右两个孩子
+func (t *BytesTree) createNewRoot(key []byte, rightPageNum uint32) error {
+	root, err := t.getWritablePage(rootPageNum)
+	if err != nil {
+		return err
+	}
+	leftPageNum, left, err := t.allocatePage()
+	if err != nil {
+		return err
+	}
+	copy(left, root)
+	return writeSlottedInternal(root, [][]byte{key}, []uint32{leftPageNum, rightPageNum})
+}
+
+// rebalance 删除后节点使用的空间不足四分之一时，与兄弟节点合并，
+// 合并会从父节点删除一个 key，因此可能需要继续向上调整。
+// cell 是变长的，借一个 cell 可能使父节点中的分隔 key 变长，因此放不下时不做调整
+func (t *BytesTree) rebalance(path []pathEntry, node []byte) error {
+	pageNum := path[len(path)-1].pageNum
+	if pageNum == rootPageNum {
+		return t.adjustRoot(node)
+	}
+	space := uintptr(len(node)) - SlottedHeaderSize
+	if slottedUsedSpace(node) >= space/4 {
+		return nil
+	}
+	parentEntry := path[len(path)-2]
+	parent, err := t.getWritablePage(parentEntry.pageNum)
+	if err != nil {
+		return err
+	}
+	// 优先选择左兄弟，sep 为左右两个节点之间的 key 在父节点中的下标
+	sep := parentEntry.childNum
+	if sep > 0 {
+		sep--
+	}
+	keys, children := slottedInternalKeys(parent)
+	left, err := t.getWritablePage(children[sep])
+	if err != nil {
+		return err
+	}
+	right, err := t.getWritablePage(children[sep+1])
+	if err != nil {
+		return err
+	}
+
+	if getNodeType(node) == NodeSlottedLeaf {
+		cells := append(slottedLeafCells(left), slottedLeafCells(right)...)
+		if slottedLeafSpace(cells) > space {
+			return nil
+		}
+		setSlottedLink(left, slottedLink(right))
+		if err := writeSlottedLeaf(left, cells); err != nil {
+			return err
+		}
+	} else {
+		leftKeys, leftChildren := slottedInternalKeys(left)
+		rightKeys, rightChildren := slottedInternalKeys(right)
+		// 父节点中的 key 下移，连接左右两个节点
+		merged := append(append(leftKeys, keys[sep]), rightKeys...)
+		if slottedInternalSpace(merged) > space {
+			return nil
+		}
+		if err := writeSlottedInternal(left, merged, append(leftChildren, rightChildren...)); err != nil {
+			return err
+		}
+	}
+	if err := t.freePage(children[sep+1]); err != nil {
+		return err
+	}
+	// 左节点接管右节点在父节点中的位置
+	keys = append(keys[:sep], keys[sep+1:]...)
+	children = append(children[:sep+1], children[sep+2:]...)
+	if err := writeSlottedInternal(parent, keys, children); err != nil {
+		return err
+	}
+	return t.rebalance(path[:len(path)-1], parent)
+}
+
+// adjustRoot 根节点为内部节点且只剩一个孩子时，孩子的内容拷贝到根节点，树的高度减一，
+// 孩子的页被释放
+func (t *BytesTree) adjustRoot(root []byte) error {
+	if getNodeType(root) != NodeSlottedInternal || slottedNumCells(root) > 0 {
+		return nil
+	}
+	childPageNum := slottedLink(root)
+	child, err := t.getPage(childPageNum)
+	if err != nil {
+		return err
+	}
+	copy(root, child)
+	return t.freePage(childPageNum)
+}
@@ -0,0 +1,249 @@
+package disk
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readSlottedPage returns a copy of the page without keeping it pinned
+func readSlottedPage(t *testing.T, tree *BytesTree, pageNum uint32) []byte {
+	page, err := tree.pager.Fetch(pageNum)
+	require.NoError(t, err)
+	defer tree.pager.Release(page)
+	return append([]byte(nil), page.Data...)
+}
+
+// verifyBytesTree checks the slotted pages, key order, separators, leaf depth,
+// overflow chains and the sibling chain, it returns the keys in order
+func verifyBytesTree(t *testing.T, tree *BytesTree) []string {
+	var (
+		keys      []string
+		leaves    []uint32
+		leafDepth = -1
+	)
+	// key 在 [lo, hi) 中，nil 表示没有边界
+	var walk func(pageNum uint32, depth int, lo, hi []byte)
+	walk = func(pageNum uint32, depth int, lo, hi []byte) {
+		node := readSlottedPage(t, tree, pageNum)
+		content := uintptr(getUint32(node, SlottedContentOffset))
+		end := slottedPointers(node) + uintptr(slottedNumCells(node))*SlottedPointerSize
+		assert.GreaterOrEqual(t, int(content), int(end), "page %d overlaps", pageNum)
+		if getNodeType(node) == NodeSlottedLeaf {
+			if leafDepth < 0 {
+				leafDepth = depth
+			}
+			assert.Equal(t, leafDepth, depth, "page %d depth", pageNum)
+			for i := uint32(0); i < slottedNumCells(node); i++ {
+				key := slottedLeafKey(node, i)
+				cell := slottedCell(node, i)
+				n := len(slottedLeafCellValue(cell))
+				for num := slottedLeafCellOverflow(cell); num != 0; {
+					page := readSlottedPage(t, tree, num)
+					require.Equal(t, NodeOverflow, getNodeType(page), "page %d type", num)
+					n += len(overflowData(page))
+					num = getUint32(page, OverflowNextOffset)
+				}
+				assert.Equal(t, int(slottedLeafCellValueLen(cell)), n, "page %d key %q value length", pageNum, key)
+				assert.True(t, (lo == nil || bytes.Compare(key, lo) >= 0) && (hi == nil || bytes.Compare(key, hi) < 0),
+					"page %d key %q not in [%q, %q)", pageNum, key, lo, hi)
+				keys = append(keys, string(key))
+			}
+			leaves = append(leaves, pageNum)
+			return
+		}
+		require.Equal(t, NodeSlottedInternal, getNodeType(node), "page %d type", pageNum)
+		numCells := slottedNumCells(node)
+		for i := uint32(0); i <= numCells; i++ {
+			childHi := hi
+			if i < numCells {
+				childHi = slottedInternalKey(node, i)
+				assert.True(t, (lo == nil || bytes.Compare(childHi, lo) > 0) && (hi == nil || bytes.Compare(childHi, hi) < 0),
+					"page %d separator %q not in (%q, %q)", pageNum, childHi, lo, hi)
+			}
+			walk(slottedInternalChild(node, i), depth+1, lo, childHi)
+			lo = childHi
+		}
+	}
+	walk(rootPageNum, 0, nil, nil)
+	assert.True(t, sort.StringsAreSorted(keys))
+
+	for i, pageNum := range leaves {
+		next := uint32(0)
+		if i+1 < len(leaves) {
+			next = leaves[i+1]
+		}
+		assert.Equal(t, next, slottedLink(readSlottedPage(t, tree, pageNum)), "leaf %d next", pageNum)
+	}
+	return keys
+}
+
+func randomBytes(r *rand.Rand, n int) []byte {
+	b := make([]byte, n)
+	r.Read(b)
+	return b
+}
+
+func TestBytesTree_PutGet(t *testing.T) {
+	assert := assert.New(t)
+	tree, err := OpenBytes(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer tree.Close()
+
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("key/%d", i)
+		assert.NoError(tree.Put([]byte(key), []byte(key+"/value")))
+	}
+	for i := 0; i < 5000; i++ {
+		key := fmt.Sprintf("key/%d", i)
+		got, ok, err := tree.Get([]byte(key))
+		assert.NoError(err)
+		assert.True(ok)
+		assert.Equal(key+"/value", string(got))
+	}
+	_, ok, err := tree.Get([]byte("key/"))
+	assert.NoError(err)
+	assert.False(ok)
+	assert.Len(verifyBytesTree(t, tree), 5000)
+
+	// 空的 key 和 value
+	assert.NoError(tree.Put(nil, nil))
+	got, ok, err := tree.Get([]byte{})
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal([]byte{}, got)
+
+	assert.Equal(ErrKeyTooLarge, tree.Put(make([]byte, SlottedMaxKeySize+1), nil))
+	assert.NoError(tree.Put(make([]byte, SlottedMaxKeySize), make([]byte, SlottedMaxCellSize-SlottedLeafCellHeaderSize-SlottedMaxKeySize)))
+}
+
+func TestBytesTree_Random(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tree, err := OpenBytes(filepath.Join(t.TempDir(), "test.db"), PoolSize(64))
+	require.NoError(t, err)
+	defer tree.Close()
+
+	m := map[string][]byte{}
+	for i := 0; i < 5000; i++ {
+		// 长度差别很大的 key 和 value，大部分 key 有相同的前缀
+		key := fmt.Sprintf("%0*d", r.Intn(200)+1, r.Intn(2000))
+		switch r.Intn(3) {
+		case 0:
+			ok, err := tree.Delete([]byte(key))
+			require.NoError(t, err)
+			_, want := m[key]
+			require.Equal(t, want, ok, "step %d delete %q", i, key)
+			delete(m, key)
+		default:
+			size := r.Intn(int(SlottedMaxCellSize-SlottedLeafCellHeaderSize) - len(key))
+			if r.Intn(10) == 0 {
+				size = r.Intn(3 * int(PageSize))
+			}
+			value := randomBytes(r, size)
+			require.NoError(t, tree.Put([]byte(key), value))
+			m[key] = value
+		}
+		if i%500 == 0 {
+			verifyBytesTree(t, tree)
+		}
+	}
+
+	want := make([]string, 0, len(m))
+	for key := range m {
+		want = append(want, key)
+	}
+	sort.Strings(want)
+	assert.Equal(t, want, verifyBytesTree(t, tree))
+
+	var got []string
+	require.NoError(t, tree.AscendRange(nil, nil, func(key, value []byte) bool {
+		assert.Equal(t, m[string(key)], value)
+		got = append(got, string(key))
+		return true
+	}))
+	assert.Equal(t, want, got)
+
+	lo, hi := want[len(want)/4], want[len(want)/2]
+	got = got[:0]
+	require.NoError(t, tree.AscendRange([]byte(lo), []byte(hi), func(key, value []byte) bool {
+		got = append(got, string(key))
+		return true
+	}))
+	assert.Equal(t, want[len(want)/4:len(want)/2], got)
+
+	for key := range m {
+		ok, err := tree.Delete([]byte(key))
+		require.NoError(t, err)
+		require.True(t, ok)
+	}
+	assert.Empty(t, verifyBytesTree(t, tree))
+}
+
+func TestBytesTree_PrefixCompression(t *testing.T) {
+	assert := assert.New(t)
+	tree, err := OpenBytes(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer tree.Close()
+
+	const prefix = "tenants/acme/users/"
+	for i := 0; i < 20000; i++ {
+		key := fmt.Sprintf("%s%08d/profile", prefix, i)
+		require.NoError(t, tree.Put([]byte(key), []byte("v")))
+	}
+	verifyBytesTree(t, tree)
+
+	// 分隔 key 被截断，公共前缀只存一次
+	root := readSlottedPage(t, tree, rootPageNum)
+	require.Equal(t, NodeSlottedInternal, getNodeType(root))
+	child := readSlottedPage(t, tree, slottedInternalChild(root, 0))
+	require.Equal(t, NodeSlottedInternal, getNodeType(child))
+	assert.Contains(string(slottedPrefix(child)), prefix)
+	for i := uint32(0); i < slottedNumCells(child); i++ {
+		key := slottedInternalKey(child, i)
+		assert.Less(len(key), len(prefix)+len("00000000/profile"), "key %q", key)
+		assert.LessOrEqual(len(slottedInternalSuffix(child, i)), 4, "key %q", key)
+	}
+	assert.Greater(int(slottedNumCells(child)), int(SlottedSpaceForCells/(SlottedPointerSize+SlottedInternalCellHeaderSize+uintptr(len(prefix)))))
+}
+
+func TestBytesTree_Reopen(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	tree, err := OpenBytes(path)
+	require.NoError(t, err)
+	for i := 0; i < 3000; i++ {
+		assert.NoError(tree.Put([]byte(fmt.Sprint(i)), bytes.Repeat([]byte{byte(i)}, i%300)))
+	}
+	for i := 0; i < 3000; i += 3 {
+		_, err := tree.Delete([]byte(fmt.Sprint(i)))
+		assert.NoError(err)
+	}
+	require.NoError(t, tree.Close())
+
+	tree, err = OpenBytes(path)
+	require.NoError(t, err)
+	for i := 0; i < 3000; i++ {
+		got, ok, err := tree.Get([]byte(fmt.Sprint(i)))
+		assert.NoError(err)
+		assert.Equal(i%3 != 0, ok, "key %d", i)
+		if ok {
+			assert.Equal(bytes.Repeat([]byte{byte(i)}, i%300), got)
+		}
+	}
+	assert.Len(verifyBytesTree(t, tree), 2000)
+	require.NoError(t, tree.Close())
+
+	other := filepath.Join(t.TempDir(), "tree.db")
+	tr, err := Open(other)
+	require.NoError(t, err)
+	require.NoError(t, tr.Close())
+	_, err = OpenBytes(other)
+	assert.Equal(ErrNotBytesTree, err)
+}
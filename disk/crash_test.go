@@ -0,0 +1,183 @@
+package disk
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errCrash = errors.New("disk: simulated crash")
+
+// crashFile 模拟进程崩溃：所有文件共享写操作的预算，预算耗尽时最后一次写只写一半，
+// 之后的写操作全部失败
+type crashFile struct {
+	*os.File
+	budget *int
+}
+
+func (f *crashFile) crashed() bool {
+	if *f.budget < 0 {
+		return false
+	}
+	if *f.budget == 0 {
+		return true
+	}
+	*f.budget--
+	return false
+}
+
+func (f *crashFile) WriteAt(p []byte, off int64) (int, error) {
+	if *f.budget == 0 {
+		return 0, errCrash
+	}
+	if *f.budget == 1 {
+		*f.budget = 0
+		n, _ := f.File.WriteAt(p[:len(p)/2], off)
+		return n, errCrash
+	}
+	if f.crashed() {
+		return 0, errCrash
+	}
+	return f.File.WriteAt(p, off)
+}
+
+func (f *crashFile) Sync() error {
+	if f.crashed() {
+		return errCrash
+	}
+	return f.File.Sync()
+}
+
+func (f *crashFile) Truncate(size int64) error {
+	if f.crashed() {
+		return errCrash
+	}
+	return f.File.Truncate(size)
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	in, err := os.Open(src)
+	if os.IsNotExist(err) {
+		return
+	}
+	require.NoError(t, err)
+	defer in.Close()
+	out, err := os.Create(dst)
+	require.NoError(t, err)
+	_, err = io.Copy(out, in)
+	require.NoError(t, err)
+	require.NoError(t, out.Close())
+}
+
+// crashAt 在 src 的副本上执行 op 和 Close，第 budget 次写操作时崩溃，
+// 返回副本的路径，以及崩溃是否发生
+func crashAt(t *testing.T, src string, budget int, op func(tree *Tree) error, options ...Option) (string, bool) {
+	path := filepath.Join(t.TempDir(), "crash.db")
+	copyFile(t, src, path)
+	copyFile(t, src+"-wal", path+"-wal")
+
+	remain := -1
+	openFile := func(path string) (common.File, error) {
+		file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return &crashFile{File: file, budget: &remain}, nil
+	}
+	tree, err := Open(path, append(options, withPagerOptions(common.WithOpenFile(openFile)))...)
+	require.NoError(t, err)
+	remain = budget
+	err = op(tree)
+	if cerr := tree.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		require.ErrorIs(t, err, errCrash)
+		return path, true
+	}
+	return path, false
+}
+
+// testCrash 在 op 的每一次写操作处崩溃，重新打开后树必须是完整的，
+// 并且键集合要么是 before，要么是 after
+func testCrash(t *testing.T, src string, before, after []uint32, op func(tree *Tree) error, options ...Option) {
+	for budget := 0; ; budget++ {
+		path, crashed := crashAt(t, src, budget, op, options...)
+
+		tree, err := Open(path, options...)
+		require.NoError(t, err, "budget %d", budget)
+		keys := verifyTree(t, tree)
+		if !crashed {
+			assert.Equal(t, after, keys)
+		} else if len(keys) == len(before) {
+			assert.Equal(t, before, keys, "budget %d", budget)
+		} else {
+			assert.Equal(t, after, keys, "budget %d", budget)
+		}
+		for _, key := range keys {
+			got, ok, err := tree.Search(key)
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, row(key), got[:8])
+		}
+		require.NoError(t, tree.Close())
+		if !crashed {
+			return
+		}
+	}
+}
+
+func TestTree_Crash(t *testing.T) {
+	options := []Option{MaxLeafCells(3), MaxInternalCells(3)}
+	src := filepath.Join(t.TempDir(), "test.db")
+	tree, err := Open(src, options...)
+	require.NoError(t, err)
+
+	var keys []uint32
+	// 键都是偶数，插入奇数时会引起叶子节点和内部节点的分裂
+	for i := uint32(0); i < 40; i += 2 {
+		require.NoError(t, tree.Insert(i, row(i)))
+		keys = append(keys, i)
+	}
+	// 只提交到 WAL，不做 checkpoint，副本打开时需要先恢复
+	src2 := filepath.Join(t.TempDir(), "test.db")
+	copyFile(t, src, src2)
+	copyFile(t, src+"-wal", src2+"-wal")
+	require.NoError(t, tree.Close())
+
+	with := func(keys []uint32, key uint32) []uint32 {
+		keys = append(append([]uint32(nil), keys...), key)
+		sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+		return keys
+	}
+	without := func(keys []uint32, key uint32) []uint32 {
+		var out []uint32
+		for _, k := range keys {
+			if k != key {
+				out = append(out, k)
+			}
+		}
+		return out
+	}
+
+	for _, src := range []string{src, src2} {
+		for _, key := range []uint32{13, 27, 39} {
+			testCrash(t, src, keys, with(keys, key), func(tree *Tree) error {
+				return tree.Insert(key, row(key))
+			}, options...)
+		}
+		for _, key := range []uint32{0, 20, 38} {
+			testCrash(t, src, keys, without(keys, key), func(tree *Tree) error {
+				_, err := tree.Delete(key)
+				return err
+			}, options...)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package disk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Stats describes the pages of a tree file
+type Stats struct {
+	Pages         uint32 // 文件中的页数，包括 header 页
+	FreePages     uint32
+	Height        int
+	InternalPages int
+	LeafPages     int
+	OverflowPages int
+	Keys          int
+	Fill          float64 // 叶子节点的平均使用率
+}
+
+// Stats walks the tree and counts its pages and keys
+func (t *Tree) Stats() (Stats, error) {
+	return t.stats()
+}
+
+// Stats walks the tree and counts its pages and keys
+func (t *BytesTree) Stats() (Stats, error) {
+	return t.stats()
+}
+
+// Check verifies the whole tree: node types, key order, parent pointers,
+// leaf depth, the sibling chain and that every page is either reachable or
+// free. The first problem is returned as an ErrCorrupt error
+func (t *Tree) Check() error {
+	c := &checker{pageSet: &t.pageSet, leafDepth: -1}
+	if err := c.checkNode(rootPageNum, 0, 0, -1, 1<<32); err != nil {
+		return err
+	}
+	return c.finish()
+}
+
+// Check verifies the whole tree: slotted pages, key order, separators, leaf
+// depth, overflow chains, the sibling chain and that every page is either
+// reachable or free. The first problem is returned as an ErrCorrupt error
+func (t *BytesTree) Check() error {
+	c := &checker{pageSet: &t.pageSet, leafDepth: -1}
+	if err := c.checkSlotted(rootPageNum, 0, nil, nil); err != nil {
+		return err
+	}
+	return c.finish()
+}
+
+// WriteDot writes the tree in the Graphviz dot language
+func (t *Tree) WriteDot(w io.Writer) error {
+	return t.writeDot(w)
+}
+
+// WriteDot writes the tree in the Graphviz dot language
+func (t *BytesTree) WriteDot(w io.Writer) error {
+	return t.writeDot(w)
+}
+
+// PrintTree writes an indented dump of the tree in the format of the
+// db_tutorial, internal nodes list their children with the keys in between
+func (t *Tree) PrintTree(w io.Writer) error {
+	return t.printTree(w, rootPageNum, 0)
+}
+
+// PrintTree writes an indented dump of the tree in the format of the
+// db_tutorial, internal nodes list their children with the keys in between
+func (t *BytesTree) PrintTree(w io.Writer) error {
+	return t.printTree(w, rootPageNum, 0)
+}
+
+// readPage 读取页的副本并立即释放，遍历整棵树时不会占满缓冲池
+func (t *pageSet) readPage(num uint32) ([]byte, error) {
+	page, err := t.pager.Fetch(num)
+	if err != nil {
+		return nil, err
+	}
+	defer t.pager.Release(page)
+	return bytes.Clone(page.Data), nil
+}
+
+// treeChildren 内部节点的孩子，其他节点返回 nil
+func treeChildren(node []byte) []uint32 {
+	switch getNodeType(node) {
+	case NodeInternal, NodeSlottedInternal:
+		return pageLinks(node)
+	}
+	return nil
+}
+
+// stats 按层遍历整棵树
+func (t *pageSet) stats() (Stats, error) {
+	s := Stats{Pages: t.pager.NumPages(), FreePages: t.pager.FreePages()}
+	level := []uint32{rootPageNum}
+	for len(level) > 0 {
+		s.Height++
+		var next []uint32
+		for _, num := range level {
+			node, err := t.readPage(num)
+			if err != nil {
+				return s, err
+			}
+			switch getNodeType(node) {
+			case NodeInternal, NodeSlottedInternal:
+				s.InternalPages++
+				next = append(next, treeChildren(node)...)
+			case NodeLeaf:
+				s.LeafPages++
+				s.Keys += int(leafNodeNumCells(node))
+				s.Fill += float64(leafNodeNumCells(node)) / float64(LeafNodeMaxCells)
+			case NodeSlottedLeaf:
+				s.LeafPages++
+				s.Keys += int(slottedNumCells(node))
+				s.Fill += float64(slottedUsedSpace(node)) / float64(SlottedSpaceForCells)
+				for i := uint32(0); i < slottedNumCells(node); i++ {
+					if err := t.countOverflow(slottedLeafCellOverflow(slottedCell(node, i)), &s); err != nil {
+						return s, err
+					}
+				}
+			default:
+				return s, fmt.Errorf("%w: page %d has type %d", ErrCorrupt, num, getNodeType(node))
+			}
+		}
+		level = next
+	}
+	if s.LeafPages > 0 {
+		s.Fill /= float64(s.LeafPages)
+	}
+	return s, nil
+}
+
+// countOverflow 统计从 num 开始的溢出页链表
+func (t *pageSet) countOverflow(num uint32, s *Stats) error {
+	for num != 0 {
+		page, err := t.readPage(num)
+		if err != nil {
+			return err
+		}
+		s.OverflowPages++
+		num = getUint32(page, OverflowNextOffset)
+	}
+	return nil
+}
+
+// checker 检查整棵树，记录访问过的页和叶子节点的顺序
+type checker struct {
+	*pageSet
+	visited   map[uint32]bool
+	leaves    []uint32
+	leafDepth int
+}
+
+func (c *checker) corrupt(num uint32, format string, args ...any) error {
+	return fmt.Errorf("%w: page %d: %s", ErrCorrupt, num, fmt.Sprintf(format, args...))
+}
+
+// visit 读取页，同一页被引用两次时返回错误
+func (c *checker) visit(num uint32) ([]byte, error) {
+	if c.visited == nil {
+		c.visited = make(map[uint32]bool)
+	}
+	if num == 0 || num >= c.pager.NumPages() {
+		return nil, c.corrupt(num, "out of range")
+	}
+	if c.visited[num] {
+		return nil, c.corrupt(num, "referenced twice")
+	}
+	c.visited[num] = true
+	return c.readPage(num)
+}
+
+func (c *checker) checkDepth(num uint32, depth int) error {
+	if c.leafDepth < 0 {
+		c.leafDepth = depth
+	}
+	if depth != c.leafDepth {
+		return c.corrupt(num, "leaf at depth %d, expected %d", depth, c.leafDepth)
+	}
+	c.leaves = append(c.leaves, num)
+	return nil
+}
+
+// checkNode 检查 Tree 的节点，key 在 (lo, hi] 中
+func (c *checker) checkNode(num, parent uint32, depth int, lo, hi int64) error {
+	node, err := c.visit(num)
+	if err != nil {
+		return err
+	}
+	if isNodeRoot(node) != (num == rootPageNum) {
+		return c.corrupt(num, "wrong root flag")
+	}
+	if num != rootPageNum && nodeParent(node) != parent {
+		return c.corrupt(num, "parent %d, expected %d", nodeParent(node), parent)
+	}
+	switch getNodeType(node) {
+	case NodeLeaf:
+		if leafNodeNumCells(node) > uint32(LeafNodeMaxCells) {
+			return c.corrupt(num, "%d cells", leafNodeNumCells(node))
+		}
+		for i := uint32(0); i < leafNodeNumCells(node); i++ {
+			key := int64(leafNodeKey(node, i))
+			if key <= lo || key > hi {
+				return c.corrupt(num, "key %d not in (%d, %d]", key, lo, hi)
+			}
+			lo = key
+		}
+		return c.checkDepth(num, depth)
+	case NodeInternal:
+		numKeys := internalNodeNumKeys(node)
+		if numKeys > uint32(InternalNodeMaxCells) {
+			return c.corrupt(num, "%d keys", numKeys)
+		}
+		for i := uint32(0); i <= numKeys; i++ {
+			childHi := hi
+			if i < numKeys {
+				childHi = int64(internalNodeKey(node, i))
+				if childHi <= lo || childHi > hi {
+					return c.corrupt(num, "key %d not in (%d, %d]", childHi, lo, hi)
+				}
+			}
+			if err := c.checkNode(internalNodeChild(node, i), num, depth+1, lo, childHi); err != nil {
+				return err
+			}
+			lo = childHi
+		}
+		return nil
+	}
+	return c.corrupt(num, "type %d is not a Tree node", getNodeType(node))
+}
+
+// checkSlottedPage 检查 slotted 页的 header 和 cell 指针都在页内，之后才能安全地读取 cell
+func (c *checker) checkSlottedPage(num uint32, node []byte) error {
+	size := uintptr(len(node))
+	content := uintptr(getUint32(node, SlottedContentOffset))
+	end := slottedPointers(node) + uintptr(slottedNumCells(node))*SlottedPointerSize
+	if end > content || content > size {
+		return c.corrupt(num, "cell pointers end at %d, content starts at %d", end, content)
+	}
+	for i := uint32(0); i < slottedNumCells(node); i++ {
+		offset := uintptr(getUint16(node, slottedPointers(node)+uintptr(i)*SlottedPointerSize))
+		if offset < content || offset+SlottedLeafCellHeaderSize > size || offset+slottedCellSize(node, offset) > size {
+			return c.corrupt(num, "cell %d at %d is outside the content", i, offset)
+		}
+	}
+	return nil
+}
+
+// checkSlotted 检查 BytesTree 的节点，key 在 [lo, hi) 中，nil 表示没有边界
+func (c *checker) checkSlotted(num uint32, depth int, lo, hi []byte) error {
+	node, err := c.visit(num)
+	if err != nil {
+		return err
+	}
+	typ := getNodeType(node)
+	if typ != NodeSlottedLeaf && typ != NodeSlottedInternal {
+		return c.corrupt(num, "type %d is not a BytesTree node", typ)
+	}
+	if err := c.checkSlottedPage(num, node); err != nil {
+		return err
+	}
+	inRange := func(key []byte) bool {
+		return (lo == nil || bytes.Compare(key, lo) >= 0) && (hi == nil || bytes.Compare(key, hi) < 0)
+	}
+	if typ == NodeSlottedLeaf {
+		for i := uint32(0); i < slottedNumCells(node); i++ {
+			key := slottedLeafKey(node, i)
+			if !inRange(key) {
+				return c.corrupt(num, "key %q not in [%q, %q)", key, lo, hi)
+			}
+			lo = append(bytes.Clone(key), 0)
+			if err := c.checkOverflow(num, slottedCell(node, i)); err != nil {
+				return err
+			}
+		}
+		return c.checkDepth(num, depth)
+	}
+	numCells := slottedNumCells(node)
+	for i := uint32(0); i <= numCells; i++ {
+		childHi := hi
+		if i < numCells {
+			childHi = slottedInternalKey(node, i)
+			if !inRange(childHi) || lo != nil && bytes.Equal(childHi, lo) {
+				return c.corrupt(num, "separator %q not in (%q, %q)", childHi, lo, hi)
+			}
+		}
+		child := slottedLink(node)
+		if i < numCells {
+			child = slottedInternalChild(node, i)
+		}
+		if err := c.checkSlotted(child, depth+1, lo, childHi); err != nil {
+			return err
+		}
+		lo = childHi
+	}
+	return nil
+}
+
+// checkOverflow 检查 cell 的溢出页链表，链表中的数据加上本地的前缀等于 value 的长度
+func (c *checker) checkOverflow(leaf uint32, cell []byte) error {
+	n := uint64(len(slottedLeafCellValue(cell)))
+	for num := slottedLeafCellOverflow(cell); num != 0; {
+		page, err := c.visit(num)
+		if err != nil {
+			return err
+		}
+		if getNodeType(page) != NodeOverflow {
+			return c.corrupt(num, "type %d is not an overflow page", getNodeType(page))
+		}
+		if getUint32(page, OverflowLenOffset) > uint32(OverflowSpace) {
+			return c.corrupt(num, "overflow length %d", getUint32(page, OverflowLenOffset))
+		}
+		n += uint64(len(overflowData(page)))
+		num = getUint32(page, OverflowNextOffset)
+	}
+	if n != uint64(slottedLeafCellValueLen(cell)) {
+		return c.corrupt(leaf, "value of key %q has %d bytes, expected %d", slottedLeafCellKey(cell), n, slottedLeafCellValueLen(cell))
+	}
+	return nil
+}
+
+// finish 检查叶子节点的兄弟链表，以及所有的页要么可达，要么是空闲页
+func (c *checker) finish() error {
+	for i, num := range c.leaves {
+		node, err := c.readPage(num)
+		if err != nil {
+			return err
+		}
+		next := uint32(0)
+		if i+1 < len(c.leaves) {
+			next = c.leaves[i+1]
+		}
+		link := slottedLink(node)
+		if getNodeType(node) == NodeLeaf {
+			link = leafNodeNextLeaf(node)
+		}
+		if link != next {
+			return c.corrupt(num, "next leaf %d, expected %d", link, next)
+		}
+	}
+	if used := uint32(len(c.visited)) + c.pager.FreePages() + 1; used != c.pager.NumPages() {
+		return fmt.Errorf("%w: %d reachable and %d free pages, the file has %d", ErrCorrupt,
+			len(c.visited), c.pager.FreePages(), c.pager.NumPages()-1)
+	}
+	return nil
+}
+
+// writeDot 按层输出所有的节点，叶子节点之间的兄弟指针用虚线表示
+func (t *pageSet) writeDot(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph btree {\n\tnode [shape=record];\n")
+	level := []uint32{rootPageNum}
+	for len(level) > 0 {
+		var next []uint32
+		b.WriteString("\t{ rank=same;")
+		for _, num := range level {
+			fmt.Fprintf(&b, " n%d;", num)
+		}
+		b.WriteString(" }\n")
+		for _, num := range level {
+			node, err := t.readPage(num)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&b, "\tn%d [label=%s];\n", num, dotLabel(num, nodeKeys(node)))
+			for _, child := range treeChildren(node) {
+				fmt.Fprintf(&b, "\tn%d -> n%d;\n", num, child)
+			}
+			if link := leafLink(node); link != 0 {
+				fmt.Fprintf(&b, "\tn%d -> n%d [style=dashed];\n", num, link)
+			}
+			next = append(next, treeChildren(node)...)
+		}
+		level = next
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// printTree 每一层缩进两个空格
+func (t *pageSet) printTree(w io.Writer, num uint32, level int) error {
+	node, err := t.readPage(num)
+	if err != nil {
+		return err
+	}
+	indent := strings.Repeat("  ", level)
+	keys := nodeKeys(node)
+	children := treeChildren(node)
+	if children == nil {
+		if _, err := fmt.Fprintf(w, "%s- leaf (size %d)\n", indent, len(keys)); err != nil {
+			return err
+		}
+		for _, key := range keys {
+			if _, err := fmt.Fprintf(w, "%s  - %s\n", indent, key); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "%s- internal (size %d)\n", indent, len(keys)); err != nil {
+		return err
+	}
+	for i, child := range children {
+		if err := t.printTree(w, child, level+1); err != nil {
+			return err
+		}
+		if i < len(keys) {
+			if _, err := fmt.Fprintf(w, "%s  - key %s\n", indent, keys[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// nodeKeys 节点中 key 的文本形式
+func nodeKeys(node []byte) []string {
+	var keys []string
+	switch getNodeType(node) {
+	case NodeInternal:
+		for i := uint32(0); i < internalNodeNumKeys(node); i++ {
+			keys = append(keys, strconv.FormatUint(uint64(internalNodeKey(node, i)), 10))
+		}
+	case NodeLeaf:
+		for i := uint32(0); i < leafNodeNumCells(node); i++ {
+			keys = append(keys, strconv.FormatUint(uint64(leafNodeKey(node, i)), 10))
+		}
+	case NodeSlottedInternal:
+		for i := uint32(0); i < slottedNumCells(node); i++ {
+			keys = append(keys, strconv.Quote(string(slottedInternalKey(node, i))))
+		}
+	case NodeSlottedLeaf:
+		for i := uint32(0); i < slottedNumCells(node); i++ {
+			keys = append(keys, strconv.Quote(string(slottedLeafKey(node, i))))
+		}
+	}
+	return keys
+}
+
+// leafLink 叶子节点的兄弟，不是叶子节点时返回 0
+func leafLink(node []byte) uint32 {
+	switch getNodeType(node) {
+	case NodeLeaf:
+		return leafNodeNextLeaf(node)
+	case NodeSlottedLeaf:
+		return slottedLink(node)
+	}
+	return 0
+}
+
+// dotLabel record 节点的标签，第一格是页号，特殊字符需要转义
+func dotLabel(num uint32, keys []string) string {
+	fields := []string{fmt.Sprintf("page %d", num)}
+	for _, key := range keys {
+		fields = append(fields, strings.NewReplacer(
+			`\`, `\\`, `"`, `\"`, `{`, `\{`, `}`, `\}`, `|`, `\|`, `<`, `\<`, `>`, `\>`,
+		).Replace(key))
+	}
+	return `"{` + strings.Join(fields, " | ") + `}"`
+}
@@ -0,0 +1,115 @@
+package disk
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTree_Inspect(t *testing.T) {
+	assert := assert.New(t)
+	tree, err := Open(filepath.Join(t.TempDir(), "test.db"), MaxLeafCells(4), MaxInternalCells(4))
+	require.NoError(t, err)
+	defer tree.Close()
+	for i := uint32(0); i < 500; i++ {
+		require.NoError(t, tree.Insert(i*2, row(i*2)))
+	}
+	for i := uint32(0); i < 500; i += 5 {
+		_, err := tree.Delete(i * 2)
+		require.NoError(t, err)
+	}
+	assert.NoError(tree.Check())
+
+	var keys []uint32
+	assert.NoError(tree.Ascend(501, func(key uint32, row []byte) bool {
+		keys = append(keys, key)
+		return key < 520
+	}))
+	assert.Equal([]uint32{502, 504, 506, 508, 512, 514, 516, 518, 522}, keys)
+
+	stats, err := tree.Stats()
+	require.NoError(t, err)
+	assert.Equal(400, stats.Keys)
+	assert.Equal(tree.pager.NumPages(), stats.Pages)
+	assert.Equal(int(stats.Pages-stats.FreePages-1), stats.InternalPages+stats.LeafPages)
+	assert.Greater(stats.Height, 3)
+	assert.Greater(stats.Fill, 0.0)
+
+	var dot bytes.Buffer
+	require.NoError(t, tree.WriteDot(&dot))
+	assert.True(strings.HasPrefix(dot.String(), "digraph btree {"))
+	assert.Contains(dot.String(), fmt.Sprintf("n%d [label=", rootPageNum))
+	assert.Contains(dot.String(), "[style=dashed]")
+
+	// 叶子节点中的 key 乱序
+	path, leaf, err := tree.findLeaf(100)
+	require.NoError(t, err)
+	leaf, err = tree.getWritablePage(path[len(path)-1].pageNum)
+	require.NoError(t, err)
+	setLeafNodeKey(leaf, 0, 1<<31)
+	require.NoError(t, tree.commit(nil))
+	assert.ErrorIs(tree.Check(), ErrCorrupt)
+}
+
+func TestBytesTree_Inspect(t *testing.T) {
+	assert := assert.New(t)
+	tree, err := OpenBytes(filepath.Join(t.TempDir(), "bytes.db"))
+	require.NoError(t, err)
+	defer tree.Close()
+	for i := 0; i < 3000; i++ {
+		require.NoError(t, tree.Put([]byte(fmt.Sprintf("key|%05d", i)), bytes.Repeat([]byte{'v'}, i%40*50)))
+	}
+	assert.NoError(tree.Check())
+
+	stats, err := tree.Stats()
+	require.NoError(t, err)
+	assert.Equal(3000, stats.Keys)
+	assert.Greater(stats.OverflowPages, 0)
+	assert.Equal(int(stats.Pages-stats.FreePages-1), stats.InternalPages+stats.LeafPages+stats.OverflowPages)
+
+	var dot bytes.Buffer
+	require.NoError(t, tree.WriteDot(&dot))
+	assert.Contains(dot.String(), `\"key\|00000\"`)
+
+	// 释放了一个溢出页，但是 cell 仍然指向它
+	_, leaf, err := tree.findLeaf([]byte("key|00039"))
+	require.NoError(t, err)
+	idx, ok := slottedLeafFind(leaf, []byte("key|00039"))
+	require.True(t, ok)
+	overflow := slottedLeafCellOverflow(slottedCell(leaf, idx))
+	tree.release()
+	require.NotZero(t, overflow)
+	require.NoError(t, tree.freePage(overflow))
+	require.NoError(t, tree.commit(nil))
+	assert.ErrorIs(tree.Check(), ErrCorrupt)
+}
+
+func TestTree_PrintTree(t *testing.T) {
+	tree, err := Open(filepath.Join(t.TempDir(), "test.db"), MaxLeafCells(3), MaxInternalCells(3))
+	require.NoError(t, err)
+	defer tree.Close()
+	for i := uint32(1); i <= 7; i++ {
+		require.NoError(t, tree.Insert(i, row(i)))
+	}
+	var out strings.Builder
+	require.NoError(t, tree.PrintTree(&out))
+	assert.Equal(t, `- internal (size 2)
+  - leaf (size 2)
+    - 1
+    - 2
+  - key 2
+  - leaf (size 2)
+    - 3
+    - 4
+  - key 4
+  - leaf (size 3)
+    - 5
+    - 6
+    - 7
+`, out.String())
+}
@@ -0,0 +1,222 @@
+package disk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/pedrogao/btrees/common"
+)
+
+// common
+var (
+	// PageSize 节点可以使用的页大小，页尾的校验和由 pager 使用
+	PageSize         = uintptr(os.Getpagesize() - common.PageTrailerSize)
+	RowSize  uintptr = 100
+)
+
+type NodeType uint8
+
+const (
+	NodeInternal NodeType = iota + 1
+	NodeLeaf
+	NodeSlottedInternal // BytesTree 的 slotted 页
+	NodeSlottedLeaf
+	NodeOverflow // BytesTree 中放不下的 value
+)
+
+/*
+ * Common Node Header Layout
+ * 1. 节点类型，叶子节点、内部节点, uint8
+ * 2. 是否根节点，uint8
+ * 3. 父节点Id指针，uint32
+ */
+var (
+	NodeTypeSize         = unsafe.Sizeof(uint8(0))
+	NodeTypeOffset       = uintptr(0)
+	IsRootSize           = unsafe.Sizeof(uint8(0))
+	IsRootOffset         = NodeTypeSize
+	ParentPointerSize    = unsafe.Sizeof(uint32(0))
+	ParentPointerOffset  = IsRootOffset + IsRootSize
+	CommonNodeHeaderSize = NodeTypeSize + IsRootSize + ParentPointerSize
+)
+
+/*
+ * Leaf Node Header Layout
+ */
+var (
+	LeafNodeNumCellsSize   = unsafe.Sizeof(uint32(0))
+	LeafNodeNumCellsOffset = CommonNodeHeaderSize
+	LeafNodeNextLeafSize   = unsafe.Sizeof(uint32(0))
+	LeafNodeNextLeafOffset = LeafNodeNumCellsOffset + LeafNodeNumCellsSize
+	LeafNodeHeaderSize     = CommonNodeHeaderSize + LeafNodeNumCellsSize + LeafNodeNextLeafSize
+)
+
+/*
+ * Leaf Node Body Layout
+ */
+var (
+	LeafNodeKeySize               = unsafe.Sizeof(uint32(0))
+	LeafNodeKeyOffset     uintptr = 0
+	LeafNodeValueSize             = RowSize
+	LeafNodeValueOffset           = LeafNodeKeyOffset + LeafNodeKeySize
+	LeafNodeCellSize              = LeafNodeKeySize + LeafNodeValueSize
+	LeafNodeSpaceForCells         = PageSize - LeafNodeHeaderSize
+	LeafNodeMaxCells              = LeafNodeSpaceForCells / LeafNodeCellSize
+)
+
+/*
+ * Internal Node Header Layout
+ */
+var (
+	InternalNodeNumKeysSize      = unsafe.Sizeof(uint32(0))
+	InternalNodeNumKeysOffset    = CommonNodeHeaderSize
+	InternalNodeRightChildSize   = unsafe.Sizeof(uint32(0)) // 右孩子
+	InternalNodeRightChildOffset = InternalNodeNumKeysOffset + InternalNodeNumKeysSize
+	InternalNodeHeaderSize       = CommonNodeHeaderSize + InternalNodeNumKeysSize + InternalNodeRightChildSize
+)
+
+/*
+ * Internal Node Body Layout
+ * 每个 cell 由孩子页号和该孩子中的最大 key 组成，最右边的孩子单独放在 header 中
+ */
+var (
+	InternalNodeKeySize       = unsafe.Sizeof(uint32(0))
+	InternalNodeChildSize     = unsafe.Sizeof(uint32(0))
+	InternalNodeCellSize      = InternalNodeChildSize + InternalNodeKeySize
+	InternalNodeSpaceForCells = PageSize - InternalNodeHeaderSize
+	InternalNodeMaxCells      = InternalNodeSpaceForCells / InternalNodeCellSize
+)
+
+func getUint32(node []byte, offset uintptr) uint32 {
+	return binary.LittleEndian.Uint32(node[offset:])
+}
+
+func putUint32(node []byte, offset uintptr, v uint32) {
+	binary.LittleEndian.PutUint32(node[offset:], v)
+}
+
+func getNodeType(node []byte) NodeType {
+	return NodeType(node[NodeTypeOffset])
+}
+
+func setNodeType(node []byte, typ NodeType) {
+	node[NodeTypeOffset] = uint8(typ)
+}
+
+func isNodeRoot(node []byte) bool {
+	return node[IsRootOffset] == 1
+}
+
+func setNodeRoot(node []byte, isRoot bool) {
+	value := uint8(0)
+	if isRoot {
+		value = 1
+	}
+	node[IsRootOffset] = value
+}
+
+func nodeParent(node []byte) uint32 {
+	return getUint32(node, ParentPointerOffset)
+}
+
+func setNodeParent(node []byte, parent uint32) {
+	putUint32(node, ParentPointerOffset, parent)
+}
+
+func internalNodeNumKeys(node []byte) uint32 {
+	return getUint32(node, InternalNodeNumKeysOffset)
+}
+
+func setInternalNodeNumKeys(node []byte, numKeys uint32) {
+	putUint32(node, InternalNodeNumKeysOffset, numKeys)
+}
+
+func internalNodeRightChild(node []byte) uint32 {
+	return getUint32(node, InternalNodeRightChildOffset)
+}
+
+func setInternalNodeRightChild(node []byte, child uint32) {
+	putUint32(node, InternalNodeRightChildOffset, child)
+}
+
+func internalNodeCell(node []byte, cellNum uint32) uintptr {
+	return InternalNodeHeaderSize + uintptr(cellNum)*InternalNodeCellSize
+}
+
+func internalNodeChild(node []byte, childNum uint32) uint32 {
+	numKeys := internalNodeNumKeys(node)
+	if childNum > numKeys {
+		panic(fmt.Sprintf("tried to access child_num %d > num_keys %d", childNum, numKeys))
+	} else if childNum == numKeys {
+		return internalNodeRightChild(node)
+	} else {
+		return getUint32(node, internalNodeCell(node, childNum))
+	}
+}
+
+func setInternalNodeChild(node []byte, childNum uint32, child uint32) {
+	numKeys := internalNodeNumKeys(node)
+	if childNum > numKeys {
+		panic(fmt.Sprintf("tried to access child_num %d > num_keys %d", childNum, numKeys))
+	} else if childNum == numKeys {
+		setInternalNodeRightChild(node, child)
+	} else {
+		putUint32(node, internalNodeCell(node, childNum), child)
+	}
+}
+
+func internalNodeKey(node []byte, keyNum uint32) uint32 {
+	return getUint32(node, internalNodeCell(node, keyNum)+InternalNodeChildSize)
+}
+
+func setInternalNodeKey(node []byte, keyNum uint32, key uint32) {
+	putUint32(node, internalNodeCell(node, keyNum)+InternalNodeChildSize, key)
+}
+
+func leafNodeNumCells(node []byte) uint32 {
+	return getUint32(node, LeafNodeNumCellsOffset)
+}
+
+func setLeafNodeNumCells(node []byte, numCells uint32) {
+	putUint32(node, LeafNodeNumCellsOffset, numCells)
+}
+
+func leafNodeCell(node []byte, cellNum uint32) []byte {
+	offset := LeafNodeHeaderSize + uintptr(cellNum)*LeafNodeCellSize
+	return node[offset : offset+LeafNodeCellSize]
+}
+
+func leafNodeKey(node []byte, cellNum uint32) uint32 {
+	return getUint32(leafNodeCell(node, cellNum), LeafNodeKeyOffset)
+}
+
+func setLeafNodeKey(node []byte, cellNum uint32, key uint32) {
+	putUint32(leafNodeCell(node, cellNum), LeafNodeKeyOffset, key)
+}
+
+func leafNodeValue(node []byte, cellNum uint32) []byte {
+	return leafNodeCell(node, cellNum)[LeafNodeValueOffset:]
+}
+
+func leafNodeNextLeaf(node []byte) uint32 {
+	return getUint32(node, LeafNodeNextLeafOffset)
+}
+
+func setLeafNodeNextLeaf(node []byte, next uint32) {
+	putUint32(node, LeafNodeNextLeafOffset, next)
+}
+
+func initializeLeafNode(node []byte) {
+	setNodeType(node, NodeLeaf)
+	setNodeRoot(node, false)
+	setLeafNodeNumCells(node, 0)
+	setLeafNodeNextLeaf(node, 0) // 0 represents no sibling
+}
+
+func initializeInternalNode(node []byte) {
+	setNodeType(node, NodeInternal)
+	setNodeRoot(node, false)
+	setInternalNodeNumKeys(node, 0)
+}
@@ -0,0 +1,105 @@
+package disk
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTree_Mmap(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "test.db")
+	options := []Option{Mmap(), PoolSize(64), MaxLeafCells(8), MaxInternalCells(8)}
+
+	// 缓冲池很小，文件增长时映射会多次扩大
+	tree, err := Open(path, options...)
+	require.NoError(t, err)
+	keys := rand.New(rand.NewSource(1)).Perm(5000)
+	for _, key := range keys {
+		require.NoError(t, tree.Insert(uint32(key), row(uint32(key))))
+	}
+	for _, key := range keys[:2000] {
+		ok, err := tree.Delete(uint32(key))
+		require.NoError(t, err)
+		assert.True(ok)
+	}
+	assert.Len(verifyTree(t, tree), 3000)
+	require.NoError(t, tree.Close())
+
+	tree, err = Open(path, options...)
+	require.NoError(t, err)
+	defer tree.Close()
+	for i, key := range keys {
+		got, ok, err := tree.Search(uint32(key))
+		require.NoError(t, err)
+		assert.Equal(i >= 2000, ok, "key %d", key)
+		if ok {
+			assert.Equal(row(uint32(key)), got[:8])
+		}
+	}
+	require.NoError(t, tree.Vacuum())
+	assert.Len(verifyTree(t, tree), 3000)
+}
+
+func TestBytesTree_Mmap(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "bytes.db")
+	tree, err := OpenBytes(path, Mmap(), PoolSize(64))
+	require.NoError(t, err)
+	for i := 0; i < 2000; i++ {
+		require.NoError(t, tree.Put([]byte(fmt.Sprint(i)), bytes.Repeat([]byte{byte(i)}, i%50*100)))
+	}
+	require.NoError(t, tree.Close())
+
+	tree, err = OpenBytes(path, Mmap(), PoolSize(64))
+	require.NoError(t, err)
+	defer tree.Close()
+	for i := 0; i < 2000; i++ {
+		got, ok, err := tree.Get([]byte(fmt.Sprint(i)))
+		require.NoError(t, err)
+		assert.True(ok)
+		assert.Equal(bytes.Repeat([]byte{byte(i)}, i%50*100), got, "key %d", i)
+	}
+	assert.Len(verifyBytesTree(t, tree), 2000)
+}
+
+// BenchmarkTree_Search 缓冲池放不下整棵树时，比较从文件读取和从映射读取
+func BenchmarkTree_Search(b *testing.B) {
+	const n = 200000
+	src := filepath.Join(b.TempDir(), "bench.db")
+	tree, err := BulkLoad(src, func(yield func(uint32, []byte) bool) {
+		for i := uint32(0); i < n; i++ {
+			if !yield(i, row(i)) {
+				return
+			}
+		}
+	})
+	require.NoError(b, err)
+	require.NoError(b, tree.Close())
+
+	for _, bench := range []struct {
+		name    string
+		options []Option
+	}{
+		{"pager", []Option{PoolSize(64)}},
+		{"mmap", []Option{PoolSize(64), Mmap()}},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			tree, err := Open(src, bench.options...)
+			require.NoError(b, err)
+			defer tree.Close()
+			r := rand.New(rand.NewSource(1))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, ok, err := tree.Search(uint32(r.Intn(n))); err != nil || !ok {
+					b.Fatal(ok, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package disk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"unsafe"
+
+	"github.com/pedrogao/btrees/common"
+)
+
+/*
+ * Overflow Page Layout
+ * cell 中放不下的 value 依次存放在溢出页的链表中
+ * 1. 节点类型，uint8
+ * 2. 下一个溢出页，uint32，0 表示最后一页
+ * 3. 本页中数据的字节数，uint32
+ */
+var (
+	OverflowNextSize   = unsafe.Sizeof(uint32(0))
+	OverflowNextOffset = NodeTypeSize
+	OverflowLenSize    = unsafe.Sizeof(uint32(0))
+	OverflowLenOffset  = OverflowNextOffset + OverflowNextSize
+	OverflowHeaderSize = OverflowLenOffset + OverflowLenSize
+	OverflowSpace      = PageSize - OverflowHeaderSize
+)
+
+func overflowData(node []byte) []byte {
+	n := uintptr(getUint32(node, OverflowLenOffset))
+	return node[OverflowHeaderSize : OverflowHeaderSize+n]
+}
+
+// writeOverflow 把 r 中剩余的数据写到新的溢出页链表中，返回第一页和写入的字节数，
+// 超过 limit 时返回 ErrValueTooLarge。写完的页立即 unpin，但是在提交之前仍然占用缓冲池
+func (t *BytesTree) writeOverflow(r io.Reader, limit uint64) (uint32, uint64, error) {
+	var (
+		first uint32
+		n     uint64
+		prev  *common.Page
+	)
+	defer func() {
+		if prev != nil {
+			t.pager.Release(prev)
+		}
+	}()
+	buf := make([]byte, OverflowSpace)
+	for {
+		read, err := io.ReadFull(r, buf)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return 0, 0, err
+		}
+		if read == 0 {
+			return first, n, nil
+		}
+		if n += uint64(read); n > limit {
+			return 0, 0, ErrValueTooLarge
+		}
+		page, aerr := t.pager.Allocate()
+		if aerr != nil {
+			return 0, 0, aerr
+		}
+		setNodeType(page.Data, NodeOverflow)
+		putUint32(page.Data, OverflowLenOffset, uint32(read))
+		copy(page.Data[OverflowHeaderSize:], buf[:read])
+		if prev == nil {
+			first = page.Id
+		} else {
+			putUint32(prev.Data, OverflowNextOffset, page.Id)
+			t.pager.Release(prev)
+		}
+		prev = page
+		if err != nil {
+			return first, n, nil
+		}
+	}
+}
+
+// newLeafCell 读取 value，cell 中放不下时只保留前缀，其余的写到溢出页
+func (t *BytesTree) newLeafCell(key []byte, r io.Reader) ([]byte, error) {
+	buf := make([]byte, SlottedMaxCellSize-SlottedLeafCellHeaderSize-uintptr(len(key))+1)
+	n, err := io.ReadFull(r, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return newSlottedLeafCell(key, buf[:n]), nil
+	} else if err != nil {
+		return nil, err
+	}
+	local := slottedMaxLocal(uintptr(len(key)))
+	rest := io.MultiReader(bytes.NewReader(buf[local:]), r)
+	overflow, written, err := t.writeOverflow(rest, math.MaxUint32-uint64(local))
+	if err != nil {
+		return nil, err
+	}
+	return newSlottedOverflowCell(key, buf[:local], uint32(uint64(local)+written), overflow), nil
+}
+
+// freeOverflow 释放从 first 开始的溢出页链表
+func (t *BytesTree) freeOverflow(first uint32) error {
+	for num := first; num != 0; {
+		page, err := t.pager.Fetch(num)
+		if err != nil {
+			return err
+		}
+		next := getUint32(page.Data, OverflowNextOffset)
+		t.pager.Release(page)
+		if err := t.freePage(num); err != nil {
+			return err
+		}
+		num = next
+	}
+	return nil
+}
+
+// ValueReader reads a value of a BytesTree. The overflow pages of a large value
+// are read one at a time, modifying the tree invalidates the reader
+type ValueReader struct {
+	pager *common.Pager
+	buf   []byte // 当前页中还没有读取的数据
+	next  uint32 // 下一个溢出页
+	size  int
+}
+
+func newValueReader(pager *common.Pager, cell []byte) *ValueReader {
+	return &ValueReader{
+		pager: pager,
+		buf:   append([]byte(nil), slottedLeafCellValue(cell)...),
+		next:  slottedLeafCellOverflow(cell),
+		size:  int(slottedLeafCellValueLen(cell)),
+	}
+}
+
+// Size returns the length of the whole value
+func (r *ValueReader) Size() int {
+	return r.size
+}
+
+func (r *ValueReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.next == 0 {
+			return 0, io.EOF
+		}
+		page, err := r.pager.Fetch(r.next)
+		if err != nil {
+			return 0, err
+		}
+		if getNodeType(page.Data) != NodeOverflow {
+			r.pager.Release(page)
+			return 0, fmt.Errorf("%w: %d is not an overflow page", common.ErrInvalidPage, page.Id)
+		}
+		r.buf = append(r.buf[:0], overflowData(page.Data)...)
+		r.next = getUint32(page.Data, OverflowNextOffset)
+		r.pager.Release(page)
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// readValue 读取 cell 中的 value，包括溢出页中的部分
+func (t *BytesTree) readValue(cell []byte) ([]byte, error) {
+	if slottedLeafCellOverflow(cell) == 0 {
+		return append([]byte{}, slottedLeafCellValue(cell)...), nil
+	}
+	r := newValueReader(t.pager, cell)
+	value := make([]byte, r.Size())
+	if _, err := io.ReadFull(r, value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
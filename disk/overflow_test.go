@@ -0,0 +1,141 @@
+package disk
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// overflowPages 溢出页链表的长度
+func overflowPages(t *testing.T, tree *BytesTree, key []byte) int {
+	_, node, err := tree.findLeaf(key)
+	require.NoError(t, err)
+	idx, ok := slottedLeafFind(node, key)
+	require.True(t, ok)
+	num := slottedLeafCellOverflow(slottedCell(node, idx))
+	tree.release()
+	n := 0
+	for ; num != 0; n++ {
+		page := readSlottedPage(t, tree, num)
+		require.Equal(t, NodeOverflow, getNodeType(page))
+		num = getUint32(page, OverflowNextOffset)
+	}
+	return n
+}
+
+func TestBytesTree_Overflow(t *testing.T) {
+	assert := assert.New(t)
+	r := rand.New(rand.NewSource(1))
+	tree, err := OpenBytes(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer tree.Close()
+
+	// 刚好放得下、刚好放不下，以及跨越多个溢出页的 value
+	inline := int(SlottedMaxCellSize - SlottedLeafCellHeaderSize - 3)
+	sizes := []int{inline, inline + 1, int(OverflowSpace) + 1000, 10 * int(PageSize), 3*int(OverflowSpace) + int(slottedMaxLocal(3))}
+	values := map[string][]byte{}
+	for i, size := range sizes {
+		key := fmt.Sprintf("k%02d", i)
+		values[key] = randomBytes(r, size)
+		require.NoError(t, tree.Put([]byte(key), values[key]))
+	}
+	assert.Equal(0, overflowPages(t, tree, []byte("k00")))
+	assert.Equal(1, overflowPages(t, tree, []byte("k01")))
+	assert.Equal(3, overflowPages(t, tree, []byte("k04")))
+
+	for key, value := range values {
+		got, ok, err := tree.Get([]byte(key))
+		require.NoError(t, err)
+		assert.True(ok)
+		assert.Equal(value, got, "key %s", key)
+
+		reader, ok, err := tree.GetReader([]byte(key))
+		require.NoError(t, err)
+		assert.True(ok)
+		assert.Equal(len(value), reader.Size())
+		got, err = io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(value, got, "key %s", key)
+	}
+	require.NoError(t, tree.AscendRange(nil, nil, func(key, value []byte) bool {
+		assert.Equal(values[string(key)], value)
+		return true
+	}))
+	verifyBytesTree(t, tree)
+}
+
+func TestBytesTree_OverflowReuse(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "test.db")
+	tree, err := OpenBytes(path)
+	require.NoError(t, err)
+
+	big := bytes.Repeat([]byte("0123456789"), 3000)
+	for i := 0; i < 20; i++ {
+		require.NoError(t, tree.Put([]byte(fmt.Sprint(i)), big))
+	}
+	numPages := tree.pager.NumPages()
+
+	// 覆盖和删除释放的溢出页被之后的 Put 复用
+	for i := 0; i < 20; i++ {
+		require.NoError(t, tree.Put([]byte(fmt.Sprint(i)), big[:len(big)-i]))
+	}
+	for i := 0; i < 10; i++ {
+		ok, err := tree.Delete([]byte(fmt.Sprint(i)))
+		require.NoError(t, err)
+		assert.True(ok)
+	}
+	for i := 20; i < 30; i++ {
+		require.NoError(t, tree.PutReader([]byte(fmt.Sprint(i)), bytes.NewReader(big)))
+	}
+	// 每个 value 占 8 个溢出页，新增的只有分裂出来的叶子节点
+	assert.Less(int(tree.pager.NumPages()), int(numPages)+8)
+	require.NoError(t, tree.Close())
+
+	tree, err = OpenBytes(path)
+	require.NoError(t, err)
+	defer tree.Close()
+	for i := 0; i < 30; i++ {
+		got, ok, err := tree.Get([]byte(fmt.Sprint(i)))
+		require.NoError(t, err)
+		assert.Equal(i >= 10, ok, "key %d", i)
+		if i >= 10 && i < 20 {
+			assert.Equal(big[:len(big)-i], got)
+		} else if i >= 20 {
+			assert.Equal(big, got)
+		}
+	}
+}
+
+func TestBytesTree_OverflowRollback(t *testing.T) {
+	assert := assert.New(t)
+	tree, err := OpenBytes(filepath.Join(t.TempDir(), "test.db"), PoolSize(8))
+	require.NoError(t, err)
+	defer tree.Close()
+
+	require.NoError(t, tree.Put([]byte("a"), []byte("small")))
+	numPages := tree.pager.NumPages()
+	// 读取 value 出错时回滚，已经写入的溢出页超过了缓冲池
+	errRead := errors.New("read failed")
+	r := io.MultiReader(bytes.NewReader(make([]byte, 20*int(PageSize))), iotest.ErrReader(errRead))
+	err = tree.PutReader([]byte("a"), r)
+	assert.ErrorIs(err, errRead)
+	assert.Equal(numPages, tree.pager.NumPages())
+	got, ok, err := tree.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.True(ok)
+	assert.Equal([]byte("small"), got)
+
+	require.NoError(t, tree.Put([]byte("a"), make([]byte, 4*int(PageSize))))
+	got, _, err = tree.Get([]byte("a"))
+	require.NoError(t, err)
+	assert.Len(got, 4*int(PageSize))
+}
@@ -0,0 +1,114 @@
+package disk
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/pedrogao/btrees/common"
+)
+
+var ErrCorrupt = errors.New("disk: tree is corrupt")
+
+// pageSet 打开的 pager，以及当前操作 pin 住的页，操作结束时统一释放
+type pageSet struct {
+	pager  *common.Pager
+	pinned map[uint32]*common.Page
+	path   string  // Vacuum 时重新打开
+	opts   options // Vacuum 时重新打开
+}
+
+// open 打开 pager，所有的修改都先提交到 WAL
+func (t *pageSet) open(path string, opts options) (*common.Pager, error) {
+	pagerOptions := append([]common.PagerOption{
+		common.PoolSize(opts.poolSize),
+		common.WithWAL(0),
+	}, opts.pagerOptions...)
+	p, err := common.OpenPager(path, pagerOptions...)
+	if err != nil {
+		return nil, err
+	}
+	t.pager = p
+	t.pinned = make(map[uint32]*common.Page)
+	t.path, t.opts = path, opts
+	return p, nil
+}
+
+// checkRoot 检查已有文件的根节点，header 中记录的根节点页号和根节点的类型都要正确
+func (t *pageSet) checkRoot(notTree error, types ...NodeType) error {
+	if root := t.pager.Root(); root != rootPageNum {
+		return fmt.Errorf("%w: root page %d", ErrCorrupt, root)
+	}
+	defer t.release()
+	root, err := t.getPage(rootPageNum)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(types, getNodeType(root)) {
+		return notTree
+	}
+	return nil
+}
+
+// getPage 读取并 pin 住页，同一次操作中重复读取只 pin 一次
+func (t *pageSet) getPage(num uint32) ([]byte, error) {
+	if page, ok := t.pinned[num]; ok {
+		return page.Data, nil
+	}
+	page, err := t.pager.Fetch(num)
+	if err != nil {
+		return nil, err
+	}
+	t.pinned[num] = page
+	return page.Data, nil
+}
+
+// getWritablePage 读取页，并标记为脏页
+func (t *pageSet) getWritablePage(num uint32) ([]byte, error) {
+	node, err := t.getPage(num)
+	if err != nil {
+		return nil, err
+	}
+	t.pager.Write(t.pinned[num])
+	return node, nil
+}
+
+func (t *pageSet) allocatePage() (uint32, []byte, error) {
+	page, err := t.pager.Allocate()
+	if err != nil {
+		return 0, nil, err
+	}
+	t.pinned[page.Id] = page
+	return page.Id, page.Data, nil
+}
+
+// release 操作结束，释放所有 pin 住的页
+func (t *pageSet) release() {
+	for num, page := range t.pinned {
+		t.pager.Release(page)
+		delete(t.pinned, num)
+	}
+}
+
+// commit 结束一次修改，成功时提交到 WAL，失败时回滚所有修改过的页
+func (t *pageSet) commit(err error) error {
+	t.release()
+	if err != nil {
+		t.pager.Rollback()
+		return err
+	}
+	if err := t.pager.Commit(); err != nil {
+		t.pager.Rollback()
+		return err
+	}
+	return nil
+}
+
+// freePage 释放页，页号可以被之后的 allocatePage 复用
+func (t *pageSet) freePage(num uint32) error {
+	if page, ok := t.pinned[num]; ok {
+		t.pager.Release(page)
+		delete(t.pinned, num)
+	}
+	return t.pager.Delete(num)
+}
@@ -0,0 +1,368 @@
+package disk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"sort"
+	"unsafe"
+)
+
+// errPageFull 重写节点时 cell 放不下，调用者事先检查了空间，出现时说明有 bug
+var errPageFull = errors.New("disk: cells do not fit in the page")
+
+/*
+ * Slotted Page Header Layout
+ * 变长的 cell 从页尾向前存放，header 之后是按 key 排序的 cell 指针数组，
+ * 两者之间是空闲空间，删除 cell 留下的碎片在空间不够时整理
+ * 1. 节点类型，uint8
+ * 2. cell 数量，uint16
+ * 3. cell 内容区的起始位置，uint32
+ * 4. 内容区中的碎片字节数，uint32
+ * 5. 叶子节点为下一个叶子节点，内部节点为最右边的孩子，uint32
+ * 6. 内部节点所有 key 的公共前缀长度，uint16，前缀紧跟在 header 之后，cell 中只存后缀
+ */
+var (
+	SlottedNumCellsSize     = unsafe.Sizeof(uint16(0))
+	SlottedNumCellsOffset   = NodeTypeSize + 1
+	SlottedContentSize      = unsafe.Sizeof(uint32(0))
+	SlottedContentOffset    = SlottedNumCellsOffset + SlottedNumCellsSize
+	SlottedFragmentedSize   = unsafe.Sizeof(uint32(0))
+	SlottedFragmentedOffset = SlottedContentOffset + SlottedContentSize
+	SlottedLinkSize         = unsafe.Sizeof(uint32(0))
+	SlottedLinkOffset       = SlottedFragmentedOffset + SlottedFragmentedSize
+	SlottedPrefixLenSize    = unsafe.Sizeof(uint16(0))
+	SlottedPrefixLenOffset  = SlottedLinkOffset + SlottedLinkSize
+	SlottedHeaderSize       = SlottedPrefixLenOffset + SlottedPrefixLenSize
+	SlottedPointerSize      = unsafe.Sizeof(uint16(0))
+)
+
+/*
+ * Slotted Cell Layout
+ * 叶子节点：key 长度 uint16，value 长度 uint32，key，value
+ * 放不下的 value 只存前缀，之后是第一个溢出页的页号 uint32，cell 正好占 SlottedMaxCellSize
+ * 内部节点：孩子页号 uint32，key 后缀长度 uint16，key 后缀，孩子中的 key 都小于该 key
+ * 一个 cell 加上指针最多占页的四分之一，分裂时两边总能放下
+ */
+var (
+	SlottedKeyLenSize             = unsafe.Sizeof(uint16(0))
+	SlottedValueLenSize           = unsafe.Sizeof(uint32(0))
+	SlottedLeafCellHeaderSize     = SlottedKeyLenSize + SlottedValueLenSize
+	SlottedChildSize              = unsafe.Sizeof(uint32(0))
+	SlottedInternalCellHeaderSize = SlottedChildSize + SlottedKeyLenSize
+	SlottedSpaceForCells          = PageSize - SlottedHeaderSize
+	SlottedMaxCellSize            = SlottedSpaceForCells/4 - SlottedPointerSize
+	SlottedMaxKeySize             = (SlottedMaxCellSize - SlottedLeafCellHeaderSize) / 2
+	SlottedOverflowSize           = unsafe.Sizeof(uint32(0))
+)
+
+func getUint16(node []byte, offset uintptr) uint16 {
+	return binary.LittleEndian.Uint16(node[offset:])
+}
+
+func putUint16(node []byte, offset uintptr, v uint16) {
+	binary.LittleEndian.PutUint16(node[offset:], v)
+}
+
+func initializeSlottedNode(node []byte, typ NodeType) {
+	clear(node[:SlottedHeaderSize])
+	setNodeType(node, typ)
+	putUint32(node, SlottedContentOffset, uint32(len(node)))
+}
+
+func slottedNumCells(node []byte) uint32 {
+	return uint32(getUint16(node, SlottedNumCellsOffset))
+}
+
+func slottedLink(node []byte) uint32 {
+	return getUint32(node, SlottedLinkOffset)
+}
+
+func setSlottedLink(node []byte, link uint32) {
+	putUint32(node, SlottedLinkOffset, link)
+}
+
+func slottedPrefix(node []byte) []byte {
+	n := uintptr(getUint16(node, SlottedPrefixLenOffset))
+	return node[SlottedHeaderSize : SlottedHeaderSize+n]
+}
+
+// slottedPointers cell 指针数组的起始位置
+func slottedPointers(node []byte) uintptr {
+	return SlottedHeaderSize + uintptr(getUint16(node, SlottedPrefixLenOffset))
+}
+
+// slottedCell 第 i 个 cell 的内容
+func slottedCell(node []byte, i uint32) []byte {
+	offset := uintptr(getUint16(node, slottedPointers(node)+uintptr(i)*SlottedPointerSize))
+	return node[offset : offset+slottedCellSize(node, offset)]
+}
+
+func slottedCellSize(node []byte, offset uintptr) uintptr {
+	if getNodeType(node) == NodeSlottedLeaf {
+		keyLen := uintptr(getUint16(node, offset))
+		valueLen := uintptr(getUint32(node, offset+SlottedKeyLenSize))
+		if slottedOverflows(keyLen, valueLen) {
+			return SlottedMaxCellSize
+		}
+		return SlottedLeafCellHeaderSize + keyLen + valueLen
+	}
+	return SlottedInternalCellHeaderSize + uintptr(getUint16(node, offset+SlottedChildSize))
+}
+
+// slottedFreeSpace 空闲空间，包括需要整理才能使用的碎片
+func slottedFreeSpace(node []byte) uintptr {
+	end := slottedPointers(node) + uintptr(slottedNumCells(node))*SlottedPointerSize
+	return uintptr(getUint32(node, SlottedContentOffset)) - end + uintptr(getUint32(node, SlottedFragmentedOffset))
+}
+
+// slottedUsedSpace header 之外已经使用的空间
+func slottedUsedSpace(node []byte) uintptr {
+	return uintptr(len(node)) - SlottedHeaderSize - slottedFreeSpace(node)
+}
+
+// slottedInsertCell 在第 i 个位置插入 cell，空间不够时返回 false
+func slottedInsertCell(node []byte, i uint32, cell []byte) bool {
+	need := uintptr(len(cell)) + SlottedPointerSize
+	if slottedFreeSpace(node) < need {
+		return false
+	}
+	numCells := slottedNumCells(node)
+	pointers := slottedPointers(node)
+	end := pointers + uintptr(numCells)*SlottedPointerSize
+	if uintptr(getUint32(node, SlottedContentOffset))-end < need {
+		slottedCompact(node)
+	}
+	content := uintptr(getUint32(node, SlottedContentOffset)) - uintptr(len(cell))
+	copy(node[content:], cell)
+	putUint32(node, SlottedContentOffset, uint32(content))
+
+	at := pointers + uintptr(i)*SlottedPointerSize
+	copy(node[at+SlottedPointerSize:end+SlottedPointerSize], node[at:end])
+	putUint16(node, at, uint16(content))
+	putUint16(node, SlottedNumCellsOffset, uint16(numCells+1))
+	return true
+}
+
+// slottedRemoveCell 删除第 i 个 cell，cell 占用的空间成为碎片
+func slottedRemoveCell(node []byte, i uint32) {
+	numCells := slottedNumCells(node)
+	pointers := slottedPointers(node)
+	at := pointers + uintptr(i)*SlottedPointerSize
+	offset := uintptr(getUint16(node, at))
+	size := slottedCellSize(node, offset)
+	if offset == uintptr(getUint32(node, SlottedContentOffset)) {
+		putUint32(node, SlottedContentOffset, uint32(offset+size))
+	} else {
+		putUint32(node, SlottedFragmentedOffset, getUint32(node, SlottedFragmentedOffset)+uint32(size))
+	}
+	end := pointers + uintptr(numCells)*SlottedPointerSize
+	copy(node[at:], node[at+SlottedPointerSize:end])
+	putUint16(node, SlottedNumCellsOffset, uint16(numCells-1))
+}
+
+// slottedCompact 按指针顺序把所有 cell 紧凑地排到页尾，消除碎片
+func slottedCompact(node []byte) {
+	numCells := slottedNumCells(node)
+	cells := make([][]byte, numCells)
+	for i := range cells {
+		cells[i] = append([]byte(nil), slottedCell(node, uint32(i))...)
+	}
+	content := uintptr(len(node))
+	pointers := slottedPointers(node)
+	for i, cell := range cells {
+		content -= uintptr(len(cell))
+		copy(node[content:], cell)
+		putUint16(node, pointers+uintptr(i)*SlottedPointerSize, uint16(content))
+	}
+	putUint32(node, SlottedContentOffset, uint32(content))
+	putUint32(node, SlottedFragmentedOffset, 0)
+}
+
+// slottedOverflows value 是否需要溢出页
+func slottedOverflows(keyLen, valueLen uintptr) bool {
+	return SlottedLeafCellHeaderSize+keyLen+valueLen > SlottedMaxCellSize
+}
+
+// slottedMaxLocal 需要溢出页时 cell 中保留的 value 前缀长度
+func slottedMaxLocal(keyLen uintptr) uintptr {
+	return SlottedMaxCellSize - SlottedLeafCellHeaderSize - keyLen - SlottedOverflowSize
+}
+
+func newSlottedLeafCell(key, value []byte) []byte {
+	cell := make([]byte, SlottedLeafCellHeaderSize+uintptr(len(key)+len(value)))
+	putUint16(cell, 0, uint16(len(key)))
+	putUint32(cell, SlottedKeyLenSize, uint32(len(value)))
+	copy(cell[SlottedLeafCellHeaderSize:], key)
+	copy(cell[SlottedLeafCellHeaderSize+uintptr(len(key)):], value)
+	return cell
+}
+
+// newSlottedOverflowCell 长度为 valueLen 的 value，前缀 local 存在 cell 中，其余的从 overflow 页开始
+func newSlottedOverflowCell(key, local []byte, valueLen uint32, overflow uint32) []byte {
+	cell := make([]byte, SlottedLeafCellHeaderSize+uintptr(len(key)+len(local))+SlottedOverflowSize)
+	putUint16(cell, 0, uint16(len(key)))
+	putUint32(cell, SlottedKeyLenSize, valueLen)
+	copy(cell[SlottedLeafCellHeaderSize:], key)
+	copy(cell[SlottedLeafCellHeaderSize+uintptr(len(key)):], local)
+	putUint32(cell, uintptr(len(cell))-SlottedOverflowSize, overflow)
+	return cell
+}
+
+func slottedLeafCellKey(cell []byte) []byte {
+	keyLen := uintptr(getUint16(cell, 0))
+	return cell[SlottedLeafCellHeaderSize : SlottedLeafCellHeaderSize+keyLen]
+}
+
+// slottedLeafCellValueLen value 的总长度，包括溢出页中的部分
+func slottedLeafCellValueLen(cell []byte) uint32 {
+	return getUint32(cell, SlottedKeyLenSize)
+}
+
+// slottedLeafCellValue cell 中的 value，有溢出页时只是前缀
+func slottedLeafCellValue(cell []byte) []byte {
+	value := cell[SlottedLeafCellHeaderSize+uintptr(getUint16(cell, 0)):]
+	if slottedLeafCellOverflow(cell) != 0 {
+		return value[:uintptr(len(value))-SlottedOverflowSize]
+	}
+	return value
+}
+
+// slottedLeafCellOverflow 第一个溢出页，0 表示没有
+func slottedLeafCellOverflow(cell []byte) uint32 {
+	keyLen := uintptr(getUint16(cell, 0))
+	if !slottedOverflows(keyLen, uintptr(slottedLeafCellValueLen(cell))) {
+		return 0
+	}
+	return getUint32(cell, uintptr(len(cell))-SlottedOverflowSize)
+}
+
+func slottedLeafKey(node []byte, i uint32) []byte {
+	return slottedLeafCellKey(slottedCell(node, i))
+}
+
+func slottedLeafValue(node []byte, i uint32) []byte {
+	return slottedLeafCellValue(slottedCell(node, i))
+}
+
+// slottedLeafFind returns the index of the key, or the index to insert the key
+func slottedLeafFind(node []byte, key []byte) (uint32, bool) {
+	numCells := slottedNumCells(node)
+	i := uint32(sort.Search(int(numCells), func(i int) bool {
+		return bytes.Compare(slottedLeafKey(node, uint32(i)), key) >= 0
+	}))
+	return i, i < numCells && bytes.Equal(slottedLeafKey(node, i), key)
+}
+
+// slottedInternalChild 第 i 个孩子，i 等于 cell 数量时为最右边的孩子
+func slottedInternalChild(node []byte, i uint32) uint32 {
+	if i == slottedNumCells(node) {
+		return slottedLink(node)
+	}
+	return getUint32(slottedCell(node, i), 0)
+}
+
+func slottedInternalSuffix(node []byte, i uint32) []byte {
+	return slottedCell(node, i)[SlottedInternalCellHeaderSize:]
+}
+
+// slottedInternalKey 第 i 个 key，由公共前缀和 cell 中的后缀拼接而成
+func slottedInternalKey(node []byte, i uint32) []byte {
+	return append(append([]byte(nil), slottedPrefix(node)...), slottedInternalSuffix(node, i)...)
+}
+
+// slottedInternalFindChild returns the index of the child which should contain the key,
+// the first child whose key is greater than the key
+func slottedInternalFindChild(node []byte, key []byte) uint32 {
+	numCells := slottedNumCells(node)
+	prefix := slottedPrefix(node)
+	n := min(len(prefix), len(key))
+	// 与前缀不同时，key 小于或者大于所有的 key
+	if c := bytes.Compare(key[:n], prefix[:n]); c < 0 || c == 0 && len(key) < len(prefix) {
+		return 0
+	} else if c > 0 {
+		return numCells
+	}
+	suffix := key[len(prefix):]
+	return uint32(sort.Search(int(numCells), func(i int) bool {
+		return bytes.Compare(slottedInternalSuffix(node, uint32(i)), suffix) > 0
+	}))
+}
+
+// slottedInternalKeys 所有的 key 和孩子
+func slottedInternalKeys(node []byte) ([][]byte, []uint32) {
+	numCells := slottedNumCells(node)
+	keys := make([][]byte, 0, numCells+1)
+	children := make([]uint32, 0, numCells+2)
+	for i := uint32(0); i < numCells; i++ {
+		keys = append(keys, slottedInternalKey(node, i))
+		children = append(children, slottedInternalChild(node, i))
+	}
+	return keys, append(children, slottedLink(node))
+}
+
+// commonPrefix keys 的公共前缀长度，keys 是有序的，只需要比较第一个和最后一个
+func commonPrefix(keys [][]byte) int {
+	if len(keys) == 0 {
+		return 0
+	}
+	first, last := keys[0], keys[len(keys)-1]
+	n := 0
+	for n < len(first) && n < len(last) && first[n] == last[n] {
+		n++
+	}
+	return n
+}
+
+// slottedInternalSpace 用 keys 重写内部节点需要的空间，不包括 header
+func slottedInternalSpace(keys [][]byte) uintptr {
+	prefix := commonPrefix(keys)
+	space := uintptr(prefix)
+	for _, key := range keys {
+		space += SlottedPointerSize + SlottedInternalCellHeaderSize + uintptr(len(key)-prefix)
+	}
+	return space
+}
+
+// writeSlottedInternal 用 keys 和 children 重写内部节点，公共前缀只存一次
+func writeSlottedInternal(node []byte, keys [][]byte, children []uint32) error {
+	initializeSlottedNode(node, NodeSlottedInternal)
+	prefix := commonPrefix(keys)
+	if len(keys) > 0 {
+		copy(node[SlottedHeaderSize:], keys[0][:prefix])
+	}
+	putUint16(node, SlottedPrefixLenOffset, uint16(prefix))
+	for i, key := range keys {
+		cell := make([]byte, SlottedInternalCellHeaderSize+uintptr(len(key)-prefix))
+		putUint32(cell, 0, children[i])
+		putUint16(cell, SlottedChildSize, uint16(len(key)-prefix))
+		copy(cell[SlottedInternalCellHeaderSize:], key[prefix:])
+		if !slottedInsertCell(node, uint32(i), cell) {
+			return errPageFull
+		}
+	}
+	setSlottedLink(node, children[len(children)-1])
+	return nil
+}
+
+// writeSlottedLeaf 用 cells 重写叶子节点，保留 next 指针
+func writeSlottedLeaf(node []byte, cells [][]byte) error {
+	next := slottedLink(node)
+	initializeSlottedNode(node, NodeSlottedLeaf)
+	setSlottedLink(node, next)
+	for i, cell := range cells {
+		if !slottedInsertCell(node, uint32(i), cell) {
+			return errPageFull
+		}
+	}
+	return nil
+}
+
+// separator 后缀截断：返回满足 left < s <= right 的最短 s
+func separator(left, right []byte) []byte {
+	n := 0
+	for n < len(left) && n < len(right) && left[n] == right[n] {
+		n++
+	}
+	return append([]byte(nil), right[:n+1]...)
+}
@@ -0,0 +1,87 @@
+package disk
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlotted_InsertRemove(t *testing.T) {
+	assert := assert.New(t)
+	node := make([]byte, PageSize)
+	initializeSlottedNode(node, NodeSlottedLeaf)
+	value := make([]byte, 200)
+
+	// 插满之后删除一半，碎片整理后可以再插入
+	var n uint32
+	for slottedInsertCell(node, n, newSlottedLeafCell([]byte(fmt.Sprintf("key%04d", n)), value)) {
+		n++
+	}
+	assert.Greater(n, uint32(10))
+	for i := uint32(0); i < n/2; i++ {
+		slottedRemoveCell(node, i)
+	}
+	assert.Equal(n-n/2, slottedNumCells(node))
+	for i := uint32(0); i < n/2; i++ {
+		assert.True(slottedInsertCell(node, 0, newSlottedLeafCell([]byte("a000000"), value)))
+	}
+	assert.False(slottedInsertCell(node, 0, newSlottedLeafCell([]byte("a000000"), value)))
+	assert.Equal(n, slottedNumCells(node))
+	assert.Equal([]byte("a000000"), slottedLeafKey(node, 0))
+	for i := n / 2; i < n; i++ {
+		assert.Equal(value, slottedLeafValue(node, i))
+	}
+	assert.Equal(uintptr(len(node))-SlottedHeaderSize, slottedUsedSpace(node)+slottedFreeSpace(node))
+}
+
+func TestSlotted_InternalPrefix(t *testing.T) {
+	assert := assert.New(t)
+	r := rand.New(rand.NewSource(1))
+	node := make([]byte, PageSize)
+	keys := make([][]byte, 50)
+	children := make([]uint32, 51)
+	for i := range keys {
+		keys[i] = []byte(fmt.Sprintf("users/%06d", r.Intn(1000000)))
+		children[i] = uint32(i)
+	}
+	children[50] = 50
+	sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+	require.NoError(t, writeSlottedInternal(node, keys, children))
+	assert.Equal([]byte("users/"), slottedPrefix(node)[:6])
+
+	gotKeys, gotChildren := slottedInternalKeys(node)
+	assert.Equal(keys, gotKeys)
+	assert.Equal(children, gotChildren)
+	for _, key := range [][]byte{nil, []byte("a"), []byte("users"), []byte("users/"), []byte("users/5"), []byte("z"), keys[10]} {
+		want := sort.Search(len(keys), func(i int) bool { return bytes.Compare(keys[i], key) > 0 })
+		assert.Equal(uint32(want), slottedInternalFindChild(node, key), "key %q", key)
+	}
+	assert.Less(int(slottedInternalSpace(keys)), 50*(8+12))
+}
+
+func TestSeparator(t *testing.T) {
+	assert := assert.New(t)
+	assert.Equal([]byte("b"), separator([]byte("apple"), []byte("banana")))
+	assert.Equal([]byte("appli"), separator([]byte("apple"), []byte("application")))
+	assert.Equal([]byte("ab"), separator([]byte("a"), []byte("abc")))
+	assert.Equal([]byte{0}, separator(nil, []byte{0, 1}))
+}
+
+// cell 放不下时返回错误，而不是丢掉 cell
+func TestSlotted_WritePageFull(t *testing.T) {
+	node := make([]byte, PageSize)
+	cell := make([]byte, SlottedMaxCellSize)
+	var cells [][]byte
+	for uintptr(len(cells))*SlottedMaxCellSize < PageSize {
+		cells = append(cells, cell)
+	}
+	assert.ErrorIs(t, writeSlottedLeaf(node, cells), errPageFull)
+
+	keys := [][]byte{bytes.Repeat([]byte("a"), int(PageSize)), bytes.Repeat([]byte("b"), int(PageSize))}
+	assert.ErrorIs(t, writeSlottedInternal(node, keys, []uint32{1, 2, 3}), errPageFull)
+}
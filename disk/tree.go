@@ -0,0 +1,641 @@
+package disk
+
+import (
+	"errors"
+	"sort"
+	"syscall"
+
+	"github.com/pedrogao/btrees/common"
+)
+
+var (
+	ErrValueTooLarge = errors.New("disk: value is larger than a row")
+	ErrNotTree       = errors.New("disk: file does not hold a Tree")
+)
+
+// rootPageNum 根节点总是在 page 1，page 0 是 pager 的 header
+const rootPageNum uint32 = 1
+
+// Tree is a b+ tree stored in a file, using the page layout of the db_tutorial.
+// The root always lives in the same page, keys are uint32 and values are rows of RowSize bytes
+type Tree struct {
+	options
+	pageSet
+}
+
+// options 由 Tree 和 BytesTree 共享，不适用的选项会被忽略
+type options struct {
+	maxLeafCells     uint32
+	maxInternalCells uint32
+	poolSize         int
+	fill             float64
+	pagerOptions     []common.PagerOption
+}
+
+type Option func(opts *options)
+
+// MaxLeafCells limits the cells of a leaf node, it must be at least 2
+// and defaults to LeafNodeMaxCells
+func MaxLeafCells(max int) Option {
+	return func(opts *options) {
+		opts.maxLeafCells = uint32(max)
+	}
+}
+
+// MaxInternalCells limits the keys of an internal node, it must be at least 2
+// and defaults to InternalNodeMaxCells
+func MaxInternalCells(max int) Option {
+	return func(opts *options) {
+		opts.maxInternalCells = uint32(max)
+	}
+}
+
+// PoolSize sets the number of pages cached in memory, pages modified by the
+// current Insert, Delete or WriteBatch are kept on top of it until the commit
+func PoolSize(size int) Option {
+	return func(opts *options) {
+		opts.poolSize = size
+	}
+}
+
+// FillFactor sets how full BulkLoad packs the pages, from (0, 1],
+// it defaults to common.DefaultFillFactor
+func FillFactor(fill float64) Option {
+	return func(opts *options) {
+		opts.fill = fill
+	}
+}
+
+// Mmap reads the pages from a memory mapping of the file instead of copying
+// them into the buffer pool, the kernel is told to expect random access
+func Mmap() Option {
+	return withPagerOptions(common.WithMmap(syscall.MADV_RANDOM))
+}
+
+// withPagerOptions 测试时用于注入出错的文件
+func withPagerOptions(pagerOptions ...common.PagerOption) Option {
+	return func(opts *options) {
+		opts.pagerOptions = append(opts.pagerOptions, pagerOptions...)
+	}
+}
+
+// Open opens the tree stored in path, the file is created if it does not exist.
+// Every Insert and Delete is committed to the write-ahead log in path + "-wal",
+// so a crash never leaves a half-done split or merge in the file
+func Open(path string, options ...Option) (*Tree, error) {
+	t := &Tree{}
+	for _, option := range options {
+		option(&t.options)
+	}
+	p, err := t.open(path, t.options)
+	if err != nil {
+		return nil, err
+	}
+	if t.maxLeafCells < 2 || t.maxLeafCells > uint32(LeafNodeMaxCells) {
+		t.maxLeafCells = uint32(LeafNodeMaxCells)
+	}
+	if t.maxInternalCells < 2 || t.maxInternalCells > uint32(InternalNodeMaxCells) {
+		t.maxInternalCells = uint32(InternalNodeMaxCells)
+	}
+	if p.NumPages() > 1 {
+		if err := t.checkRoot(ErrNotTree, NodeLeaf, NodeInternal); err != nil {
+			p.Close()
+			return nil, err
+		}
+		return t, nil
+	}
+	// 新文件，根节点初始化为空的叶子节点
+	_, root, err := t.allocatePage()
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	initializeLeafNode(root)
+	setNodeRoot(root, true)
+	p.SetRoot(rootPageNum)
+	if err := t.commit(nil); err != nil {
+		p.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+// Flush writes all pages back to the file
+func (t *Tree) Flush() error {
+	return t.pager.Flush()
+}
+
+// Close flushes the tree and closes the file
+func (t *Tree) Close() error {
+	return t.pager.Close()
+}
+
+// Search searches the key in the tree
+// If the key exists, it returns a copy of the row and true
+func (t *Tree) Search(key uint32) ([]byte, bool, error) {
+	defer t.release()
+	_, node, err := t.findLeaf(key)
+	if err != nil {
+		return nil, false, err
+	}
+	idx, ok := leafNodeFind(node, key)
+	if !ok {
+		return nil, false, nil
+	}
+	row := make([]byte, LeafNodeValueSize)
+	copy(row, leafNodeValue(node, idx))
+	return row, true, nil
+}
+
+// Ascend calls fn for every key >= from in ascending order until fn returns false.
+// The row passed to fn is only valid during the call
+func (t *Tree) Ascend(from uint32, fn func(key uint32, row []byte) bool) error {
+	defer t.release()
+	_, node, err := t.findLeaf(from)
+	if err != nil {
+		return err
+	}
+	idx, _ := leafNodeFind(node, from)
+	for {
+		for i := idx; i < leafNodeNumCells(node); i++ {
+			if !fn(leafNodeKey(node, i), leafNodeValue(node, i)) {
+				return nil
+			}
+		}
+		next := leafNodeNextLeaf(node)
+		if next == 0 {
+			return nil
+		}
+		// 只 pin 住当前的叶子节点
+		t.release()
+		if node, err = t.getPage(next); err != nil {
+			return err
+		}
+		idx = 0
+	}
+}
+
+// Insert key->value, the value of an existing key is replaced.
+// Values shorter than RowSize are padded with zeros
+func (t *Tree) Insert(key uint32, value []byte) error {
+	if uintptr(len(value)) > LeafNodeValueSize {
+		return ErrValueTooLarge
+	}
+	return t.commit(t.insert(key, value))
+}
+
+func (t *Tree) insert(key uint32, value []byte) error {
+	path, node, err := t.findLeaf(key)
+	if err != nil {
+		return err
+	}
+	return t.insertIntoLeaf(path, node, key, value)
+}
+
+// insertIntoLeaf 在 findLeaf 返回的叶子节点中插入
+func (t *Tree) insertIntoLeaf(path []pathEntry, node []byte, key uint32, value []byte) error {
+	t.pager.Write(t.pinned[path[len(path)-1].pageNum])
+	idx, ok := leafNodeFind(node, key)
+	if ok {
+		setLeafNodeValue(node, idx, value)
+		return nil
+	}
+	if leafNodeNumCells(node) >= t.maxLeafCells {
+		return t.leafNodeSplitAndInsert(path, node, idx, key, value)
+	}
+	leafNodeInsertAt(node, idx, key, value)
+	return nil
+}
+
+// Delete removes the key, it reports whether the key existed
+func (t *Tree) Delete(key uint32) (bool, error) {
+	ok, err := t.delete(key)
+	if err := t.commit(err); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+func (t *Tree) delete(key uint32) (bool, error) {
+	path, node, err := t.findLeaf(key)
+	if err != nil {
+		return false, err
+	}
+	return t.deleteFromLeaf(path, node, key)
+}
+
+// deleteFromLeaf 从 findLeaf 返回的叶子节点中删除
+func (t *Tree) deleteFromLeaf(path []pathEntry, node []byte, key uint32) (bool, error) {
+	idx, ok := leafNodeFind(node, key)
+	if !ok {
+		return false, nil
+	}
+	t.pager.Write(t.pinned[path[len(path)-1].pageNum])
+	leafNodeRemoveAt(node, idx)
+	return true, t.rebalance(path, node)
+}
+
+// pathEntry 记录从根节点下降时经过的内部节点，以及选择的孩子下标
+type pathEntry struct {
+	pageNum  uint32
+	childNum uint32
+}
+
+// findLeaf 从根节点下降到 key 所在的叶子节点，返回下降路径，
+// 路径最后一项是叶子节点本身
+func (t *Tree) findLeaf(key uint32) ([]pathEntry, []byte, error) {
+	pageNum := rootPageNum
+	var path []pathEntry
+	for {
+		node, err := t.getPage(pageNum)
+		if err != nil {
+			return nil, nil, err
+		}
+		if getNodeType(node) == NodeLeaf {
+			return append(path, pathEntry{pageNum: pageNum}), node, nil
+		}
+		childNum := internalNodeFindChild(node, key)
+		path = append(path, pathEntry{pageNum: pageNum, childNum: childNum})
+		pageNum = internalNodeChild(node, childNum)
+	}
+}
+
+// leafNodeFind returns the index of the key, or the index to insert the key
+func leafNodeFind(node []byte, key uint32) (uint32, bool) {
+	numCells := leafNodeNumCells(node)
+	i := uint32(sort.Search(int(numCells), func(i int) bool {
+		return leafNodeKey(node, uint32(i)) >= key
+	}))
+	return i, i < numCells && leafNodeKey(node, i) == key
+}
+
+// internalNodeFindChild returns the index of the child which should contain the key
+func internalNodeFindChild(node []byte, key uint32) uint32 {
+	numKeys := internalNodeNumKeys(node)
+	return uint32(sort.Search(int(numKeys), func(i int) bool {
+		return internalNodeKey(node, uint32(i)) >= key
+	}))
+}
+
+func setLeafNodeValue(node []byte, cellNum uint32, value []byte) {
+	dst := leafNodeValue(node, cellNum)
+	n := copy(dst, value)
+	clear(dst[n:])
+}
+
+func leafNodeInsertAt(node []byte, cellNum uint32, key uint32, value []byte) {
+	numCells := leafNodeNumCells(node)
+	if cellNum < numCells {
+		start := LeafNodeHeaderSize + uintptr(cellNum)*LeafNodeCellSize
+		end := LeafNodeHeaderSize + uintptr(numCells)*LeafNodeCellSize
+		copy(node[start+LeafNodeCellSize:], node[start:end])
+	}
+	setLeafNodeNumCells(node, numCells+1)
+	setLeafNodeKey(node, cellNum, key)
+	setLeafNodeValue(node, cellNum, value)
+}
+
+func leafNodeRemoveAt(node []byte, cellNum uint32) {
+	numCells := leafNodeNumCells(node)
+	start := LeafNodeHeaderSize + uintptr(cellNum)*LeafNodeCellSize
+	end := LeafNodeHeaderSize + uintptr(numCells)*LeafNodeCellSize
+	copy(node[start:], node[start+LeafNodeCellSize:end])
+	setLeafNodeNumCells(node, numCells-1)
+}
+
+// internalNodeInsertAt inserts the cell (child, key) before cell cellNum
+func internalNodeInsertAt(node []byte, cellNum uint32, child, key uint32) {
+	numKeys := internalNodeNumKeys(node)
+	if cellNum < numKeys {
+		start := internalNodeCell(node, cellNum)
+		end := internalNodeCell(node, numKeys)
+		copy(node[start+InternalNodeCellSize:], node[start:end])
+	}
+	setInternalNodeNumKeys(node, numKeys+1)
+	setInternalNodeChild(node, cellNum, child)
+	setInternalNodeKey(node, cellNum, key)
+}
+
+// internalNodeRemoveAt removes the cell cellNum, the right child is untouched
+func internalNodeRemoveAt(node []byte, cellNum uint32) {
+	numKeys := internalNodeNumKeys(node)
+	start := internalNodeCell(node, cellNum)
+	end := internalNodeCell(node, numKeys)
+	copy(node[start:], node[start+InternalNodeCellSize:end])
+	setInternalNodeNumKeys(node, numKeys-1)
+}
+
+// leafNodeSplitAndInsert 叶子节点已满，将已有的 cell 和新 cell 平分到老节点和新节点中，
+// 然后把新节点插入到父节点
+func (t *Tree) leafNodeSplitAndInsert(path []pathEntry, oldNode []byte, cellNum uint32, key uint32, value []byte) error {
+	newPageNum, newNode, err := t.allocatePage()
+	if err != nil {
+		return err
+	}
+	initializeLeafNode(newNode)
+	setNodeParent(newNode, nodeParent(oldNode))
+	setLeafNodeNextLeaf(newNode, leafNodeNextLeaf(oldNode))
+	setLeafNodeNextLeaf(oldNode, newPageNum)
+
+	// 先把所有 cell（包括新 cell）按顺序收集起来
+	numCells := leafNodeNumCells(oldNode)
+	cells := make([][]byte, 0, numCells+1)
+	for i := uint32(0); i < numCells; i++ {
+		if i == cellNum {
+			cells = append(cells, nil)
+		}
+		cells = append(cells, append([]byte(nil), leafNodeCell(oldNode, i)...))
+	}
+	if cellNum == numCells {
+		cells = append(cells, nil)
+	}
+	cell := make([]byte, LeafNodeCellSize)
+	putUint32(cell, LeafNodeKeyOffset, key)
+	copy(cell[LeafNodeValueOffset:], value)
+	cells[cellNum] = cell
+
+	rightCount := uint32(len(cells)) / 2
+	leftCount := uint32(len(cells)) - rightCount
+	for i, cell := range cells {
+		if uint32(i) < leftCount {
+			copy(leafNodeCell(oldNode, uint32(i)), cell)
+		} else {
+			copy(leafNodeCell(newNode, uint32(i)-leftCount), cell)
+		}
+	}
+	setLeafNodeNumCells(oldNode, leftCount)
+	setLeafNodeNumCells(newNode, rightCount)
+
+	return t.insertIntoParent(path, leafNodeKey(oldNode, leftCount-1), newPageNum)
+}
+
+// insertIntoParent 路径最后一项的节点分裂成了两个节点，左边节点的最大 key 为 key，
+// 右边节点为 rightPageNum，将右边节点插入到父节点中
+func (t *Tree) insertIntoParent(path []pathEntry, key uint32, rightPageNum uint32) error {
+	leftPageNum := path[len(path)-1].pageNum
+	if leftPageNum == rootPageNum {
+		return t.createNewRoot(key, rightPageNum)
+	}
+	parentEntry := path[len(path)-2]
+	parent, err := t.getWritablePage(parentEntry.pageNum)
+	if err != nil {
+		return err
+	}
+	if internalNodeNumKeys(parent) >= t.maxInternalCells {
+		return t.internalNodeSplitAndInsert(path[:len(path)-1], parent, key, rightPageNum)
+	}
+	// 左节点原来的上界留给右节点，左节点的上界变为 key
+	internalNodeInsertAt(parent, parentEntry.childNum, leftPageNum, key)
+	setInternalNodeChild(parent, parentEntry.childNum+1, rightPageNum)
+	return nil
+}
+
+// internalNodeSplitAndInsert 内部节点已满，插入新孩子后平分成两个节点，
+// 中间的 key 上移到父节点
+func (t *Tree) internalNodeSplitAndInsert(path []pathEntry, oldNode []byte, key uint32, rightPageNum uint32) error {
+	entry := path[len(path)-1]
+	numKeys := internalNodeNumKeys(oldNode)
+	keys := make([]uint32, 0, numKeys+1)
+	children := make([]uint32, 0, numKeys+2)
+	for i := uint32(0); i < numKeys; i++ {
+		keys = append(keys, internalNodeKey(oldNode, i))
+		children = append(children, internalNodeChild(oldNode, i))
+	}
+	children = append(children, internalNodeRightChild(oldNode))
+	keys = append(keys[:entry.childNum], append([]uint32{key}, keys[entry.childNum:]...)...)
+	children = append(children[:entry.childNum+1], append([]uint32{rightPageNum}, children[entry.childNum+1:]...)...)
+
+	newPageNum, newNode, err := t.allocatePage()
+	if err != nil {
+		return err
+	}
+	initializeInternalNode(newNode)
+	setNodeParent(newNode, nodeParent(oldNode))
+
+	mid := uint32(len(keys)) / 2
+	t.writeInternalNode(oldNode, entry.pageNum, keys[:mid], children[:mid+1])
+	t.writeInternalNode(newNode, newPageNum, keys[mid+1:], children[mid+1:])
+	if err := t.setParents(children, entry.pageNum, newPageNum, mid+1); err != nil {
+		return err
+	}
+	return t.insertIntoParent(path, keys[mid], newPageNum)
+}
+
+func (t *Tree) writeInternalNode(node []byte, pageNum uint32, keys, children []uint32) {
+	setInternalNodeNumKeys(node, uint32(len(keys)))
+	for i, key := range keys {
+		setInternalNodeChild(node, uint32(i), children[i])
+		setInternalNodeKey(node, uint32(i), key)
+	}
+	setInternalNodeRightChild(node, children[len(children)-1])
+}
+
+// setParents 前 split 个孩子的父节点为 left，其余为 right。
+// 孩子可能很多，本次操作没有用到的孩子改完后立即释放，避免占满缓冲池
+func (t *Tree) setParents(children []uint32, left, right uint32, split uint32) error {
+	for i, child := range children {
+		parent := right
+		if uint32(i) < split {
+			parent = left
+		}
+		if page, ok := t.pinned[child]; ok {
+			t.pager.Write(page)
+			setNodeParent(page.Data, parent)
+			continue
+		}
+		page, err := t.pager.Fetch(child)
+		if err != nil {
+			return err
+		}
+		t.pager.Write(page)
+		setNodeParent(page.Data, parent)
+		t.pager.Release(page)
+	}
+	return nil
+}
+
+// createNewRoot 根节点分裂：根节点的内容拷贝到新的左节点，
+// 根节点重新初始化为内部节点，指向左右两个孩子
+func (t *Tree) createNewRoot(key uint32, rightPageNum uint32) error {
+	root, err := t.getWritablePage(rootPageNum)
+	if err != nil {
+		return err
+	}
+	leftPageNum, left, err := t.allocatePage()
+	if err != nil {
+		return err
+	}
+	copy(left, root)
+	setNodeRoot(left, false)
+	if getNodeType(left) == NodeInternal {
+		if err := t.setParents(internalNodeChildren(left), leftPageNum, leftPageNum, 0); err != nil {
+			return err
+		}
+	}
+
+	initializeInternalNode(root)
+	setNodeRoot(root, true)
+	setInternalNodeNumKeys(root, 1)
+	setInternalNodeChild(root, 0, leftPageNum)
+	setInternalNodeKey(root, 0, key)
+	setInternalNodeRightChild(root, rightPageNum)
+	setNodeParent(left, rootPageNum)
+
+	right, err := t.getWritablePage(rightPageNum)
+	if err != nil {
+		return err
+	}
+	setNodeParent(right, rootPageNum)
+	return nil
+}
+
+func internalNodeChildren(node []byte) []uint32 {
+	numKeys := internalNodeNumKeys(node)
+	children := make([]uint32, 0, numKeys+1)
+	for i := uint32(0); i <= numKeys; i++ {
+		children = append(children, internalNodeChild(node, i))
+	}
+	return children
+}
+
+func (t *Tree) nodeSize(node []byte) uint32 {
+	if getNodeType(node) == NodeLeaf {
+		return leafNodeNumCells(node)
+	}
+	return internalNodeNumKeys(node)
+}
+
+func (t *Tree) minSize(node []byte) uint32 {
+	if getNodeType(node) == NodeLeaf {
+		return t.maxLeafCells / 2
+	}
+	return t.maxInternalCells / 2
+}
+
+// rebalance 删除后节点不足半满时，向兄弟节点借一个 cell 或者与兄弟节点合并，
+// 合并会从父节点删除一个 key，因此可能需要继续向上调整
+func (t *Tree) rebalance(path []pathEntry, node []byte) error {
+	pageNum := path[len(path)-1].pageNum
+	if pageNum == rootPageNum {
+		return t.adjustRoot(node)
+	}
+	if t.nodeSize(node) >= t.minSize(node) {
+		return nil
+	}
+	parentEntry := path[len(path)-2]
+	parent, err := t.getWritablePage(parentEntry.pageNum)
+	if err != nil {
+		return err
+	}
+	// 优先选择左兄弟，sep 为左右两个节点之间的 key 在父节点中的下标
+	sep := parentEntry.childNum
+	if sep > 0 {
+		sep--
+	}
+	leftPageNum := internalNodeChild(parent, sep)
+	rightPageNum := internalNodeChild(parent, sep+1)
+	left, err := t.getWritablePage(leftPageNum)
+	if err != nil {
+		return err
+	}
+	right, err := t.getWritablePage(rightPageNum)
+	if err != nil {
+		return err
+	}
+
+	if getNodeType(node) == NodeLeaf {
+		leftCells, rightCells := leafNodeNumCells(left), leafNodeNumCells(right)
+		if leftCells+rightCells <= t.maxLeafCells {
+			for i := uint32(0); i < rightCells; i++ {
+				copy(leafNodeCell(left, leftCells+i), leafNodeCell(right, i))
+			}
+			setLeafNodeNumCells(left, leftCells+rightCells)
+			setLeafNodeNextLeaf(left, leafNodeNextLeaf(right))
+			if err := t.freePage(rightPageNum); err != nil {
+				return err
+			}
+			return t.removeFromParent(path[:len(path)-1], parent, sep, leftPageNum)
+		}
+		if pageNum == leftPageNum {
+			// 右兄弟的第一个 cell 移到左节点末尾
+			leafNodeInsertAt(left, leftCells, leafNodeKey(right, 0), leafNodeValue(right, 0))
+			leafNodeRemoveAt(right, 0)
+		} else {
+			// 左兄弟的最后一个 cell 移到右节点开头
+			leafNodeInsertAt(right, 0, leafNodeKey(left, leftCells-1), leafNodeValue(left, leftCells-1))
+			leafNodeRemoveAt(left, leftCells-1)
+		}
+		setInternalNodeKey(parent, sep, leafNodeKey(left, leafNodeNumCells(left)-1))
+		return nil
+	}
+
+	leftKeys, rightKeys := internalNodeNumKeys(left), internalNodeNumKeys(right)
+	sepKey := internalNodeKey(parent, sep)
+	if leftKeys+rightKeys+1 <= t.maxInternalCells {
+		// 左节点的右孩子和父节点中的 key 下移成为一个 cell，再拼上右节点
+		children := internalNodeChildren(right)
+		internalNodeInsertAt(left, leftKeys, internalNodeRightChild(left), sepKey)
+		for i := uint32(0); i < rightKeys; i++ {
+			internalNodeInsertAt(left, leftKeys+1+i, internalNodeChild(right, i), internalNodeKey(right, i))
+		}
+		setInternalNodeRightChild(left, internalNodeRightChild(right))
+		if err := t.setParents(children, leftPageNum, leftPageNum, 0); err != nil {
+			return err
+		}
+		if err := t.freePage(rightPageNum); err != nil {
+			return err
+		}
+		return t.removeFromParent(path[:len(path)-1], parent, sep, leftPageNum)
+	}
+	var moved uint32
+	if pageNum == leftPageNum {
+		// 右兄弟的第一个孩子经由父节点旋转到左节点
+		moved = internalNodeChild(right, 0)
+		internalNodeInsertAt(left, leftKeys, internalNodeRightChild(left), sepKey)
+		setInternalNodeRightChild(left, moved)
+		setInternalNodeKey(parent, sep, internalNodeKey(right, 0))
+		internalNodeRemoveAt(right, 0)
+		err = t.setParents([]uint32{moved}, leftPageNum, leftPageNum, 0)
+	} else {
+		// 左兄弟的右孩子经由父节点旋转到右节点
+		moved = internalNodeRightChild(left)
+		internalNodeInsertAt(right, 0, moved, sepKey)
+		setInternalNodeKey(parent, sep, internalNodeKey(left, leftKeys-1))
+		setInternalNodeRightChild(left, internalNodeChild(left, leftKeys-1))
+		internalNodeRemoveAt(left, leftKeys-1)
+		err = t.setParents([]uint32{moved}, rightPageNum, rightPageNum, 0)
+	}
+	return err
+}
+
+// removeFromParent 右节点合并进左节点并被释放后，从父节点中删除两者之间的 key，
+// 左节点接管右节点在父节点中的位置
+func (t *Tree) removeFromParent(path []pathEntry, parent []byte, sep uint32, leftPageNum uint32) error {
+	setInternalNodeChild(parent, sep+1, leftPageNum)
+	internalNodeRemoveAt(parent, sep)
+	return t.rebalance(path, parent)
+}
+
+// adjustRoot 根节点为内部节点且只剩一个孩子时，孩子的内容拷贝到根节点，树的高度减一，
+// 孩子的页被释放
+func (t *Tree) adjustRoot(root []byte) error {
+	if getNodeType(root) != NodeInternal || internalNodeNumKeys(root) > 0 {
+		return nil
+	}
+	childPageNum := internalNodeRightChild(root)
+	child, err := t.getPage(childPageNum)
+	if err != nil {
+		return err
+	}
+	t.pager.Write(t.pinned[rootPageNum])
+	copy(root, child)
+	if err := t.freePage(childPageNum); err != nil {
+		return err
+	}
+	setNodeRoot(root, true)
+	setNodeParent(root, 0)
+	if getNodeType(root) == NodeInternal {
+		return t.setParents(internalNodeChildren(root), rootPageNum, rootPageNum, 0)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package disk
+
+import (
+	"encoding/binary"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func row(key uint32) []byte {
+	value := make([]byte, 8)
+	binary.LittleEndian.PutUint32(value, key)
+	binary.LittleEndian.PutUint32(value[4:], ^key)
+	return value
+}
+
+// readPage returns a copy of the page without keeping it pinned
+func readPage(t *testing.T, tree *Tree, pageNum uint32) []byte {
+	page, err := tree.pager.Fetch(pageNum)
+	require.NoError(t, err)
+	defer tree.pager.Release(page)
+	return append([]byte(nil), page.Data...)
+}
+
+// verifyTree checks parent pointers, key order, occupancy, leaf depth and
+// the sibling chain, it returns the keys in order
+func verifyTree(t *testing.T, tree *Tree) []uint32 {
+	var (
+		keys      []uint32
+		leaves    []uint32
+		leafDepth = -1
+	)
+	var walk func(pageNum, parent uint32, depth int, lo, hi int64)
+	walk = func(pageNum, parent uint32, depth int, lo, hi int64) {
+		node := readPage(t, tree, pageNum)
+		assert.Equal(t, pageNum == rootPageNum, isNodeRoot(node), "page %d root flag", pageNum)
+		if pageNum != rootPageNum {
+			assert.Equal(t, parent, nodeParent(node), "page %d parent", pageNum)
+			assert.GreaterOrEqual(t, tree.nodeSize(node), tree.minSize(node), "page %d size", pageNum)
+		}
+		if getNodeType(node) == NodeLeaf {
+			if leafDepth < 0 {
+				leafDepth = depth
+			}
+			assert.Equal(t, leafDepth, depth, "page %d depth", pageNum)
+			assert.LessOrEqual(t, leafNodeNumCells(node), tree.maxLeafCells)
+			for i := uint32(0); i < leafNodeNumCells(node); i++ {
+				key := int64(leafNodeKey(node, i))
+				assert.True(t, key > lo && key <= hi, "page %d key %d not in (%d, %d]", pageNum, key, lo, hi)
+				keys = append(keys, uint32(key))
+			}
+			leaves = append(leaves, pageNum)
+			return
+		}
+		numKeys := internalNodeNumKeys(node)
+		assert.LessOrEqual(t, numKeys, tree.maxInternalCells)
+		for i := uint32(0); i <= numKeys; i++ {
+			childHi := hi
+			if i < numKeys {
+				childHi = int64(internalNodeKey(node, i))
+			}
+			walk(internalNodeChild(node, i), pageNum, depth+1, lo, childHi)
+			lo = childHi
+		}
+	}
+	walk(rootPageNum, 0, 0, -1, 1<<32)
+	assert.True(t, sort.SliceIsSorted(keys, func(i, j int) bool { return keys[i] < keys[j] }))
+
+	for i, pageNum := range leaves {
+		node := readPage(t, tree, pageNum)
+		next := uint32(0)
+		if i+1 < len(leaves) {
+			next = leaves[i+1]
+		}
+		assert.Equal(t, next, leafNodeNextLeaf(node), "leaf %d next", pageNum)
+	}
+	return keys
+}
+
+func TestTree_InsertSearch(t *testing.T) {
+	assert := assert.New(t)
+	tree, err := Open(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer tree.Close()
+
+	count := uint32(5000)
+	for i := uint32(1); i <= count; i++ {
+		assert.NoError(tree.Insert(i, row(i)))
+	}
+	for i := uint32(1); i <= count; i++ {
+		got, ok, err := tree.Search(i)
+		assert.NoError(err)
+		assert.True(ok)
+		assert.Equal(row(i), got[:8])
+		assert.Len(got, int(RowSize))
+	}
+	_, ok, err := tree.Search(count + 1)
+	assert.NoError(err)
+	assert.False(ok)
+
+	assert.Len(verifyTree(t, tree), int(count))
+
+	assert.Equal(ErrValueTooLarge, tree.Insert(1, make([]byte, RowSize+1)))
+	assert.NoError(tree.Insert(1, []byte("one")))
+	got, ok, err := tree.Search(1)
+	assert.NoError(err)
+	assert.True(ok)
+	assert.Equal([]byte("one\x00"), got[:4])
+}
+
+func TestTree_Random(t *testing.T) {
+	for _, size := range []int{2, 3, 4, 7} {
+		r := rand.New(rand.NewSource(int64(size)))
+		tree, err := Open(filepath.Join(t.TempDir(), "test.db"), MaxLeafCells(size), MaxInternalCells(size), PoolSize(64))
+		require.NoError(t, err)
+
+		m := map[uint32]bool{}
+		for i := 0; i < 3000; i++ {
+			key := uint32(r.Intn(1000))
+			if r.Intn(3) == 0 {
+				ok, err := tree.Delete(key)
+				require.NoError(t, err)
+				require.Equal(t, m[key], ok, "size %d step %d delete %d", size, i, key)
+				delete(m, key)
+			} else {
+				require.NoError(t, tree.Insert(key, row(key)))
+				m[key] = true
+			}
+			if i%100 == 0 {
+				verifyTree(t, tree)
+			}
+		}
+
+		want := make([]uint32, 0, len(m))
+		for key := range m {
+			want = append(want, key)
+		}
+		sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+		assert.Equal(t, want, verifyTree(t, tree))
+
+		for key := range m {
+			ok, err := tree.Delete(key)
+			require.NoError(t, err)
+			require.True(t, ok)
+		}
+		assert.Empty(t, verifyTree(t, tree))
+		require.NoError(t, tree.Close())
+	}
+}
+
+func TestTree_Reopen(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	tree, err := Open(path, MaxLeafCells(4), MaxInternalCells(4))
+	require.NoError(t, err)
+	for i := uint32(0); i < 1000; i++ {
+		assert.NoError(tree.Insert(i*7%1000, row(i*7%1000)))
+	}
+	for i := uint32(0); i < 1000; i += 3 {
+		_, err := tree.Delete(i)
+		assert.NoError(err)
+	}
+	require.NoError(t, tree.Close())
+
+	tree, err = Open(path, MaxLeafCells(4), MaxInternalCells(4))
+	require.NoError(t, err)
+	defer tree.Close()
+	for i := uint32(0); i < 1000; i++ {
+		got, ok, err := tree.Search(i)
+		assert.NoError(err)
+		assert.Equal(i%3 != 0, ok, "key %d", i)
+		if ok {
+			assert.Equal(row(i), got[:8])
+		}
+	}
+	verifyTree(t, tree)
+}
+
+func TestTree_Corrupt(t *testing.T) {
+	assert := assert.New(t)
+	dir := t.TempDir()
+
+	other := filepath.Join(dir, "bytes.db")
+	bt, err := OpenBytes(other)
+	require.NoError(t, err)
+	require.NoError(t, bt.Close())
+	_, err = Open(other)
+	assert.Equal(ErrNotTree, err)
+
+	path := filepath.Join(dir, "test.db")
+	tree, err := Open(path, MaxLeafCells(4), MaxInternalCells(4))
+	require.NoError(t, err)
+	for i := uint32(0); i < 100; i++ {
+		assert.NoError(tree.Insert(i, row(i)))
+	}
+	require.NoError(t, tree.Close())
+
+	// 叶子节点中的一个字节被改掉，查找时返回校验和错误而不是错误的数据
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	require.NoError(t, err)
+	info, err := file.Stat()
+	require.NoError(t, err)
+	_, err = file.WriteAt([]byte{0xff}, info.Size()-int64(os.Getpagesize())+int64(LeafNodeHeaderSize))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	tree, err = Open(path, MaxLeafCells(4), MaxInternalCells(4))
+	require.NoError(t, err)
+	defer tree.Close()
+	var found error
+	for i := uint32(0); i < 100 && found == nil; i++ {
+		_, _, found = tree.Search(i)
+	}
+	assert.ErrorIs(found, common.ErrChecksum)
+}
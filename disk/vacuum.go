@@ -0,0 +1,178 @@
+package disk
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/pedrogao/btrees/common"
+)
+
+// Vacuum rewrites the file with only the pages reachable from the root, so the
+// free pages are dropped and the file shrinks. The copy is written next to the
+// file and renamed over it, on an error the tree keeps using the original file
+func (t *Tree) Vacuum() error {
+	return t.vacuum()
+}
+
+// Vacuum drops the free pages and shrinks the file like Tree.Vacuum
+func (t *BytesTree) Vacuum() error {
+	return t.vacuum()
+}
+
+// vacuum 替换前给原来的文件建一个硬链接，替换或者重新打开失败时把它换回来
+func (t *pageSet) vacuum() error {
+	if err := t.pager.Flush(); err != nil {
+		return err
+	}
+	// 按广度优先的顺序给可达的页重新编号，根节点仍然在 rootPageNum
+	remap := map[uint32]uint32{0: 0, rootPageNum: rootPageNum}
+	order := []uint32{rootPageNum}
+	for i := 0; i < len(order); i++ {
+		page, err := t.pager.Fetch(order[i])
+		if err != nil {
+			return err
+		}
+		for _, link := range pageLinks(page.Data) {
+			if _, ok := remap[link]; !ok {
+				remap[link] = uint32(len(order)) + rootPageNum
+				order = append(order, link)
+			}
+		}
+		t.pager.Release(page)
+	}
+
+	tmp, orig := t.path+".vacuum", t.path+".orig"
+	if err := t.copyPages(tmp, order, remap); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	os.Remove(orig)
+	if err := os.Link(t.path, orig); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	// 原来的 pager 一直打开着，替换失败时还可以继续使用
+	if err := os.Rename(tmp, t.path); err != nil {
+		os.Remove(tmp)
+		os.Remove(orig)
+		return err
+	}
+	if err := syncDir(filepath.Dir(t.path)); err != nil {
+		return errors.Join(err, t.restore(orig))
+	}
+	// 两个 pager 共用 WAL，关闭原来的 pager 会重置 WAL，所以先关闭再重新打开。
+	// WAL 在 Flush 时已经清空
+	if err := t.pager.Close(); err != nil {
+		return errors.Join(err, t.reopen(orig))
+	}
+	if _, err := t.open(t.path, t.opts); err != nil {
+		return errors.Join(err, t.reopen(orig))
+	}
+	os.Remove(orig)
+	return nil
+}
+
+// restore 把 orig 换回原来的位置，原来的 pager 打开的就是这个文件，可以继续使用
+func (t *pageSet) restore(orig string) error {
+	if err := os.Rename(orig, t.path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(t.path))
+}
+
+// reopen 原来的 pager 已经关闭，换回原来的文件后重新打开
+func (t *pageSet) reopen(orig string) error {
+	if err := t.restore(orig); err != nil {
+		return err
+	}
+	_, err := t.open(t.path, t.opts)
+	return err
+}
+
+// copyPages 按 order 的顺序把页拷贝到新文件中，并用 remap 替换页中的页号
+func (t *pageSet) copyPages(path string, order []uint32, remap map[uint32]uint32) error {
+	dst, err := common.OpenPager(path, common.PoolSize(t.opts.poolSize))
+	if err != nil {
+		return err
+	}
+	for _, num := range order {
+		src, err := t.pager.Fetch(num)
+		if err != nil {
+			dst.Close()
+			return err
+		}
+		page, err := dst.Allocate()
+		if err != nil {
+			t.pager.Release(src)
+			dst.Close()
+			return err
+		}
+		copy(page.Data, src.Data)
+		t.pager.Release(src)
+		relinkPage(page.Data, remap)
+		dst.Release(page)
+	}
+	dst.SetRoot(rootPageNum)
+	return dst.Close()
+}
+
+func syncDir(dir string) error {
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Sync()
+}
+
+// pageLinks 页中指向的其他页，不包括父节点
+func pageLinks(node []byte) []uint32 {
+	var links []uint32
+	switch getNodeType(node) {
+	case NodeInternal:
+		links = internalNodeChildren(node)
+	case NodeLeaf:
+		links = append(links, leafNodeNextLeaf(node))
+	case NodeSlottedInternal:
+		_, links = slottedInternalKeys(node)
+	case NodeSlottedLeaf:
+		for i := uint32(0); i < slottedNumCells(node); i++ {
+			links = append(links, slottedLeafCellOverflow(slottedCell(node, i)))
+		}
+		links = append(links, slottedLink(node))
+	case NodeOverflow:
+		links = append(links, getUint32(node, OverflowNextOffset))
+	}
+	return links
+}
+
+// relinkPage 用 remap 替换页中所有的页号，包括父节点
+func relinkPage(node []byte, remap map[uint32]uint32) {
+	switch getNodeType(node) {
+	case NodeInternal:
+		setNodeParent(node, remap[nodeParent(node)])
+		for i := uint32(0); i <= internalNodeNumKeys(node); i++ {
+			setInternalNodeChild(node, i, remap[internalNodeChild(node, i)])
+		}
+	case NodeLeaf:
+		setNodeParent(node, remap[nodeParent(node)])
+		setLeafNodeNextLeaf(node, remap[leafNodeNextLeaf(node)])
+	case NodeSlottedInternal:
+		for i := uint32(0); i < slottedNumCells(node); i++ {
+			cell := slottedCell(node, i)
+			putUint32(cell, 0, remap[getUint32(cell, 0)])
+		}
+		setSlottedLink(node, remap[slottedLink(node)])
+	case NodeSlottedLeaf:
+		for i := uint32(0); i < slottedNumCells(node); i++ {
+			cell := slottedCell(node, i)
+			if overflow := slottedLeafCellOverflow(cell); overflow != 0 {
+				putUint32(cell, uintptr(len(cell))-SlottedOverflowSize, remap[overflow])
+			}
+		}
+		setSlottedLink(node, remap[slottedLink(node)])
+	case NodeOverflow:
+		putUint32(node, OverflowNextOffset, remap[getUint32(node, OverflowNextOffset)])
+	}
+}
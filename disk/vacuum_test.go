@@ -0,0 +1,171 @@
+package disk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fileSize(t *testing.T, path string) int64 {
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	return info.Size()
+}
+
+func TestTree_ReusePages(t *testing.T) {
+	tree, err := Open(filepath.Join(t.TempDir(), "test.db"), MaxLeafCells(4), MaxInternalCells(4))
+	require.NoError(t, err)
+	defer tree.Close()
+
+	// 合并释放的页被之后的插入复用
+	for round := 0; round < 3; round++ {
+		for i := uint32(0); i < 1000; i++ {
+			require.NoError(t, tree.Insert(i, row(i)))
+		}
+		numPages := tree.pager.NumPages()
+		for i := uint32(0); i < 1000; i++ {
+			_, err := tree.Delete(i)
+			require.NoError(t, err)
+		}
+		assert.Empty(t, verifyTree(t, tree))
+		assert.Equal(t, numPages-2, tree.pager.FreePages(), "round %d", round)
+	}
+}
+
+func TestTree_Vacuum(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "test.db")
+	tree, err := Open(path, MaxLeafCells(4), MaxInternalCells(4))
+	require.NoError(t, err)
+	for i := uint32(0); i < 3000; i++ {
+		require.NoError(t, tree.Insert(i, row(i)))
+	}
+	var want []uint32
+	for i := uint32(0); i < 3000; i++ {
+		if i%10 == 0 {
+			want = append(want, i)
+			continue
+		}
+		_, err := tree.Delete(i)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tree.Flush())
+	before := fileSize(t, path)
+	assert.Greater(tree.pager.FreePages(), uint32(0))
+
+	require.NoError(t, tree.Vacuum())
+	assert.Less(fileSize(t, path), before/2)
+	assert.Equal(uint32(0), tree.pager.FreePages())
+	assert.Equal(want, verifyTree(t, tree))
+
+	// vacuum 之后可以继续修改，重新打开后内容不变
+	require.NoError(t, tree.Insert(1, row(1)))
+	require.NoError(t, tree.Close())
+	tree, err = Open(path, MaxLeafCells(4), MaxInternalCells(4))
+	require.NoError(t, err)
+	defer tree.Close()
+	want = append(want, 1)
+	sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+	assert.Equal(want, verifyTree(t, tree))
+}
+
+// 重新打开新文件失败时换回原来的文件，树可以继续使用
+func TestTree_VacuumReopenFails(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "test.db")
+	errOpen := errors.New("open failed")
+	fail := false
+	openFile := func(name string) (common.File, error) {
+		if fail && name == path {
+			fail = false
+			return nil, errOpen
+		}
+		return os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	}
+	options := []Option{MaxLeafCells(4), MaxInternalCells(4), withPagerOptions(common.WithOpenFile(openFile))}
+	tree, err := Open(path, options...)
+	require.NoError(t, err)
+	var want []uint32
+	for i := uint32(0); i < 1000; i++ {
+		require.NoError(t, tree.Insert(i, row(i)))
+	}
+	for i := uint32(0); i < 1000; i++ {
+		if i%10 == 0 {
+			want = append(want, i)
+			continue
+		}
+		_, err := tree.Delete(i)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tree.Flush())
+	before := fileSize(t, path)
+	freePages := tree.pager.FreePages()
+
+	fail = true
+	assert.ErrorIs(tree.Vacuum(), errOpen)
+	assert.Equal(before, fileSize(t, path))
+	assert.Equal(freePages, tree.pager.FreePages())
+	assert.Equal(want, verifyTree(t, tree))
+	for _, name := range []string{path + ".vacuum", path + ".orig"} {
+		_, err := os.Stat(name)
+		assert.ErrorIs(err, os.ErrNotExist, name)
+	}
+
+	// 之后仍然可以修改和 vacuum
+	require.NoError(t, tree.Insert(1, row(1)))
+	require.NoError(t, tree.Vacuum())
+	assert.Less(fileSize(t, path), before/2)
+	require.NoError(t, tree.Close())
+	tree, err = Open(path, options...)
+	require.NoError(t, err)
+	defer tree.Close()
+	want = append(want, 1)
+	sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+	assert.Equal(want, verifyTree(t, tree))
+}
+
+func TestBytesTree_Vacuum(t *testing.T) {
+	assert := assert.New(t)
+	path := filepath.Join(t.TempDir(), "test.db")
+	tree, err := OpenBytes(path)
+	require.NoError(t, err)
+	value := func(i int) []byte {
+		if i%20 == 0 {
+			return append(make([]byte, 3*int(PageSize)), fmt.Sprint(i)...)
+		}
+		return []byte(fmt.Sprint(i))
+	}
+	for i := 0; i < 2000; i++ {
+		require.NoError(t, tree.Put([]byte(fmt.Sprintf("key%05d", i)), value(i)))
+	}
+	var want []string
+	for i := 0; i < 2000; i++ {
+		key := fmt.Sprintf("key%05d", i)
+		if i%50 == 0 {
+			want = append(want, key)
+			continue
+		}
+		_, err := tree.Delete([]byte(key))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tree.Flush())
+	before := fileSize(t, path)
+
+	require.NoError(t, tree.Vacuum())
+	assert.Less(fileSize(t, path), before/2)
+	assert.Equal(want, verifyBytesTree(t, tree))
+	for i := 0; i < 2000; i += 50 {
+		got, ok, err := tree.Get([]byte(fmt.Sprintf("key%05d", i)))
+		require.NoError(t, err)
+		assert.True(ok)
+		assert.Equal(value(i), got)
+	}
+	require.NoError(t, tree.Close())
+}
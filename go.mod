@@ -1,11 +1,13 @@
 module github.com/pedrogao/btrees
 
-go 1.18
+go 1.23
 
-require github.com/stretchr/testify v1.7.0
+require (
+	github.com/davecgh/go-spew v1.1.1
+	github.com/stretchr/testify v1.7.0
+)
 
 require (
-	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
 )
@@ -0,0 +1,354 @@
+// Package modeltest checks a common.OrderedMap against a reference model, a
+// map plus a sorted slice of its keys. Operation sequences are generated at
+// random or decoded from fuzz input and every step is compared with the model.
+// A failing sequence is shrunk to a short reproducer before it is reported
+package modeltest
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+)
+
+// DefaultKeys is the size of the key space, small enough that sets and
+// deletes hit existing keys often
+const DefaultKeys = 256
+
+// MaxFuzzOps caps the operations decoded from one fuzz input, the map is
+// compared with the model after every step, so long inputs are slow
+const MaxFuzzOps = 512
+
+type Kind uint8
+
+const (
+	Set Kind = iota
+	Delete
+	Get
+	numKinds
+)
+
+// Op is one step of a sequence, keys are indexes into the key space of a Target
+type Op struct {
+	Kind Kind
+	Key  int
+}
+
+func (op Op) String() string {
+	switch op.Kind {
+	case Set:
+		return fmt.Sprintf("set(%d)", op.Key)
+	case Delete:
+		return fmt.Sprintf("delete(%d)", op.Key)
+	default:
+		return fmt.Sprintf("get(%d)", op.Key)
+	}
+}
+
+// Format returns the sequence as a single line
+func Format(ops []Op) string {
+	s := make([]string, len(ops))
+	for i, op := range ops {
+		s[i] = op.String()
+	}
+	return strings.Join(s, " ")
+}
+
+// Target describes the map under test
+type Target[K cmp.Ordered] struct {
+	// New returns an empty map
+	New func() common.OrderedMap[K, any]
+	// Key maps an index of the key space to a key, it must be injective.
+	// nil means the identity for int keys
+	Key func(i int) K
+}
+
+func (t Target[K]) key(i int) K {
+	if t.Key == nil {
+		return any(i).(K)
+	}
+	return t.Key(i)
+}
+
+// Verifier is implemented by maps that can check their own invariants,
+// Run calls Verify after every step
+type Verifier interface {
+	Verify() error
+}
+
+// Failure is the first step at which the map and the model disagree
+type Failure struct {
+	Step int
+	Op   Op
+	Msg  string
+}
+
+func (f *Failure) Error() string {
+	return fmt.Sprintf("step %d %s: %s", f.Step, f.Op, f.Msg)
+}
+
+// Random returns n operations over keys keys, sets are twice as likely as the
+// others so the map grows large enough to split nodes
+func Random(r *rand.Rand, n, keys int) []Op {
+	ops := make([]Op, n)
+	for i := range ops {
+		kind := Kind(r.Intn(4))
+		if kind == numKinds {
+			kind = Set
+		}
+		ops[i] = Op{Kind: kind, Key: r.Intn(keys)}
+	}
+	return ops
+}
+
+// Decode turns fuzz input into operations, two bytes per operation
+func Decode(data []byte, keys int) []Op {
+	ops := make([]Op, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		ops = append(ops, Op{Kind: Kind(data[i] % byte(numKinds)), Key: int(data[i+1]) % keys})
+	}
+	return ops
+}
+
+// Run applies ops to a new map and to the model, the map is compared with the
+// model after every step. Maps with a Verify() error method are verified too.
+// A panic of the map is reported as a failure of the step
+func Run[K cmp.Ordered](target Target[K], ops []Op) (err error) {
+	var (
+		m    = target.New()
+		want = map[K]any{}
+		keys []K // want 中的 key，有序
+		step int
+	)
+	defer func() {
+		if r := recover(); r != nil {
+			err = &Failure{Step: step, Op: ops[step], Msg: fmt.Sprintf("panic: %v", r)}
+		}
+	}()
+	for i, op := range ops {
+		step = i
+		key := target.key(op.Key)
+		idx, found := slices.BinarySearch(keys, key)
+		fail := func(format string, args ...any) error {
+			return &Failure{Step: i, Op: op, Msg: fmt.Sprintf(format, args...)}
+		}
+		switch op.Kind {
+		case Set:
+			m.Set(key, i)
+			want[key] = i
+			if !found {
+				keys = slices.Insert(keys, idx, key)
+			}
+		case Delete:
+			if got := m.Delete(key); got != found {
+				return fail("Delete returned %v, want %v", got, found)
+			}
+			delete(want, key)
+			if found {
+				keys = slices.Delete(keys, idx, idx+1)
+			}
+		case Get:
+			got, ok := m.Get(key)
+			if ok != found || got != want[key] {
+				return fail("Get returned (%v, %v), want (%v, %v)", got, ok, want[key], found)
+			}
+		}
+		if msg := compare(m, want, keys); msg != "" {
+			return fail("%s", msg)
+		}
+		if nav, ok := m.(common.Navigable[K, any]); ok {
+			if msg := compareNearest(nav, want, keys, key); msg != "" {
+				return fail("%s", msg)
+			}
+		}
+	}
+	return nil
+}
+
+// compare 比较 map 与模型，返回第一处不一致
+func compare[K cmp.Ordered](m common.OrderedMap[K, any], want map[K]any, keys []K) string {
+	if v, ok := m.(Verifier); ok {
+		if err := v.Verify(); err != nil {
+			return err.Error()
+		}
+	}
+	if m.Len() != len(keys) {
+		return fmt.Sprintf("Len is %d, want %d", m.Len(), len(keys))
+	}
+	i, msg := 0, ""
+	m.Ascend(func(key K, value any) bool {
+		switch {
+		case i >= len(keys):
+			msg = fmt.Sprintf("Ascend yields extra key %v", key)
+		case key != keys[i]:
+			msg = fmt.Sprintf("Ascend yields key %v at %d, want %v", key, i, keys[i])
+		case value != want[key]:
+			msg = fmt.Sprintf("Ascend yields %v for key %v, want %v", value, key, want[key])
+		}
+		i++
+		return msg == ""
+	})
+	if msg != "" {
+		return msg
+	}
+	if i != len(keys) {
+		return fmt.Sprintf("Ascend yields %d keys, want %d", i, len(keys))
+	}
+	minKey, _, minOk := m.Min()
+	maxKey, _, maxOk := m.Max()
+	if minOk != (len(keys) > 0) || maxOk != (len(keys) > 0) {
+		return fmt.Sprintf("Min and Max report %v and %v on %d keys", minOk, maxOk, len(keys))
+	}
+	if len(keys) > 0 && (minKey != keys[0] || maxKey != keys[len(keys)-1]) {
+		return fmt.Sprintf("Min and Max are %v and %v, want %v and %v", minKey, maxKey, keys[0], keys[len(keys)-1])
+	}
+	if s, ok := m.(common.OrderStatistics[K, any]); ok {
+		return compareStatistics(s, keys)
+	}
+	return ""
+}
+
+// compareNearest 用有序的 keys 检查 key 附近的 Floor、Ceil、Prev 和 Next
+func compareNearest[K cmp.Ordered](nav common.Navigable[K, any], want map[K]any, keys []K, key K) string {
+	idx, found := slices.BinarySearch(keys, key)
+	// 期望结果在 keys 中的下标，越界表示不存在
+	floor, ceil, prev, next := idx-1, idx, idx-1, idx
+	if found {
+		floor, next = idx, idx+1
+	}
+	for _, c := range []struct {
+		name string
+		fn   func(key K) (K, any, bool)
+		i    int
+	}{
+		{"Floor", nav.Floor, floor},
+		{"Ceil", nav.Ceil, ceil},
+		{"Prev", nav.Prev, prev},
+		{"Next", nav.Next, next},
+	} {
+		got, value, ok := c.fn(key)
+		if c.i < 0 || c.i >= len(keys) {
+			if ok {
+				return fmt.Sprintf("%s(%v) is %v, want none", c.name, key, got)
+			}
+			continue
+		}
+		if !ok || got != keys[c.i] || value != want[got] {
+			return fmt.Sprintf("%s(%v) is (%v, %v, %v), want %v", c.name, key, got, value, ok, keys[c.i])
+		}
+	}
+	return ""
+}
+
+// compareStatistics 用有序的 keys 检查 Rank、Select 和 CountRange
+func compareStatistics[K cmp.Ordered](s common.OrderStatistics[K, any], keys []K) string {
+	for i, key := range keys {
+		if r := s.Rank(key); r != i {
+			return fmt.Sprintf("Rank(%v) is %d, want %d", key, r, i)
+		}
+		if k, _, ok := s.Select(i); !ok || k != key {
+			return fmt.Sprintf("Select(%d) is %v, %v, want %v", i, k, ok, key)
+		}
+	}
+	if _, _, ok := s.Select(len(keys)); ok {
+		return fmt.Sprintf("Select(%d) is out of range but found", len(keys))
+	}
+	if len(keys) > 0 {
+		lo, hi := keys[0], keys[len(keys)-1]
+		if c := s.CountRange(lo, hi); c != len(keys)-1 {
+			return fmt.Sprintf("CountRange(%v, %v) is %d, want %d", lo, hi, c, len(keys)-1)
+		}
+	}
+	return ""
+}
+
+// Minimize shrinks a failing sequence: it drops everything after the failing
+// step, removes chunks of operations as long as the sequence keeps failing
+// and finally renames keys to the smallest keys that still fail
+func Minimize[K cmp.Ordered](target Target[K], ops []Op) []Op {
+	err := Run(target, ops)
+	f, ok := err.(*Failure)
+	if !ok {
+		return ops
+	}
+	ops = slices.Clone(ops[:f.Step+1])
+	fails := func(ops []Op) bool { return Run(target, ops) != nil }
+
+	for chunk := len(ops) / 2; chunk >= 1; {
+		removed := false
+		for i := 0; i+chunk <= len(ops); {
+			candidate := slices.Concat(ops[:i], ops[i+chunk:])
+			if fails(candidate) {
+				ops, removed = candidate, true
+			} else {
+				i += chunk
+			}
+		}
+		if !removed {
+			chunk /= 2
+		}
+	}
+	// 一个 key 的所有出现一起替换，否则 set 和 delete 就不再是同一个 key
+	done := map[int]bool{}
+	for i := range ops {
+		old := ops[i].Key
+		if done[old] {
+			continue
+		}
+		for key := 0; key < old; key++ {
+			candidate := slices.Clone(ops)
+			for j := range candidate {
+				if candidate[j].Key == old {
+					candidate[j].Key = key
+				}
+			}
+			if fails(candidate) {
+				ops = candidate
+				break
+			}
+		}
+		done[ops[i].Key] = true
+	}
+	return ops
+}
+
+// Check runs ops and fails the test with a minimized reproducer
+func Check[K cmp.Ordered](t testing.TB, target Target[K], ops []Op) {
+	t.Helper()
+	if err := Run(target, ops); err != nil {
+		ops = Minimize(target, ops)
+		t.Fatalf("%v\nreproducer (%d ops): %s\nfailure: %v", err, len(ops), Format(ops), Run(target, ops))
+	}
+}
+
+// RandomTest runs rounds random sequences of steps operations
+func RandomTest[K cmp.Ordered](t *testing.T, target Target[K], rounds, steps int) {
+	t.Helper()
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < rounds; i++ {
+		Check(t, target, Random(r, steps, DefaultKeys))
+	}
+}
+
+// Fuzz registers a fuzz target that decodes the input into operations,
+// the seed corpus covers growing and shrinking the map
+func Fuzz[K cmp.Ordered](f *testing.F, target Target[K]) {
+	var grow, shrink []byte
+	for i := 0; i < 200; i++ {
+		grow = append(grow, byte(Set), byte(i))
+		shrink = append(shrink, byte(Delete), byte(i))
+	}
+	f.Add(grow)
+	f.Add(append(slices.Clone(grow), shrink...))
+	f.Add([]byte{byte(Set), 1, byte(Get), 1, byte(Delete), 1, byte(Get), 1})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) > 2*MaxFuzzOps {
+			data = data[:2*MaxFuzzOps]
+		}
+		Check(t, target, Decode(data, DefaultKeys))
+	})
+}
@@ -0,0 +1,93 @@
+package modeltest
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/pedrogao/btrees/common"
+	"github.com/stretchr/testify/assert"
+)
+
+// sliceMap 基于有序切片的参考实现，bug 不为 nil 时用来注入错误
+type sliceMap struct {
+	keys   []int
+	values []any
+	bug    func(key int) bool
+}
+
+func (m *sliceMap) Get(key int) (any, bool) {
+	if i, ok := slices.BinarySearch(m.keys, key); ok {
+		return m.values[i], true
+	}
+	return nil, false
+}
+
+func (m *sliceMap) Set(key int, value any) {
+	i, ok := slices.BinarySearch(m.keys, key)
+	if ok {
+		m.values[i] = value
+		return
+	}
+	m.keys = slices.Insert(m.keys, i, key)
+	m.values = slices.Insert(m.values, i, value)
+}
+
+func (m *sliceMap) Delete(key int) bool {
+	i, ok := slices.BinarySearch(m.keys, key)
+	if !ok || (m.bug != nil && m.bug(key)) {
+		return ok
+	}
+	m.keys = slices.Delete(m.keys, i, i+1)
+	m.values = slices.Delete(m.values, i, i+1)
+	return true
+}
+
+func (m *sliceMap) Len() int { return len(m.keys) }
+
+func (m *sliceMap) Min() (int, any, bool) {
+	if len(m.keys) == 0 {
+		return 0, nil, false
+	}
+	return m.keys[0], m.values[0], true
+}
+
+func (m *sliceMap) Max() (int, any, bool) {
+	if len(m.keys) == 0 {
+		return 0, nil, false
+	}
+	return m.keys[len(m.keys)-1], m.values[len(m.values)-1], true
+}
+
+func (m *sliceMap) Ascend(fn func(key int, value any) bool) {
+	for i, key := range m.keys {
+		if !fn(key, m.values[i]) {
+			return
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	RandomTest(t, Target[int]{New: func() common.OrderedMap[int, any] { return &sliceMap{} }}, 5, 2000)
+}
+
+func TestMinimize(t *testing.T) {
+	// key 大于 100 时 Delete 不生效
+	target := Target[int]{New: func() common.OrderedMap[int, any] {
+		return &sliceMap{bug: func(key int) bool { return key > 100 }}
+	}}
+	ops := []Op{{Set, 3}, {Set, 200}, {Get, 3}, {Set, 150}, {Delete, 3}, {Delete, 200}, {Set, 9}, {Get, 200}}
+	assert.Error(t, Run(target, ops))
+	ops = Minimize(target, ops)
+	assert.Equal(t, []Op{{Set, 101}, {Delete, 101}}, ops)
+	assert.Equal(t, "set(101) delete(101)", Format(ops))
+
+	err := Run(target, ops)
+	if assert.IsType(t, &Failure{}, err) {
+		assert.Equal(t, 1, err.(*Failure).Step)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	ops := Decode([]byte{0, 1, 4, 2, 5, 255, 9}, 100)
+	assert.Equal(t, []Op{{Set, 1}, {Delete, 2}, {Get, 55}}, ops)
+}